@@ -0,0 +1,199 @@
+package clustering
+
+import (
+    "math"
+    "sort"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+)
+
+
+// types of linkage, the distance between two clusters is
+const SINGLE_LINKAGE = 0 // the distance of their closest points
+const COMPLETE_LINKAGE = 1 // the distance of their farthest points
+const AVERAGE_LINKAGE = 2 // the average distance of their points
+const WARD_LINKAGE = 3 // based on the increase of the within cluster sum of squares
+
+
+/*
+This type is one step of agglomerative clustering. The points are the clusters 0, ..., N-1
+and the cluster created by the i-th merge is N+i.
+    Left int: the first merged cluster
+    Right int: the second merged cluster
+    Height float64: the distance of the two clusters
+    Size int: the number of points in the new cluster
+*/
+type Merge struct {
+    Left int
+    Right int
+    Height float64
+    Size int
+}
+
+
+/*
+This type stores the hierarchy found by agglomerative clustering.
+    NumPoints int: the number of points
+    Merges []Merge: the N-1 merges in increasing order of height
+*/
+type Dendrogram struct {
+    NumPoints int
+    Merges []Merge
+}
+
+
+/*
+SUMMARY
+    Finds the representative of a set in a union-find forest, compressing the path.
+PARAMETERS
+    Parent []int: the forest
+    I int: an element
+RETURN
+    int: the representative
+*/
+func find(Parent []int, I int) int {
+    for Parent[I] != I {
+        Parent[I] = Parent[Parent[I]]
+        I = Parent[I]
+    }
+    return I
+}
+
+
+/*
+SUMMARY
+    Implements hierarchical agglomerative clustering with the nearest neighbour chain algorithm in
+    O(N^2) time and memory: the chain follows nearest neighbours until two clusters are each other's
+    nearest neighbours, and they are merged. The distances are updated with the Lance-Williams formulas.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Linkage int: SINGLE_LINKAGE, COMPLETE_LINKAGE, AVERAGE_LINKAGE or WARD_LINKAGE
+RETURN
+    Dendrogram: the hierarchy
+*/
+func Agglomerative(X *mat.Dense, Linkage int) Dendrogram {
+    n, _ := X.Dims()
+    if Linkage < SINGLE_LINKAGE || Linkage > WARD_LINKAGE { panic("Unknown linkage encountered") }
+    distances := make([][]float64, n)
+    for i := range distances {
+        distances[i] = make([]float64, n)
+        for j:=0; j<i; j++ {
+            d := floats.Distance(X.RawRowView(i), X.RawRowView(j), 2)
+            // Ward's formula holds for squared distances
+            if Linkage == WARD_LINKAGE { d *= d }
+            distances[i][j], distances[j][i] = d, d
+        }
+    }
+    active := make([]bool, n)
+    sizes := make([]int, n)
+    for i := range active { active[i], sizes[i] = true, 1 }
+
+    // the merges refer to the slots of the distance matrix until they are sorted
+    var merges []Merge
+    var chain []int
+    for len(merges) < n-1 {
+        if len(chain) == 0 {
+            for i := range active {
+                if active[i] { chain = append(chain, i); break }
+            }
+        }
+        a := chain[len(chain)-1]
+        b, best := -1, math.Inf(1)
+        // ties are broken in favour of the previous cluster of the chain, otherwise the chain could cycle
+        if len(chain) > 1 { b, best = chain[len(chain)-2], distances[a][chain[len(chain)-2]] }
+        for k := range active {
+            if active[k] && k != a && distances[a][k] < best { b, best = k, distances[a][k] }
+        }
+        if len(chain) < 2 || b != chain[len(chain)-2] {
+            chain = append(chain, b)
+            continue
+        }
+        chain = chain[:len(chain)-2]
+        sizeA, sizeB := float64(sizes[a]), float64(sizes[b])
+        for k := range active {
+            if !active[k] || k == a || k == b { continue }
+            dA, dB := distances[a][k], distances[b][k]
+            var d float64
+            switch Linkage {
+                case SINGLE_LINKAGE:
+                    d = math.Min(dA, dB)
+                case COMPLETE_LINKAGE:
+                    d = math.Max(dA, dB)
+                case AVERAGE_LINKAGE:
+                    d = (sizeA * dA + sizeB * dB) / (sizeA + sizeB)
+                case WARD_LINKAGE:
+                    sizeK := float64(sizes[k])
+                    d = ((sizeA + sizeK) * dA + (sizeB + sizeK) * dB - sizeK * best) / (sizeA + sizeB + sizeK)
+            }
+            distances[a][k], distances[k][a] = d, d
+        }
+        height := best
+        if Linkage == WARD_LINKAGE { height = math.Sqrt(best) }
+        sizes[a] += sizes[b]
+        active[b] = false
+        merges = append(merges, Merge{Left: a, Right: b, Height: height, Size: sizes[a]})
+    }
+
+    // sort the merges by height and rename the slots to cluster numbers
+    sort.SliceStable(merges, func(i, j int) bool { return merges[i].Height < merges[j].Height })
+    parent := make([]int, n)
+    name := make([]int, n)
+    for i := range parent { parent[i], name[i] = i, i }
+    for i := range merges {
+        left, right := find(parent, merges[i].Left), find(parent, merges[i].Right)
+        merges[i].Left, merges[i].Right = name[left], name[right]
+        parent[right] = left
+        name[left] = n + i
+    }
+    return Dendrogram{NumPoints: n, Merges: merges}
+}
+
+
+/*
+SUMMARY
+    Cuts the hierarchy into a given number of clusters by undoing the highest merges.
+PARAMETERS
+    NumClusters int: the number of clusters
+RETURN
+    []int: the cluster of each point, 0, 1, ... in the order of the first points
+*/
+func (d Dendrogram) Cut(NumClusters int) []int {
+    if NumClusters <= 0 || NumClusters > d.NumPoints { panic("Number of clusters outside [1, #points] encountered") }
+    // union-find over the points and the merged clusters
+    parent := make([]int, 2*d.NumPoints - 1)
+    for i := range parent { parent[i] = i }
+    for i, merge := range d.Merges[:d.NumPoints - NumClusters] {
+        parent[find(parent, merge.Left)] = d.NumPoints + i
+        parent[find(parent, merge.Right)] = d.NumPoints + i
+    }
+    labels := make([]int, d.NumPoints)
+    names := map[int]int{}
+    for i := range labels {
+        root := find(parent, i)
+        if _, ok := names[root]; !ok { names[root] = len(names) }
+        labels[i] = names[root]
+    }
+    return labels
+}
+
+
+/*
+SUMMARY
+    The merged clusters and the heights as separate slices, the format of plt.MakeDendrogramLines.
+PARAMETERS
+    N/A
+RETURN
+    []int: the first merged cluster of each merge
+    []int: the second merged cluster of each merge
+    []float64: the height of each merge
+*/
+func (d Dendrogram) Children() ([]int, []int, []float64) {
+    left := make([]int, len(d.Merges))
+    right := make([]int, len(d.Merges))
+    heights := make([]float64, len(d.Merges))
+    for i, merge := range d.Merges {
+        left[i], right[i], heights[i] = merge.Left, merge.Right, merge.Height
+    }
+    return left, right, heights
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+    "fmt"
+    "math"
+    "image/color"
+    "gonum.org/v1/gonum/mat"
+    "golang.org/x/exp/rand"
+    "gonum.org/v1/gonum/stat/distuv"
+
+    "gonum.org/v1/plot"
+    "gonum.org/v1/plot/vg"
+
+    "ml_playground/clustering"
+    "ml_playground/kernels"
+    "ml_playground/kmeans"
+    "ml_playground/plt"
+)
+
+// random number seed and source
+var randSeed = 3
+var randSrc = rand.NewSource(uint64(randSeed))
+
+
+/*
+SUMMARY
+    Generates two interleaved noisy spirals, the second is the first rotated by 180 degrees.
+PARAMETERS
+    Num int: the number of points of each spiral
+    Noise float64: the standard deviation of the noise
+RETURN
+    *mat.Dense: matrix where each row is a point
+    []int: the spiral of each point
+*/
+func GenerateSpirals(Num int, Noise float64) (*mat.Dense, []int) {
+    normal := distuv.Normal{Mu: 0, Sigma: Noise, Src: randSrc}
+    X := mat.NewDense(2*Num, 2, nil)
+    labels := make([]int, 2*Num)
+    for y:=0; y<Num; y++ {
+        t := 2.0 + float64(y) / float64(Num) * 3.0 * math.Pi
+        for arm:=0; arm<2; arm++ {
+            sign := 1.0 - 2.0 * float64(arm)
+            X.Set(arm*Num + y, 0, sign * t * math.Sin(t) + normal.Rand())
+            X.Set(arm*Num + y, 1, sign * t * math.Cos(t) + normal.Rand())
+            labels[arm*Num + y] = arm
+        }
+    }
+    return X, labels
+}
+
+
+/*
+SUMMARY
+    Generates Gaussian blobs with centres on a circle.
+PARAMETERS
+    NumBlobs int: the number of blobs
+    Num int: the number of points of each blob
+    Sigma float64: the standard deviation of the blobs
+RETURN
+    *mat.Dense: matrix where each row is a point
+*/
+func GenerateBlobs(NumBlobs, Num int, Sigma float64) *mat.Dense {
+    normal := distuv.Normal{Mu: 0, Sigma: Sigma, Src: randSrc}
+    X := mat.NewDense(NumBlobs*Num, 2, nil)
+    for blob:=0; blob<NumBlobs; blob++ {
+        angle := 2 * math.Pi * float64(blob) / float64(NumBlobs)
+        for i:=0; i<Num; i++ {
+            X.Set(blob*Num + i, 0, 5 * math.Cos(angle) + normal.Rand())
+            X.Set(blob*Num + i, 1, 5 * math.Sin(angle) + normal.Rand())
+        }
+    }
+    return X
+}
+
+
+/*
+SUMMARY
+    Saves a scatter plot of the points coloured by cluster, noise points are grey.
+PARAMETERS
+    X *mat.Dense: the points, each row is a point
+    Labels []int: the clusters
+    Title string: the title of the plot
+    Filename string: the output file
+RETURN
+    N/A
+*/
+func SaveClusters(X *mat.Dense, Labels []int, Title, Filename string) {
+    numClusters := 0
+    for _, label := range Labels {
+        if label + 1 > numClusters { numClusters = label + 1 }
+    }
+    classColours := plt.DesignedPalette{Type: plt.RANDOM_PALETTE, Num: numClusters, Extra: 7}.Colors()
+    colours := make([]color.Color, len(Labels))
+    for i, label := range Labels {
+        if label == clustering.NOISE {
+            colours[i] = color.RGBA{R: 170, G: 170, B: 170, A: 255}
+        } else {
+            colours[i] = classColours[label]
+        }
+    }
+    p := plot.New()
+    p.Title.Text = Title
+    p.Add(plt.MakeScatterUnicorn(mat.Col(nil, 0, X), mat.Col(nil, 1, X), plt.CIRCLE_POINT_MARKER, 2.5, plt.CustomPalette{Colours: colours}))
+    p.Save(4*vg.Inch, 4*vg.Inch, Filename)
+}
+
+
+/*
+We cluster two interleaved spirals with k-means, DBSCAN, single linkage and spectral clustering.
+Only k-means fails, the clusters are not convex. We also draw the Ward dendrogram of a few blobs,
+and choose the number of clusters of Gaussian blobs with the validation metrics.
+*/
+func main() {
+    X, truth := GenerateSpirals(300, 0.2)
+
+    result := kmeans.KMeans(mat.DenseCopyOf(X.T()), kmeans.KMeansOptions{NumClasses: 2, Restarts: 10, Tolerance: 1e-6, Seed: randSeed})
+    dbscan := clustering.DBSCAN(X, 1.2, 4)
+    single := clustering.Agglomerative(X, clustering.SINGLE_LINKAGE).Cut(2)
+    spectral, _ := clustering.SpectralClustering(X, clustering.SpectralOptions{
+        NumClusters: 2,
+        Kernel: kernels.Parameters{Type: kernels.RBF, VarSigma: 1.0, LengthScale: 1.0},
+        NumNeighbours: 10,
+        Seed: randSeed,
+    })
+    for _, run := range []struct{ name, filename string; labels []int }{
+        {name: "k-means", filename: "spirals_kmeans.png", labels: result.Labels},
+        {name: "DBSCAN", filename: "spirals_dbscan.png", labels: dbscan},
+        {name: "single linkage", filename: "spirals_single_linkage.png", labels: single},
+        {name: "spectral", filename: "spirals_spectral.png", labels: spectral},
+    } {
+        fmt.Printf("%-15s adjusted Rand index %.3f, normalised mutual information %.3f\n",
+            run.name, clustering.AdjustedRandIndex(run.labels, truth), clustering.NormalisedMutualInformation(run.labels, truth))
+        SaveClusters(X, run.labels, run.name, run.filename)
+    }
+
+    blobs := kmeans.CreateRandomPoints(30, 2, randSeed, 0, 10)
+    points := mat.DenseCopyOf(blobs.T())
+    for i:=0; i<30; i++ {
+        // three blobs
+        points.Set(i, 0, points.At(i, 0) + 20.0 * float64(i % 3))
+    }
+    dendrogram := clustering.Agglomerative(points, clustering.WARD_LINKAGE)
+    left, right, heights := dendrogram.Children()
+    lines, order := plt.MakeDendrogramLines(left, right, heights, 1.5, 0x1f3a93ff)
+    p := plot.New()
+    p.Title.Text = "Ward dendrogram"
+    p.Y.Label.Text = "height"
+    for _, line := range lines { p.Add(line) }
+    leafNames := make([]plot.Tick, len(order))
+    for position, leaf := range order {
+        leafNames[position] = plot.Tick{Value: float64(position), Label: fmt.Sprint(leaf)}
+    }
+    p.X.Tick.Marker = plot.ConstantTicks(leafNames)
+    p.Save(6*vg.Inch, 3*vg.Inch, "dendrogram.svg")
+    fmt.Println("Ward clusters of the blobs", dendrogram.Cut(3))
+
+    blobs = GenerateBlobs(5, 80, 0.8)
+    sweep := clustering.SweepK(blobs, clustering.SweepOptions{MaxK: 10, Restarts: 5, Seed: randSeed})
+    for i, k := range sweep.Ks {
+        fmt.Printf("K=%2d inertia %8.1f, silhouette %.3f, Calinski-Harabasz %7.1f, Davies-Bouldin %.3f\n",
+            k, sweep.Inertias[i], sweep.Silhouettes[i], sweep.CalinskiHarabasz[i], sweep.DaviesBouldin[i])
+    }
+    _, _, gapK := clustering.GapStatistic(blobs, 10, 10, randSeed)
+    fmt.Printf("elbow K=%d, silhouette K=%d, gap statistic K=%d\n", sweep.ElbowK, sweep.SilhouetteK, gapK)
+    p = clustering.SweepPlot(sweep)
+    p.Save(5*vg.Inch, 3*vg.Inch, "sweep.svg")
+}
@@ -0,0 +1,54 @@
+package clustering
+
+import (
+    "gonum.org/v1/gonum/mat"
+)
+
+
+// the label of the points which belong to no cluster
+const NOISE = -1
+
+
+/*
+SUMMARY
+    Implements DBSCAN (Ester et al., A Density-Based Algorithm for Discovering Clusters in Large Spatial
+    Databases with Noise). A point with at least MinPoints points within Eps (itself included) is a core
+    point; clusters are the sets of core points reachable from each other through core points, together
+    with the points within Eps of them. The neighbourhoods are found with a k-d tree.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Eps float64: the radius of the neighbourhoods
+    MinPoints int: the smallest neighbourhood of a core point
+RETURN
+    []int: the cluster of each point, 0, 1, ... in the order of discovery, or NOISE
+*/
+func DBSCAN(X *mat.Dense, Eps float64, MinPoints int) []int {
+    n, _ := X.Dims()
+    tree := NewKDTree(X)
+    const UNVISITED = -2
+    labels := make([]int, n)
+    for i := range labels { labels[i] = UNVISITED }
+    cluster := 0
+    for i := range labels {
+        if labels[i] != UNVISITED { continue }
+        neighbours := tree.RadiusSearch(X.RawRowView(i), Eps)
+        if len(neighbours) < MinPoints {
+            labels[i] = NOISE
+            continue
+        }
+        labels[i] = cluster
+        queue := neighbours
+        for len(queue) > 0 {
+            j := queue[0]
+            queue = queue[1:]
+            if labels[j] == NOISE { labels[j] = cluster }
+            if labels[j] != UNVISITED { continue }
+            labels[j] = cluster
+            if next := tree.RadiusSearch(X.RawRowView(j), Eps); len(next) >= MinPoints {
+                queue = append(queue, next...)
+            }
+        }
+        cluster++
+    }
+    return labels
+}
@@ -0,0 +1,164 @@
+/*
+This library contains clustering algorithms beyond k-means: DBSCAN, hierarchical
+agglomerative clustering and spectral clustering, together with a k-d tree for
+neighbour queries. The data points are the rows of the data matrices.
+*/
+package clustering
+
+import (
+    "math"
+    "sort"
+
+    "gonum.org/v1/gonum/mat"
+)
+
+
+/*
+This type is a node of the k-d tree.
+    Point int: the index of the point stored in the node
+    Dim int: the coordinate the node splits on
+    Left, Right *kdNode: the subtrees with smaller and with not smaller coordinates
+*/
+type kdNode struct {
+    Point int
+    Dim int
+    Left, Right *kdNode
+}
+
+
+/*
+This type is a k-d tree (Bentley, Multidimensional Binary Search Trees Used for Associative Searching)
+answering radius and nearest neighbour queries in O(log N) on average for low dimensional data.
+    Points *mat.Dense: the indexed points, each row is a point
+    root *kdNode: the root of the tree
+*/
+type KDTree struct {
+    Points *mat.Dense
+    root *kdNode
+}
+
+
+/*
+SUMMARY
+    Builds a balanced k-d tree, every node splits at the median along the coordinates in turn.
+PARAMETERS
+    Points *mat.Dense: the points, each row is a point
+RETURN
+    KDTree: the tree
+*/
+func NewKDTree(Points *mat.Dense) KDTree {
+    n, _ := Points.Dims()
+    indices := make([]int, n)
+    for i := range indices { indices[i] = i }
+    tree := KDTree{Points: Points}
+    tree.root = tree.build(indices, 0)
+    return tree
+}
+
+
+/*
+SUMMARY
+    Builds the subtree of some points recursively.
+PARAMETERS
+    Indices []int: the points of the subtree, reordered
+    Depth int: the depth of the subtree
+RETURN
+    *kdNode: the root of the subtree
+*/
+func (t KDTree) build(Indices []int, Depth int) *kdNode {
+    if len(Indices) == 0 { return nil }
+    _, d := t.Points.Dims()
+    dim := Depth % d
+    sort.Slice(Indices, func(a, b int) bool { return t.Points.At(Indices[a], dim) < t.Points.At(Indices[b], dim) })
+    median := len(Indices) / 2
+    return &kdNode{
+        Point: Indices[median],
+        Dim: dim,
+        Left: t.build(Indices[:median], Depth + 1),
+        Right: t.build(Indices[median+1:], Depth + 1),
+    }
+}
+
+
+/*
+SUMMARY
+    The squared Euclidean distance of an indexed point from a query.
+PARAMETERS
+    I int: the index of the point
+    X []float64: the query
+RETURN
+    float64: the squared distance
+*/
+func (t KDTree) squaredDistance(I int, X []float64) float64 {
+    d := 0.0
+    for k, x := range X {
+        diff := t.Points.At(I, k) - x
+        d += diff * diff
+    }
+    return d
+}
+
+
+/*
+SUMMARY
+    Finds every indexed point within a radius of a query.
+PARAMETERS
+    X []float64: the query
+    Radius float64: the radius
+RETURN
+    []int: the indices of the points, the query itself is included if it is indexed
+*/
+func (t KDTree) RadiusSearch(X []float64, Radius float64) []int {
+    var found []int
+    var search func(node *kdNode)
+    search = func(node *kdNode) {
+        if node == nil { return }
+        if t.squaredDistance(node.Point, X) <= Radius * Radius { found = append(found, node.Point) }
+        diff := X[node.Dim] - t.Points.At(node.Point, node.Dim)
+        if diff <= Radius { search(node.Left) }
+        if diff >= -Radius { search(node.Right) }
+    }
+    search(t.root)
+    return found
+}
+
+
+/*
+SUMMARY
+    Finds the K indexed points closest to a query.
+PARAMETERS
+    X []float64: the query
+    K int: the number of neighbours
+RETURN
+    []int: the indices of the neighbours, the closest first
+    []float64: their distances from the query
+*/
+func (t KDTree) KNearest(X []float64, K int) ([]int, []float64) {
+    // the best candidates so far in increasing order of squared distance
+    var indices []int
+    var distances []float64
+    var search func(node *kdNode)
+    search = func(node *kdNode) {
+        if node == nil { return }
+        d := t.squaredDistance(node.Point, X)
+        if len(indices) < K || d < distances[len(distances)-1] {
+            at := sort.SearchFloat64s(distances, d)
+            indices = append(indices, 0)
+            distances = append(distances, 0)
+            copy(indices[at+1:], indices[at:])
+            copy(distances[at+1:], distances[at:])
+            indices[at], distances[at] = node.Point, d
+            if len(indices) > K {
+                indices, distances = indices[:K], distances[:K]
+            }
+        }
+        diff := X[node.Dim] - t.Points.At(node.Point, node.Dim)
+        near, far := node.Left, node.Right
+        if diff >= 0 { near, far = node.Right, node.Left }
+        search(near)
+        if len(indices) < K || diff * diff < distances[len(distances)-1] { search(far) }
+    }
+    search(t.root)
+    for i := range distances { distances[i] = math.Sqrt(distances[i]) }
+    return indices, distances
+}
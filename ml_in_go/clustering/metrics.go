@@ -0,0 +1,262 @@
+package clustering
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+)
+
+
+/*
+SUMMARY
+    The number of clusters, the largest label plus one. NOISE labels are ignored.
+PARAMETERS
+    Labels []int: the cluster of each point
+RETURN
+    int: the number of clusters
+*/
+func numClusters(Labels []int) int {
+    k := 0
+    for _, label := range Labels {
+        if label + 1 > k { k = label + 1 }
+    }
+    return k
+}
+
+
+/*
+SUMMARY
+    The centroid and the size of every cluster. NOISE points are ignored.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Labels []int: the cluster of each point
+RETURN
+    [][]float64: the centroids
+    []float64: the sizes
+*/
+func centroids(X *mat.Dense, Labels []int) ([][]float64, []float64) {
+    _, d := X.Dims()
+    k := numClusters(Labels)
+    centres := make([][]float64, k)
+    for c := range centres { centres[c] = make([]float64, d) }
+    sizes := make([]float64, k)
+    for i, label := range Labels {
+        if label == NOISE { continue }
+        floats.Add(centres[label], X.RawRowView(i))
+        sizes[label]++
+    }
+    for c := range centres {
+        if sizes[c] > 0 { floats.Scale(1.0 / sizes[c], centres[c]) }
+    }
+    return centres, sizes
+}
+
+
+/*
+SUMMARY
+    The silhouette of every point (Rousseeuw, Silhouettes: a Graphical Aid to the Interpretation and
+    Validation of Cluster Analysis), s = (b - a) / max(a, b) where a is the mean distance to the other
+    points of its cluster and b is the smallest mean distance to the points of another cluster.
+    It takes O(N^2) time.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Labels []int: the cluster of each point
+RETURN
+    []float64: the silhouettes in [-1,1], 0 for points alone in their cluster and for NOISE
+*/
+func SilhouetteSamples(X *mat.Dense, Labels []int) []float64 {
+    n, _ := X.Dims()
+    k := numClusters(Labels)
+    _, sizes := centroids(X, Labels)
+    silhouettes := make([]float64, n)
+    sums := make([]float64, k)
+    for i := range silhouettes {
+        if Labels[i] == NOISE || sizes[Labels[i]] < 2 { continue }
+        for c := range sums { sums[c] = 0.0 }
+        for j := range Labels {
+            if Labels[j] == NOISE || j == i { continue }
+            sums[Labels[j]] += floats.Distance(X.RawRowView(i), X.RawRowView(j), 2)
+        }
+        a := sums[Labels[i]] / (sizes[Labels[i]] - 1)
+        b := math.Inf(1)
+        for c := range sums {
+            if c != Labels[i] && sizes[c] > 0 { b = math.Min(b, sums[c] / sizes[c]) }
+        }
+        if math.IsInf(b, 1) { continue }
+        silhouettes[i] = (b - a) / math.Max(a, b)
+    }
+    return silhouettes
+}
+
+
+/*
+SUMMARY
+    The silhouette score, the mean silhouette of the points. Higher is better.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Labels []int: the cluster of each point
+RETURN
+    float64: the score in [-1,1]
+*/
+func Silhouette(X *mat.Dense, Labels []int) float64 {
+    return floats.Sum(SilhouetteSamples(X, Labels)) / float64(len(Labels))
+}
+
+
+/*
+SUMMARY
+    The Davies-Bouldin index, the average over the clusters of the largest ratio
+    (S_i + S_j) / |c_i - c_j| where S_i is the mean distance of the points of cluster i from its centroid c_i.
+    Lower is better.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Labels []int: the cluster of each point
+RETURN
+    float64: the index
+*/
+func DaviesBouldin(X *mat.Dense, Labels []int) float64 {
+    centres, sizes := centroids(X, Labels)
+    scatters := make([]float64, len(centres))
+    for i, label := range Labels {
+        if label == NOISE { continue }
+        scatters[label] += floats.Distance(X.RawRowView(i), centres[label], 2) / sizes[label]
+    }
+    index := 0.0
+    count := 0.0
+    for i := range centres {
+        if sizes[i] == 0 { continue }
+        worst := 0.0
+        for j := range centres {
+            if j == i || sizes[j] == 0 { continue }
+            worst = math.Max(worst, (scatters[i] + scatters[j]) / floats.Distance(centres[i], centres[j], 2))
+        }
+        index += worst
+        count++
+    }
+    return index / count
+}
+
+
+/*
+SUMMARY
+    The Calinski-Harabasz index, the ratio of the between and the within cluster dispersions
+    tr(B) / tr(W) * (N - K) / (K - 1). Higher is better.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Labels []int: the cluster of each point
+RETURN
+    float64: the index
+*/
+func CalinskiHarabasz(X *mat.Dense, Labels []int) float64 {
+    _, d := X.Dims()
+    centres, sizes := centroids(X, Labels)
+    mean := make([]float64, d)
+    total := floats.Sum(sizes)
+    for c := range centres { floats.AddScaled(mean, sizes[c] / total, centres[c]) }
+    between, within := 0.0, 0.0
+    k := 0.0
+    for c := range centres {
+        if sizes[c] == 0 { continue }
+        distance := floats.Distance(centres[c], mean, 2)
+        between += sizes[c] * distance * distance
+        k++
+    }
+    for i, label := range Labels {
+        if label == NOISE { continue }
+        distance := floats.Distance(X.RawRowView(i), centres[label], 2)
+        within += distance * distance
+    }
+    if k < 2 || within == 0 { return 0.0 }
+    return between / within * (total - k) / (k - 1)
+}
+
+
+/*
+SUMMARY
+    The contingency table of two labellings, NOISE is treated as a cluster of its own.
+PARAMETERS
+    Labels []int: the first labelling
+    Truth []int: the second labelling
+RETURN
+    [][]float64: the number of points in each pair of clusters
+    []float64: the row sums
+    []float64: the column sums
+*/
+func contingency(Labels, Truth []int) ([][]float64, []float64, []float64) {
+    if len(Labels) != len(Truth) { panic("Labellings of different lengths encountered") }
+    // NOISE moves to the last index
+    rows, cols := numClusters(Labels) + 1, numClusters(Truth) + 1
+    table := make([][]float64, rows)
+    for r := range table { table[r] = make([]float64, cols) }
+    rowSums := make([]float64, rows)
+    colSums := make([]float64, cols)
+    for i := range Labels {
+        r, c := Labels[i], Truth[i]
+        if r == NOISE { r = rows - 1 }
+        if c == NOISE { c = cols - 1 }
+        table[r][c]++
+        rowSums[r]++
+        colSums[c]++
+    }
+    return table, rowSums, colSums
+}
+
+
+/*
+SUMMARY
+    The adjusted Rand index (Hubert & Arabie, Comparing Partitions), the fraction of pairs of points on
+    which two labellings agree, corrected for chance. It is 1 for identical partitions and about 0 for
+    independent ones.
+PARAMETERS
+    Labels []int: the found clusters
+    Truth []int: the true classes
+RETURN
+    float64: the index
+*/
+func AdjustedRandIndex(Labels, Truth []int) float64 {
+    table, rowSums, colSums := contingency(Labels, Truth)
+    pairs := func (x float64) float64 { return x * (x - 1) / 2 }
+    index, rowPairs, colPairs := 0.0, 0.0, 0.0
+    for r := range table {
+        for _, count := range table[r] { index += pairs(count) }
+    }
+    for _, sum := range rowSums { rowPairs += pairs(sum) }
+    for _, sum := range colSums { colPairs += pairs(sum) }
+    expected := rowPairs * colPairs / pairs(float64(len(Labels)))
+    maximum := 0.5 * (rowPairs + colPairs)
+    if maximum == expected { return 1.0 }
+    return (index - expected) / (maximum - expected)
+}
+
+
+/*
+SUMMARY
+    The normalised mutual information of two labellings, I(Labels;Truth) / ((H(Labels) + H(Truth)) / 2).
+    It is 1 for identical partitions and 0 for independent ones.
+PARAMETERS
+    Labels []int: the found clusters
+    Truth []int: the true classes
+RETURN
+    float64: the normalised mutual information
+*/
+func NormalisedMutualInformation(Labels, Truth []int) float64 {
+    table, rowSums, colSums := contingency(Labels, Truth)
+    n := float64(len(Labels))
+    entropy := func (sums []float64) float64 {
+        h := 0.0
+        for _, sum := range sums {
+            if sum > 0 { h -= sum / n * math.Log(sum / n) }
+        }
+        return h
+    }
+    information := 0.0
+    for r := range table {
+        for c, count := range table[r] {
+            if count > 0 { information += count / n * math.Log(count * n / (rowSums[r] * colSums[c])) }
+        }
+    }
+    normaliser := 0.5 * (entropy(rowSums) + entropy(colSums))
+    if normaliser == 0 { return 1.0 }
+    return information / normaliser
+}
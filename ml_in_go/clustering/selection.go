@@ -0,0 +1,218 @@
+package clustering
+
+import (
+    "math"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+    "gonum.org/v1/gonum/stat"
+
+    "gonum.org/v1/plot"
+
+    "ml_playground/kmeans"
+    "ml_playground/plt"
+)
+
+
+/*
+This type configures the sweep over the number of clusters.
+    MinK int: the smallest number of clusters (2 if 0)
+    MaxK int: the largest number of clusters
+    Restarts int: the number of k-means runs for every K
+    Seed int: seed of k-means
+*/
+type SweepOptions struct {
+    MinK int
+    MaxK int
+    Restarts int
+    Seed int
+}
+
+
+/*
+This type stores the outcome of the sweep, the slices are indexed like Ks.
+    Ks []int: the numbers of clusters tried
+    Labels [][]int: the k-means clusters for every K
+    Inertias []float64: the within cluster sums of squares
+    Silhouettes []float64: the silhouette scores
+    CalinskiHarabasz []float64: the Calinski-Harabasz indices
+    DaviesBouldin []float64: the Davies-Bouldin indices
+    ElbowK int: the K at the elbow of the inertia curve
+    SilhouetteK int: the K with the highest silhouette score
+*/
+type SweepResult struct {
+    Ks []int
+    Labels [][]int
+    Inertias []float64
+    Silhouettes []float64
+    CalinskiHarabasz []float64
+    DaviesBouldin []float64
+    ElbowK int
+    SilhouetteK int
+}
+
+
+/*
+SUMMARY
+    Runs k-means on the data.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    K int: the number of clusters
+    Restarts int: the number of k-means runs
+    Seed int: seed of k-means
+RETURN
+    kmeans.KMeansResult: the best run
+*/
+func runKMeans(X *mat.Dense, K, Restarts, Seed int) kmeans.KMeansResult {
+    return kmeans.KMeans(mat.DenseCopyOf(X.T()), kmeans.KMeansOptions{NumClasses: K, Restarts: Restarts, Tolerance: 1e-6, Seed: Seed})
+}
+
+
+/*
+SUMMARY
+    Finds the elbow of a decreasing curve: the point farthest from the chord joining its ends,
+    after both axes are scaled to [0,1].
+PARAMETERS
+    Xs []float64: the x coordinates, increasing
+    Ys []float64: the y coordinates
+RETURN
+    int: the index of the elbow
+*/
+func elbow(Xs, Ys []float64) int {
+    last := len(Xs) - 1
+    if last < 2 { return 0 }
+    yMin, yMax := floats.Min(Ys), floats.Max(Ys)
+    scaled := func (i int) (float64, float64) {
+        return (Xs[i] - Xs[0]) / (Xs[last] - Xs[0]), (Ys[i] - yMin) / math.Max(yMax - yMin, 1e-300)
+    }
+    // the chord goes from (0,y0) to (1,y1)
+    _, y0 := scaled(0)
+    _, y1 := scaled(last)
+    best, bestDistance := 0, -1.0
+    for i := range Xs {
+        x, y := scaled(i)
+        distance := math.Abs((y1 - y0) * x - y + y0) / math.Hypot(y1 - y0, 1.0)
+        if distance > bestDistance { best, bestDistance = i, distance }
+    }
+    return best
+}
+
+
+/*
+SUMMARY
+    Clusters the data with k-means for every K in [MinK, MaxK] and scores the clusterings,
+    so that K can be chosen at the elbow of the inertia or at the highest silhouette.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Options SweepOptions: the settings
+RETURN
+    SweepResult: the scores and the chosen numbers of clusters
+*/
+func SweepK(X *mat.Dense, Options SweepOptions) SweepResult {
+    minK := Options.MinK
+    if minK <= 0 { minK = 2 }
+    if Options.MaxK < minK { panic("MaxK smaller than MinK encountered") }
+    var result SweepResult
+    var ks []float64
+    for k:=minK; k<=Options.MaxK; k++ {
+        run := runKMeans(X, k, Options.Restarts, Options.Seed)
+        result.Ks = append(result.Ks, k)
+        result.Labels = append(result.Labels, run.Labels)
+        result.Inertias = append(result.Inertias, run.Inertia)
+        result.Silhouettes = append(result.Silhouettes, Silhouette(X, run.Labels))
+        result.CalinskiHarabasz = append(result.CalinskiHarabasz, CalinskiHarabasz(X, run.Labels))
+        result.DaviesBouldin = append(result.DaviesBouldin, DaviesBouldin(X, run.Labels))
+        ks = append(ks, float64(k))
+    }
+    result.ElbowK = result.Ks[elbow(ks, result.Inertias)]
+    result.SilhouetteK = result.Ks[floats.MaxIdx(result.Silhouettes)]
+    return result
+}
+
+
+/*
+SUMMARY
+    The gap statistic (Tibshirani, Walther & Hastie, Estimating the Number of Clusters in a Data Set via
+    the Gap Statistic): Gap(K) = E[log W*_K] - log W_K where W_K is the k-means inertia and W*_K is the
+    inertia of uniform data in the bounding box. The chosen K is the smallest with Gap(K) >= Gap(K+1) - s_{K+1}.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    MaxK int: the largest number of clusters, K runs from 1
+    NumReferences int: the number of uniform reference data sets
+    Seed int: seed of the reference data and of k-means
+RETURN
+    []float64: the gaps for K = 1, ..., MaxK
+    []float64: the standard errors s_K
+    int: the chosen K
+*/
+func GapStatistic(X *mat.Dense, MaxK, NumReferences int, Seed int) ([]float64, []float64, int) {
+    n, d := X.Dims()
+    randGen := rand.New(rand.NewSource(uint64(Seed)))
+    lows, highs := make([]float64, d), make([]float64, d)
+    for j:=0; j<d; j++ {
+        column := mat.Col(nil, j, X)
+        lows[j], highs[j] = floats.Min(column), floats.Max(column)
+    }
+    references := make([]*mat.Dense, NumReferences)
+    for b := range references {
+        references[b] = mat.NewDense(n, d, nil)
+        references[b].Apply(func (i, j int, v float64) float64 { return lows[j] + randGen.Float64() * (highs[j] - lows[j]) }, references[b])
+    }
+    gaps := make([]float64, MaxK)
+    errors := make([]float64, MaxK)
+    logs := make([]float64, NumReferences)
+    for k:=1; k<=MaxK; k++ {
+        for b, reference := range references {
+            logs[b] = math.Log(runKMeans(reference, k, 1, Seed).Inertia)
+        }
+        mean, sd := stat.PopMeanStdDev(logs, nil)
+        gaps[k-1] = mean - math.Log(runKMeans(X, k, 1, Seed).Inertia)
+        errors[k-1] = sd * math.Sqrt(1.0 + 1.0 / float64(NumReferences))
+    }
+    chosen := MaxK
+    for k:=1; k<MaxK; k++ {
+        if gaps[k-1] >= gaps[k] - errors[k] {
+            chosen = k
+            break
+        }
+    }
+    return gaps, errors, chosen
+}
+
+
+/*
+SUMMARY
+    Creates a plot of the inertia and of the silhouette score against K, both scaled to [0,1],
+    with markers at the elbow and at the best silhouette.
+PARAMETERS
+    Result SweepResult: the outcome of SweepK
+RETURN
+    *plot.Plot: the resulting plot
+*/
+func SweepPlot(Result SweepResult) *plot.Plot {
+    ks := make([]float64, len(Result.Ks))
+    for i, k := range Result.Ks { ks[i] = float64(k) }
+    scale := func (Ys []float64) []float64 {
+        scaled := make([]float64, len(Ys))
+        low, high := floats.Min(Ys), floats.Max(Ys)
+        for i, y := range Ys { scaled[i] = (y - low) / math.Max(high - low, 1e-300) }
+        return scaled
+    }
+    inertias, silhouettes := scale(Result.Inertias), scale(Result.Silhouettes)
+    p := plot.New()
+    p.Title.Text = "Choice of the number of clusters"
+    p.X.Label.Text, p.Y.Label.Text = "K", "scaled score"
+    inertiaLine := plt.MakeLineUnicorn(ks, inertias, 2.0, 0x1f3a93ff, []float64{})
+    silhouetteLine := plt.MakeLineUnicorn(ks, silhouettes, 2.0, 0xc0392bff, []float64{})
+    p.Add(inertiaLine, silhouetteLine)
+    p.Legend.Add("inertia", inertiaLine)
+    p.Legend.Add("silhouette", silhouetteLine)
+    var markerX, markerY []float64
+    for i, k := range Result.Ks {
+        if k == Result.ElbowK { markerX, markerY = append(markerX, ks[i]), append(markerY, inertias[i]) }
+        if k == Result.SilhouetteK { markerX, markerY = append(markerX, ks[i]), append(markerY, silhouettes[i]) }
+    }
+    p.Add(plt.MakeScatterUnicorn(markerX, markerY, plt.CIRCLE_POINT_MARKER, 4.0, plt.DesignedPalette{Type: plt.UNI_PALETTE, Num: len(markerX), Extra: 0x000000ff}))
+    return p
+}
@@ -0,0 +1,107 @@
+package clustering
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+
+    "ml_playground/kernels"
+    "ml_playground/kmeans"
+)
+
+
+/*
+This type configures spectral clustering.
+    NumClusters int: the number of clusters
+    Kernel kernels.Parameters: the kernel giving the affinities of the points
+    NumNeighbours int: if positive, only the affinities of the mutual NumNeighbours nearest neighbours are kept
+    Seed int: seed of the k-means step
+*/
+type SpectralOptions struct {
+    NumClusters int
+    Kernel kernels.Parameters
+    NumNeighbours int
+    Seed int
+}
+
+
+/*
+SUMMARY
+    The affinity matrix of the points: the kernel with zero diagonal, optionally restricted
+    to pairs of points which are both among the nearest neighbours of the other.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Options SpectralOptions: the settings
+RETURN
+    *mat.SymDense: the affinities
+*/
+func affinities(X *mat.Dense, Options SpectralOptions) *mat.SymDense {
+    n, _ := X.Dims()
+    kernel := kernels.Kernel(X, X, Options.Kernel)
+    W := mat.NewSymDense(n, nil)
+    var neighbours []map[int]bool
+    if Options.NumNeighbours > 0 {
+        tree := NewKDTree(X)
+        neighbours = make([]map[int]bool, n)
+        for i := range neighbours {
+            // the closest point is the point itself
+            indices, _ := tree.KNearest(X.RawRowView(i), Options.NumNeighbours + 1)
+            neighbours[i] = map[int]bool{}
+            for _, j := range indices { neighbours[i][j] = true }
+        }
+    }
+    for i:=0; i<n; i++ {
+        for j:=i+1; j<n; j++ {
+            if neighbours != nil && !(neighbours[i][j] && neighbours[j][i]) { continue }
+            W.SetSym(i, j, kernel.At(i, j))
+        }
+    }
+    return W
+}
+
+
+/*
+SUMMARY
+    Implements spectral clustering (Ng, Jordan & Weiss, On Spectral Clustering: Analysis and an Algorithm).
+    The points are embedded with the leading eigenvectors of the normalised affinity matrix
+    D^{-1/2} W D^{-1/2}, the rows of the embedding are normalised to unit length and clustered by k-means.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Options SpectralOptions: the settings
+RETURN
+    []int: the cluster of each point
+    *mat.Dense: N by NumClusters matrix, the spectral embedding
+*/
+func SpectralClustering(X *mat.Dense, Options SpectralOptions) ([]int, *mat.Dense) {
+    n, _ := X.Dims()
+    if Options.NumClusters <= 0 || Options.NumClusters > n { panic("Number of clusters outside [1, #points] encountered") }
+    W := affinities(X, Options)
+    degrees := make([]float64, n)
+    for i := range degrees {
+        for j:=0; j<n; j++ { degrees[i] += W.At(i, j) }
+        // an isolated point has no affinity at all
+        degrees[i] = 1.0 / math.Sqrt(math.Max(degrees[i], 1e-12))
+    }
+    normalised := mat.NewSymDense(n, nil)
+    for i:=0; i<n; i++ {
+        for j:=i; j<n; j++ { normalised.SetSym(i, j, degrees[i] * W.At(i, j) * degrees[j]) }
+    }
+    var eigen mat.EigenSym
+    if ok := eigen.Factorize(normalised, true); !ok { panic("Eigendecomposition failed") }
+    var vectors mat.Dense
+    eigen.VectorsTo(&vectors)
+    // the eigenvalues are in increasing order, the leading ones are the last columns
+    embedding := mat.DenseCopyOf(vectors.Slice(0, n, n - Options.NumClusters, n))
+    for i:=0; i<n; i++ {
+        row := embedding.RawRowView(i)
+        if norm := floats.Norm(row, 2); norm > 0 { floats.Scale(1.0 / norm, row) }
+    }
+    result := kmeans.KMeans(mat.DenseCopyOf(embedding.T()), kmeans.KMeansOptions{
+        NumClasses: Options.NumClusters,
+        Restarts: 10,
+        Tolerance: 1e-8,
+        Seed: Options.Seed,
+    })
+    return result.Labels, embedding
+}
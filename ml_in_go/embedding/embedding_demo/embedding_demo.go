@@ -0,0 +1,140 @@
+package main
+
+import (
+    "fmt"
+    "math"
+    "time"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+    "gonum.org/v1/gonum/stat/distuv"
+
+    "gonum.org/v1/plot"
+    "gonum.org/v1/plot/vg"
+    "gonum.org/v1/plot/text"
+    "gonum.org/v1/plot/font"
+    "gonum.org/v1/plot/font/liberation"
+
+    "ml_playground/clustering"
+    "ml_playground/embedding"
+    "ml_playground/plt"
+)
+
+// random number seed and source
+var randSeed = 10
+var randSrc = rand.NewSource(uint64(randSeed))
+
+
+/*
+SUMMARY
+    Generates a spiral in 2d and embeds it linearly in a higher dimensional space with noise,
+    as in the PCA and GPLVM programs.
+PARAMETERS
+    Num int: the number of points
+    Dimensions int: the dimension of the observed space
+    Noise float64: the standard deviation of the noise
+RETURN
+    *mat.Dense: Num by 2 matrix, the spiral
+    *mat.Dense: Num by Dimensions matrix, the observed points
+*/
+func GenerateSpiral(Num, Dimensions int, Noise float64) (*mat.Dense, *mat.Dense) {
+    X := mat.NewDense(Num, 2, nil)
+    for y:=0; y<Num; y++ {
+        t := 2.0 + float64(y) / float64(Num) * 3.0 * math.Pi
+        X.Set(y, 0, t * math.Sin(t))
+        X.Set(y, 1, t * math.Cos(t))
+    }
+    normal := distuv.Normal{Mu: 0, Sigma: 1, Src: randSrc}
+    W := mat.NewDense(Dimensions, 2, nil)
+    W.Apply(func (i, j int, v float64) float64 { return normal.Rand() }, W)
+    Y := mat.NewDense(Num, Dimensions, nil)
+    Y.Mul(X, W.T())
+    Y.Apply(func (i, j int, v float64) float64 { return v + Noise * normal.Rand() }, Y)
+    return X, Y
+}
+
+
+/*
+SUMMARY
+    The average fraction of the K nearest neighbours of a point in the original space which are also
+    among its K nearest neighbours in the embedding.
+PARAMETERS
+    Original *mat.Dense: the original points, each row is a point
+    Embedded *mat.Dense: the embedded points, each row is a point
+    K int: the number of neighbours
+RETURN
+    float64: 1 if every neighbourhood is preserved
+*/
+func NeighbourPreservation(Original, Embedded *mat.Dense, K int) float64 {
+    n, _ := Original.Dims()
+    originalTree, embeddedTree := clustering.NewKDTree(Original), clustering.NewKDTree(Embedded)
+    preserved := 0
+    for i:=0; i<n; i++ {
+        before, _ := originalTree.KNearest(Original.RawRowView(i), K + 1)
+        after, _ := embeddedTree.KNearest(Embedded.RawRowView(i), K + 1)
+        neighbours := map[int]bool{}
+        for _, j := range after { neighbours[j] = true }
+        for _, j := range before {
+            if j != i && neighbours[j] { preserved++ }
+        }
+    }
+    return float64(preserved) / float64(n * K)
+}
+
+
+/*
+SUMMARY
+    Saves the density and the scatter plot of an embedding with the plotting of the PCA and GPLVM
+    programs, the density puts an isotropic Gaussian with 2% of the extent of the embedding on every point.
+PARAMETERS
+    Embedded *mat.Dense: N by 2 matrix, the embedded points
+    Name string: the name of the method in the titles
+    FileName string: the prefix of the files
+RETURN
+    N/A
+*/
+func SavePlots(Embedded *mat.Dense, Name, FileName string) {
+    extent := math.Max(floats.Max(mat.Col(nil, 0, Embedded)) - floats.Min(mat.Col(nil, 0, Embedded)),
+                       floats.Max(mat.Col(nil, 1, Embedded)) - floats.Min(mat.Col(nil, 1, Embedded)))
+    variance := math.Pow(0.02 * extent, 2.0)
+    covariance := mat.NewSymDense(2, []float64{variance, 0.0, 0.0, variance})
+    p := plt.LatentDensityPlot(Embedded, []*mat.SymDense{covariance}, fmt.Sprintf(`Density Plot of the Embedding (%s)`, Name))
+    p.Save(4*vg.Inch, 4*vg.Inch, FileName + "_density_plot.png")
+    p = plt.LatentScatterPlot(Embedded, fmt.Sprintf("Scatter Plot of the Embedding (%s)", Name))
+    p.Save(300, 300, FileName + "_scatter_plot.svg")
+}
+
+
+/*
+We generate a spiral, embed it in the 10d space, and recover a 2d embedding with
+t-SNE (exact and Barnes-Hut), Isomap and locally linear embedding. For each method
+we print the fraction of the 10 nearest neighbours on the spiral which stay neighbours.
+*/
+func main() {
+    fonts := font.NewCache(liberation.Collection())
+	plot.DefaultTextHandler = text.Latex{
+		Fonts: fonts,
+	}
+    X, Y := GenerateSpiral(600, 10, 0.1)
+
+    for _, theta := range []float64{-1, 0.5} {
+        start := time.Now()
+        result := embedding.TSNE(Y, embedding.TSNEOptions{Perplexity: 20, Theta: theta, Seed: randSeed})
+        name := "exact t-SNE"
+        if theta > 0 { name = "Barnes-Hut t-SNE" }
+        fmt.Printf("%-16s %6v, KL divergence %.3f, preserved neighbours %.3f\n", name, time.Since(start).Round(time.Millisecond),
+            result.KLDivergence, NeighbourPreservation(X, result.Embedding, 10))
+        if theta > 0 { SavePlots(result.Embedding, "t-SNE", "tsne") }
+    }
+
+    start := time.Now()
+    isomap := embedding.Isomap(Y, 8, 2)
+    fmt.Printf("%-16s %6v, preserved neighbours %.3f\n", "Isomap", time.Since(start).Round(time.Millisecond), NeighbourPreservation(X, isomap, 10))
+    SavePlots(isomap, "Isomap", "isomap")
+
+    start = time.Now()
+    lle := embedding.LocallyLinearEmbedding(Y, 10, 2, 1e-3)
+    fmt.Printf("%-16s %6v, preserved neighbours %.3f\n", "LLE", time.Since(start).Round(time.Millisecond), NeighbourPreservation(X, lle, 10))
+    SavePlots(lle, "LLE", "lle")
+}
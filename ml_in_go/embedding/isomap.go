@@ -0,0 +1,147 @@
+package embedding
+
+import (
+    "math"
+    "container/heap"
+
+    "gonum.org/v1/gonum/mat"
+)
+
+
+/*
+This type is an entry of the priority queue of Dijkstra's algorithm, the distance is copied
+when the entry is pushed, so the heap order stays valid when the vertex gets closer later.
+    Vertex int: the vertex
+    Distance float64: the tentative distance of the vertex when it was pushed
+*/
+type queueEntry struct {
+    Vertex int
+    Distance float64
+}
+
+
+/*
+This type is a priority queue of vertices ordered by their tentative distances for Dijkstra's algorithm.
+*/
+type vertexQueue []queueEntry
+
+func (q vertexQueue) Len() int { return len(q) }
+func (q vertexQueue) Less(i, j int) bool { return q[i].Distance < q[j].Distance }
+func (q vertexQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *vertexQueue) Push(x interface{}) { *q = append(*q, x.(queueEntry)) }
+func (q *vertexQueue) Pop() interface{} {
+    last := (*q)[len(*q)-1]
+    *q = (*q)[:len(*q)-1]
+    return last
+}
+
+
+/*
+SUMMARY
+    The shortest path distances from one vertex of a weighted graph with Dijkstra's algorithm.
+    A vertex may enter the queue several times, the outdated entries, whose distance is larger
+    than the current one, are skipped.
+PARAMETERS
+    Neighbours [][]int: the adjacency lists
+    Weights [][]float64: the lengths of the edges in the adjacency lists
+    Source int: the vertex the paths start from
+RETURN
+    []float64: the distances, +Inf for unreachable vertices
+*/
+func shortestPaths(Neighbours [][]int, Weights [][]float64, Source int) []float64 {
+    distances := make([]float64, len(Neighbours))
+    for i := range distances { distances[i] = math.Inf(1) }
+    distances[Source] = 0
+    queue := &vertexQueue{{Vertex: Source, Distance: 0}}
+    for queue.Len() > 0 {
+        entry := heap.Pop(queue).(queueEntry)
+        u := entry.Vertex
+        if entry.Distance > distances[u] { continue }
+        for e, v := range Neighbours[u] {
+            if d := distances[u] + Weights[u][e]; d < distances[v] {
+                distances[v] = d
+                heap.Push(queue, queueEntry{Vertex: v, Distance: d})
+            }
+        }
+    }
+    return distances
+}
+
+
+/*
+SUMMARY
+    Classical multidimensional scaling: the double centred matrix B = -1/2 J D^2 J of the squared
+    distances is the Gram matrix of centred points, whose coordinates are the leading eigenvectors
+    of B scaled by the square roots of the eigenvalues.
+PARAMETERS
+    Distances *mat.SymDense: the pairwise distances
+    Dimensions int: the dimension of the embedding
+RETURN
+    *mat.Dense: N by Dimensions matrix, the embedded points
+*/
+func ClassicalMDS(Distances *mat.SymDense, Dimensions int) *mat.Dense {
+    n := Distances.Symmetric()
+    if Dimensions <= 0 || Dimensions > n { panic("Dimensions outside [1, #points] encountered") }
+    squared := mat.NewDense(n, n, nil)
+    squared.Apply(func (i, j int, v float64) float64 { return v * v }, Distances)
+    rowMeans := make([]float64, n)
+    total := 0.0
+    for i:=0; i<n; i++ {
+        for j:=0; j<n; j++ { rowMeans[i] += squared.At(i, j) }
+        total += rowMeans[i]
+        rowMeans[i] /= float64(n)
+    }
+    total /= float64(n * n)
+    B := mat.NewSymDense(n, nil)
+    for i:=0; i<n; i++ {
+        for j:=i; j<n; j++ { B.SetSym(i, j, -0.5 * (squared.At(i, j) - rowMeans[i] - rowMeans[j] + total)) }
+    }
+    var eigen mat.EigenSym
+    if ok := eigen.Factorize(B, true); !ok { panic("Eigendecomposition failed") }
+    values := eigen.Values(nil)
+    var vectors mat.Dense
+    eigen.VectorsTo(&vectors)
+    // the eigenvalues are in increasing order
+    embedded := mat.NewDense(n, Dimensions, nil)
+    for c:=0; c<Dimensions; c++ {
+        scale := math.Sqrt(math.Max(values[n-1-c], 0.0))
+        for i:=0; i<n; i++ { embedded.Set(i, c, scale * vectors.At(i, n-1-c)) }
+    }
+    return embedded
+}
+
+
+/*
+SUMMARY
+    Implements Isomap (Tenenbaum, de Silva & Langford, A Global Geometric Framework for Nonlinear
+    Dimensionality Reduction). The geodesic distances along the manifold are approximated by the
+    shortest paths in the symmetrised nearest neighbour graph, then embedded with classical MDS.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    NumNeighbours int: the number of nearest neighbours joined to each point
+    Dimensions int: the dimension of the embedding
+RETURN
+    *mat.Dense: N by Dimensions matrix, the embedded points
+*/
+func Isomap(X *mat.Dense, NumNeighbours, Dimensions int) *mat.Dense {
+    n, _ := X.Dims()
+    indices, lengths := nearestNeighbours(X, NumNeighbours)
+    // an edge is in the graph if either end is among the nearest neighbours of the other
+    neighbours := make([][]int, n)
+    weights := make([][]float64, n)
+    for i:=0; i<n; i++ {
+        for e, j := range indices[i] {
+            neighbours[i], weights[i] = append(neighbours[i], j), append(weights[i], lengths[i][e])
+            neighbours[j], weights[j] = append(neighbours[j], i), append(weights[j], lengths[i][e])
+        }
+    }
+    geodesics := mat.NewSymDense(n, nil)
+    for i:=0; i<n; i++ {
+        distances := shortestPaths(neighbours, weights, i)
+        for j:=i+1; j<n; j++ {
+            if math.IsInf(distances[j], 1) { panic("Disconnected neighbourhood graph encountered, increase the number of neighbours") }
+            geodesics.SetSym(i, j, distances[j])
+        }
+    }
+    return ClassicalMDS(geodesics, Dimensions)
+}
@@ -0,0 +1,62 @@
+package embedding
+
+import (
+    "math"
+    "testing"
+    "golang.org/x/exp/rand"
+)
+
+
+/*
+SUMMARY
+    The shortest path distances between every pair of vertices with the Floyd-Warshall algorithm.
+PARAMETERS
+    Neighbours [][]int: the adjacency lists
+    Weights [][]float64: the lengths of the edges in the adjacency lists
+RETURN
+    [][]float64: the distances, +Inf for unreachable pairs
+*/
+func floydWarshall(Neighbours [][]int, Weights [][]float64) [][]float64 {
+    n := len(Neighbours)
+    distances := make([][]float64, n)
+    for i := range distances {
+        distances[i] = make([]float64, n)
+        for j := range distances[i] { distances[i][j] = math.Inf(1) }
+        distances[i][i] = 0
+        for e, j := range Neighbours[i] { distances[i][j] = math.Min(distances[i][j], Weights[i][e]) }
+    }
+    for k:=0; k<n; k++ {
+        for i:=0; i<n; i++ {
+            for j:=0; j<n; j++ { distances[i][j] = math.Min(distances[i][j], distances[i][k] + distances[k][j]) }
+        }
+    }
+    return distances
+}
+
+
+func TestShortestPaths(t *testing.T) {
+    randGen := rand.New(rand.NewSource(1))
+    for trial:=0; trial<1000; trial++ {
+        n := 2 + randGen.Intn(11)
+        probability := 0.1 + 0.5 * randGen.Float64()
+        neighbours, weights := make([][]int, n), make([][]float64, n)
+        for i:=0; i<n; i++ {
+            for j:=i+1; j<n; j++ {
+                if randGen.Float64() < probability {
+                    w := randGen.Float64()
+                    neighbours[i], weights[i] = append(neighbours[i], j), append(weights[i], w)
+                    neighbours[j], weights[j] = append(neighbours[j], i), append(weights[j], w)
+                }
+            }
+        }
+        exact := floydWarshall(neighbours, weights)
+        for source:=0; source<n; source++ {
+            distances := shortestPaths(neighbours, weights, source)
+            for v, d := range distances {
+                if !(d == exact[source][v] || math.Abs(d - exact[source][v]) < 1e-12) {
+                    t.Fatalf("trial %d: distance from %d to %d is %g instead of %g", trial, source, v, d, exact[source][v])
+                }
+            }
+        }
+    }
+}
@@ -0,0 +1,65 @@
+package embedding
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+)
+
+
+/*
+SUMMARY
+    Implements locally linear embedding (Roweis & Saul, Nonlinear Dimensionality Reduction by Locally
+    Linear Embedding). Every point is written as the affine combination of its neighbours with the
+    smallest error, then the embedding which keeps these weights best is found from the bottom
+    eigenvectors of M = (I - W)^T (I - W), skipping the constant one.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    NumNeighbours int: the number of neighbours reconstructing each point
+    Dimensions int: the dimension of the embedding
+    Regularisation float64: added to the diagonal of the local Gram matrices relative to their
+        trace, needed when NumNeighbours exceeds the dimension of the data (1e-3 if 0)
+RETURN
+    *mat.Dense: N by Dimensions matrix, the embedded points with unit covariance
+*/
+func LocallyLinearEmbedding(X *mat.Dense, NumNeighbours, Dimensions int, Regularisation float64) *mat.Dense {
+    n, d := X.Dims()
+    if Dimensions <= 0 || Dimensions >= n { panic("Dimensions outside [1, #points) encountered") }
+    if Regularisation <= 0 { Regularisation = 1e-3 }
+    indices, _ := nearestNeighbours(X, NumNeighbours)
+    k := NumNeighbours
+
+    // I - W, row i holds the reconstruction of point i
+    residual := mat.NewDense(n, n, nil)
+    differences := mat.NewDense(k, d, nil)
+    gram := mat.NewSymDense(k, nil)
+    ones := mat.NewVecDense(k, nil)
+    for a:=0; a<k; a++ { ones.SetVec(a, 1.0) }
+    for i:=0; i<n; i++ {
+        for a, j := range indices[i] {
+            floats.SubTo(differences.RawRowView(a), X.RawRowView(j), X.RawRowView(i))
+        }
+        gram.SymOuterK(1.0, differences)
+        ridge := Regularisation * math.Max(mat.Trace(gram), 1e-12)
+        for a:=0; a<k; a++ { gram.SetSym(a, a, gram.At(a, a) + ridge) }
+        var chol mat.Cholesky
+        if ok := chol.Factorize(gram); !ok { panic("Cholesky factorisation failed") }
+        var weights mat.VecDense
+        if err := chol.SolveVecTo(&weights, ones); err != nil { panic(err) }
+        weights.ScaleVec(1.0 / mat.Sum(&weights), &weights)
+        residual.Set(i, i, 1.0)
+        for a, j := range indices[i] { residual.Set(i, j, residual.At(i, j) - weights.AtVec(a)) }
+    }
+
+    M := mat.NewSymDense(n, nil)
+    M.SymOuterK(1.0, residual.T())
+    var eigen mat.EigenSym
+    if ok := eigen.Factorize(M, true); !ok { panic("Eigendecomposition failed") }
+    var vectors mat.Dense
+    eigen.VectorsTo(&vectors)
+    // the eigenvalues are in increasing order, the first eigenvector is constant
+    embedded := mat.NewDense(n, Dimensions, nil)
+    embedded.Apply(func (i, c int, v float64) float64 { return math.Sqrt(float64(n)) * vectors.At(i, c+1) }, embedded)
+    return embedded
+}
@@ -0,0 +1,41 @@
+/*
+This library contains nonlinear dimensionality reduction: t-SNE, Isomap and locally linear
+embedding. The data points are the rows of the data matrices, the embeddings are N by
+Dimensions matrices whose rows are the embedded points.
+*/
+package embedding
+
+import (
+    "gonum.org/v1/gonum/mat"
+
+    "ml_playground/clustering"
+)
+
+
+/*
+SUMMARY
+    Finds the nearest neighbours of every point with a k-d tree.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    K int: the number of neighbours
+RETURN
+    [][]int: the indices of the neighbours of each point, the closest first, the point itself excluded
+    [][]float64: their distances
+*/
+func nearestNeighbours(X *mat.Dense, K int) ([][]int, [][]float64) {
+    n, _ := X.Dims()
+    if K <= 0 || K >= n { panic("Number of neighbours outside [1, #points) encountered") }
+    tree := clustering.NewKDTree(X)
+    indices := make([][]int, n)
+    distances := make([][]float64, n)
+    for i:=0; i<n; i++ {
+        // the point itself is among the K+1 closest points, unless it has K duplicates
+        candidates, lengths := tree.KNearest(X.RawRowView(i), K + 1)
+        for c, j := range candidates {
+            if j == i || len(indices[i]) == K { continue }
+            indices[i] = append(indices[i], j)
+            distances[i] = append(distances[i], lengths[c])
+        }
+    }
+    return indices, distances
+}
@@ -0,0 +1,403 @@
+package embedding
+
+import (
+    "math"
+    "sort"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+    "gonum.org/v1/gonum/stat/distuv"
+)
+
+
+/*
+This type configures t-SNE, the zero fields take the default values.
+    Dimensions int: the dimension of the embedding (2)
+    Perplexity float64: the effective number of neighbours of a point (30)
+    Iterations int: the number of gradient steps (1000)
+    LearningRate float64: the step size (200)
+    Exaggeration float64: the factor of the attraction during the first iterations (12)
+    ExaggerationIterations int: the number of exaggerated iterations (250)
+    Theta float64: the opening angle of the Barnes-Hut approximation, negative for the exact
+        O(N^2) repulsion (0.5)
+    Seed int: seed of the initial embedding
+*/
+type TSNEOptions struct {
+    Dimensions int
+    Perplexity float64
+    Iterations int
+    LearningRate float64
+    Exaggeration float64
+    ExaggerationIterations int
+    Theta float64
+    Seed int
+}
+
+
+/*
+This type stores the outcome of t-SNE.
+    Embedding *mat.Dense: N by Dimensions matrix, the embedded points
+    KLDivergence float64: the Kullback-Leibler divergence KL(P||Q) of the final embedding, with the exact normalisation even in Barnes-Hut mode
+*/
+type TSNEResult struct {
+    Embedding *mat.Dense
+    KLDivergence float64
+}
+
+
+/*
+This type stores the symmetric input similarities P as sparse rows.
+    Columns [][]int: the nonzero columns of each row
+    Values [][]float64: the similarities in these columns
+*/
+type sparseAffinities struct {
+    Columns [][]int
+    Values [][]float64
+}
+
+
+/*
+SUMMARY
+    The input similarities of t-SNE. The conditional p_{j|i} is a Gaussian of the distance restricted
+    to the 3 Perplexity nearest neighbours, its bandwidth is found by bisection so that the entropy
+    of p_{.|i} is log(Perplexity). The result is symmetrised, P_ij = (p_{j|i} + p_{i|j}) / 2N.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Perplexity float64: the effective number of neighbours
+RETURN
+    sparseAffinities: the similarities
+*/
+func inputAffinities(X *mat.Dense, Perplexity float64) sparseAffinities {
+    n, _ := X.Dims()
+    k := int(math.Min(math.Floor(3.0 * Perplexity), float64(n - 1)))
+    indices, distances := nearestNeighbours(X, k)
+    target := math.Log(Perplexity)
+    rows := make([]map[int]float64, n)
+    for i := range rows { rows[i] = map[int]float64{} }
+    conditional := make([]float64, k)
+    for i:=0; i<n; i++ {
+        // the distances relative to the closest neighbour avoid underflow
+        squared := make([]float64, k)
+        for a, d := range distances[i] { squared[a] = d * d - distances[i][0] * distances[i][0] }
+        beta, low, high := 1.0, 0.0, math.Inf(1)
+        for step:=0; step<100; step++ {
+            for a, s := range squared { conditional[a] = math.Exp(-beta * s) }
+            total := floats.Sum(conditional)
+            entropy := 0.0
+            for a, p := range conditional {
+                entropy += beta * squared[a] * p / total
+            }
+            entropy += math.Log(total)
+            if math.Abs(entropy - target) < 1e-5 { break }
+            if entropy > target {
+                low = beta
+                if math.IsInf(high, 1) { beta *= 2 } else { beta = 0.5 * (beta + high) }
+            } else {
+                high = beta
+                beta = 0.5 * (beta + low)
+            }
+        }
+        floats.Scale(1.0 / floats.Sum(conditional), conditional)
+        for a, j := range indices[i] {
+            rows[i][j] += conditional[a] / float64(2 * n)
+            rows[j][i] += conditional[a] / float64(2 * n)
+        }
+    }
+    var P sparseAffinities
+    for _, row := range rows {
+        var columns []int
+        for j := range row { columns = append(columns, j) }
+        // sorted columns make the sums independent of the order of the map
+        sort.Ints(columns)
+        values := make([]float64, len(columns))
+        for a, j := range columns { values[a] = row[j] }
+        P.Columns, P.Values = append(P.Columns, columns), append(P.Values, values)
+    }
+    return P
+}
+
+
+/*
+This type is a cell of the space partitioning tree of the Barnes-Hut approximation, a quadtree in
+2 dimensions and an octree in 3 dimensions.
+    Centre []float64: the centre of the cell
+    HalfWidth float64: half of the side length of the cell
+    CentreOfMass []float64: the mean of the points in the cell
+    Count int: the number of points in the cell
+    Point int: the index of a point if the cell is a leaf with points, -1 otherwise
+    Children []*bhCell: the 2^Dimensions subcells, nil for a leaf
+*/
+type bhCell struct {
+    Centre []float64
+    HalfWidth float64
+    CentreOfMass []float64
+    Count int
+    Point int
+    Children []*bhCell
+}
+
+
+/*
+SUMMARY
+    Creates the tree of the embedded points.
+PARAMETERS
+    Y *mat.Dense: the embedded points, each row is a point
+RETURN
+    *bhCell: the root cell containing every point
+*/
+func newBHTree(Y *mat.Dense) *bhCell {
+    n, d := Y.Dims()
+    low, high := make([]float64, d), make([]float64, d)
+    for c:=0; c<d; c++ {
+        column := mat.Col(nil, c, Y)
+        low[c], high[c] = floats.Min(column), floats.Max(column)
+    }
+    root := &bhCell{Centre: make([]float64, d), CentreOfMass: make([]float64, d), Point: -1}
+    for c:=0; c<d; c++ {
+        root.Centre[c] = 0.5 * (low[c] + high[c])
+        root.HalfWidth = math.Max(root.HalfWidth, 0.5 * (high[c] - low[c]) + 1e-5)
+    }
+    for i:=0; i<n; i++ { root.insert(Y, i) }
+    return root
+}
+
+
+/*
+SUMMARY
+    Inserts a point into the cell, splitting the cell when it already holds a point.
+PARAMETERS
+    Y *mat.Dense: the embedded points, each row is a point
+    I int: the index of the point
+RETURN
+    N/A
+*/
+func (c *bhCell) insert(Y *mat.Dense, I int) {
+    y := Y.RawRowView(I)
+    floats.Scale(float64(c.Count), c.CentreOfMass)
+    floats.Add(c.CentreOfMass, y)
+    c.Count++
+    floats.Scale(1.0 / float64(c.Count), c.CentreOfMass)
+    if c.Count == 1 {
+        c.Point = I
+        return
+    }
+    // coincident points stay together in a leaf
+    if c.HalfWidth < 1e-10 { return }
+    if c.Children == nil {
+        d := len(c.Centre)
+        c.Children = make([]*bhCell, 1 << uint(d))
+        for o := range c.Children {
+            child := &bhCell{Centre: make([]float64, d), HalfWidth: 0.5 * c.HalfWidth, CentreOfMass: make([]float64, d), Point: -1}
+            for k:=0; k<d; k++ {
+                child.Centre[k] = c.Centre[k] - child.HalfWidth
+                if o & (1 << uint(k)) != 0 { child.Centre[k] = c.Centre[k] + child.HalfWidth }
+            }
+            c.Children[o] = child
+        }
+        c.Children[c.orthant(Y.RawRowView(c.Point))].insert(Y, c.Point)
+        c.Point = -1
+    }
+    c.Children[c.orthant(y)].insert(Y, I)
+}
+
+
+/*
+SUMMARY
+    The index of the subcell containing a point.
+PARAMETERS
+    Y []float64: the point
+RETURN
+    int: the index of the subcell, bit k is set when the point is above the centre in coordinate k
+*/
+func (c *bhCell) orthant(Y []float64) int {
+    o := 0
+    for k, y := range Y {
+        if y > c.Centre[k] { o |= 1 << uint(k) }
+    }
+    return o
+}
+
+
+/*
+SUMMARY
+    Accumulates the repulsive force on a point, sum_j q_ij^2 Z^2 (y_i - y_j), and the normalisation
+    sum_j q_ij Z where q_ij Z = 1 / (1 + |y_i - y_j|^2). A cell is summarised by its centre of mass
+    when its width divided by its distance from the point is below Theta.
+PARAMETERS
+    Y []float64: the point
+    I int: the index of the point
+    Theta float64: the opening angle
+    Force []float64: the force, updated in place
+RETURN
+    float64: the contribution to the normalisation
+*/
+func (c *bhCell) repulsion(Y []float64, I int, Theta float64, Force []float64) float64 {
+    if c.Count == 0 { return 0 }
+    squared := 0.0
+    for k, y := range Y {
+        diff := y - c.CentreOfMass[k]
+        squared += diff * diff
+    }
+    if c.Children == nil || 2.0 * c.HalfWidth < Theta * math.Sqrt(squared) {
+        count := float64(c.Count)
+        // a leaf at the position of the point holds the point itself
+        if c.Children == nil && squared == 0 { count-- }
+        q := 1.0 / (1.0 + squared)
+        for k, y := range Y { Force[k] += count * q * q * (y - c.CentreOfMass[k]) }
+        return count * q
+    }
+    total := 0.0
+    for _, child := range c.Children { total += child.repulsion(Y, I, Theta, Force) }
+    return total
+}
+
+
+/*
+SUMMARY
+    The gradient of KL(P||Q) with respect to the embedded points,
+        dC/dy_i = 4 (Exaggeration sum_j P_ij q_ij Z (y_i - y_j) - sum_j q_ij^2 Z (y_i - y_j)),
+    with the exact or the Barnes-Hut repulsion.
+PARAMETERS
+    Y *mat.Dense: the embedded points, each row is a point
+    P sparseAffinities: the input similarities
+    Exaggeration float64: the factor of the attraction
+    Theta float64: the opening angle, negative for the exact repulsion
+    Gradient *mat.Dense: the gradient, overwritten
+RETURN
+    float64: the normalisation Z = sum_{i != j} 1 / (1 + |y_i - y_j|^2)
+*/
+func tsneGradient(Y *mat.Dense, P sparseAffinities, Exaggeration, Theta float64, Gradient *mat.Dense) float64 {
+    n, d := Y.Dims()
+    repulsive := mat.NewDense(n, d, nil)
+    Z := 0.0
+    if Theta < 0 {
+        for i:=0; i<n; i++ {
+            yi := Y.RawRowView(i)
+            for j:=i+1; j<n; j++ {
+                yj := Y.RawRowView(j)
+                distance := floats.Distance(yi, yj, 2)
+                q := 1.0 / (1.0 + distance * distance)
+                Z += 2.0 * q
+                for k:=0; k<d; k++ {
+                    force := q * q * (yi[k] - yj[k])
+                    repulsive.Set(i, k, repulsive.At(i, k) + force)
+                    repulsive.Set(j, k, repulsive.At(j, k) - force)
+                }
+            }
+        }
+    } else {
+        tree := newBHTree(Y)
+        for i:=0; i<n; i++ { Z += tree.repulsion(Y.RawRowView(i), i, Theta, repulsive.RawRowView(i)) }
+    }
+    Gradient.Zero()
+    for i:=0; i<n; i++ {
+        yi := Y.RawRowView(i)
+        row := Gradient.RawRowView(i)
+        for a, j := range P.Columns[i] {
+            yj := Y.RawRowView(j)
+            distance := floats.Distance(yi, yj, 2)
+            scale := Exaggeration * P.Values[i][a] / (1.0 + distance * distance)
+            for k:=0; k<d; k++ { row[k] += scale * (yi[k] - yj[k]) }
+        }
+        floats.AddScaled(row, -1.0 / Z, repulsive.RawRowView(i))
+        floats.Scale(4.0, row)
+    }
+    return Z
+}
+
+
+/*
+SUMMARY
+    The exact normalisation of the output similarities, it costs O(N^2) but is computed only once.
+PARAMETERS
+    Y *mat.Dense: the embedded points, each row is a point
+RETURN
+    float64: the normalisation Z = sum_{i != j} 1 / (1 + |y_i - y_j|^2)
+*/
+func exactNormalisation(Y *mat.Dense) float64 {
+    n, _ := Y.Dims()
+    Z := 0.0
+    for i:=0; i<n; i++ {
+        for j:=i+1; j<n; j++ {
+            distance := floats.Distance(Y.RawRowView(i), Y.RawRowView(j), 2)
+            Z += 2.0 / (1.0 + distance * distance)
+        }
+    }
+    return Z
+}
+
+
+/*
+SUMMARY
+    Implements t-SNE (van der Maaten & Hinton, Visualizing Data using t-SNE) with the Barnes-Hut
+    approximation of the repulsive forces (van der Maaten, Accelerating t-SNE using Tree-Based
+    Algorithms), which costs O(N log N) per iteration instead of O(N^2). The input similarities
+    are restricted to the nearest neighbours, the embedding is optimised by gradient descent with
+    momentum and adaptive gains, with early exaggeration of the attraction.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Options TSNEOptions: the settings
+RETURN
+    TSNEResult: the embedding and the final divergence
+*/
+func TSNE(X *mat.Dense, Options TSNEOptions) TSNEResult {
+    n, _ := X.Dims()
+    dims := Options.Dimensions
+    if dims == 0 { dims = 2 }
+    perplexity := Options.Perplexity
+    if perplexity == 0 { perplexity = 30 }
+    if perplexity < 1 || perplexity > float64(n - 1) { panic("Perplexity outside [1, #points-1] encountered") }
+    iterations := Options.Iterations
+    if iterations == 0 { iterations = 1000 }
+    learningRate := Options.LearningRate
+    if learningRate == 0 { learningRate = 200 }
+    exaggeration := Options.Exaggeration
+    if exaggeration == 0 { exaggeration = 12 }
+    exaggerationIterations := Options.ExaggerationIterations
+    if exaggerationIterations == 0 { exaggerationIterations = 250 }
+    theta := Options.Theta
+    if theta == 0 { theta = 0.5 }
+
+    P := inputAffinities(X, perplexity)
+    normal := distuv.Normal{Mu: 0, Sigma: 1e-4, Src: rand.NewSource(uint64(Options.Seed))}
+    Y := mat.NewDense(n, dims, nil)
+    Y.Apply(func (i, j int, v float64) float64 { return normal.Rand() }, Y)
+    gradient := mat.NewDense(n, dims, nil)
+    update := mat.NewDense(n, dims, nil)
+    gains := mat.NewDense(n, dims, nil)
+    gains.Apply(func (i, j int, v float64) float64 { return 1.0 }, gains)
+    for t:=0; t<iterations; t++ {
+        factor, momentum := 1.0, 0.8
+        if t < exaggerationIterations { factor, momentum = exaggeration, 0.5 }
+        tsneGradient(Y, P, factor, theta, gradient)
+        for i:=0; i<n; i++ {
+            for k:=0; k<dims; k++ {
+                g, u, gain := gradient.At(i, k), update.At(i, k), gains.At(i, k)
+                // the gain grows when the gradient changes the direction of the update
+                if (g > 0) != (u > 0) { gain += 0.2 } else { gain *= 0.8 }
+                gain = math.Max(gain, 0.01)
+                gains.Set(i, k, gain)
+                update.Set(i, k, momentum * u - learningRate * gain * g)
+            }
+        }
+        Y.Add(Y, update)
+        // keep the embedding centred
+        for k:=0; k<dims; k++ {
+            mean := floats.Sum(mat.Col(nil, k, Y)) / float64(n)
+            for i:=0; i<n; i++ { Y.Set(i, k, Y.At(i, k) - mean) }
+        }
+    }
+
+    // the Z of the last gradient belongs to the embedding before the update and may be approximate
+    Z := exactNormalisation(Y)
+    divergence := 0.0
+    for i:=0; i<n; i++ {
+        for a, j := range P.Columns[i] {
+            distance := floats.Distance(Y.RawRowView(i), Y.RawRowView(j), 2)
+            q := 1.0 / ((1.0 + distance * distance) * Z)
+            divergence += P.Values[i][a] * math.Log(P.Values[i][a] / q)
+        }
+    }
+    return TSNEResult{Embedding: Y, KLDivergence: divergence}
+}
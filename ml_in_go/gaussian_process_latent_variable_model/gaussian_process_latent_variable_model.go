@@ -3,20 +3,21 @@ package main
 import (
     "fmt"
     "math"
+    "runtime"
     "golang.org/x/exp/rand"
 
     "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
     "gonum.org/v1/gonum/stat/distuv"
     "gonum.org/v1/gonum/stat"
-    "gonum.org/v1/gonum/stat/distmv"
 
     "gonum.org/v1/plot"
-    "gonum.org/v1/plot/plotter"
     "gonum.org/v1/plot/vg"
     "gonum.org/v1/plot/text"
     "gonum.org/v1/plot/font"
     "gonum.org/v1/plot/font/liberation"
 
+    "ml_playground/gplvm"
     "ml_playground/optimisers"
     "ml_playground/plt"
     "ml_playground/kernels"
@@ -59,7 +60,8 @@ func PartialDerivativeOfKernel(X, Kernel *mat.Dense, j, d int, Sigma, Lengthscal
 /*
 SUMMARY
     The gradient computation of the objective function. The math derivation of it
-    is very complicated and tedious.
+    is very complicated and tedious. This version builds partial k(X,X) / partial x_{jd}
+    for every (j,d), so it takes O(N^4 D) time, we keep it to check Gradient.
 PARAMETERS
     X *mat.Dense: the latent space, here we differentiate with respect to it
     Y *mat.Dense: the observed space
@@ -68,7 +70,7 @@ PARAMETERS
 RETURN
     []float64: the gradient dF(X) / DX
 */
-func Gradient(X, Y *mat.Dense, varSigma, lengthScale float64) []float64 {
+func NaiveGradient(X, Y *mat.Dense, varSigma, lengthScale float64) []float64 {
     params := kernels.Parameters{Type: kernels.RBF, VarSigma: varSigma, LengthScale: lengthScale}
     N, D := X.Dims()
     grad := make([]float64, N*D)
@@ -101,6 +103,46 @@ func Gradient(X, Y *mat.Dense, varSigma, lengthScale float64) []float64 {
     return grad
 }
 
+
+/*
+SUMMARY
+    The gradient of the objective function through the derivative with respect to the kernel,
+    dF/dK = N K^{-1} - K^{-1} Y Y^T K^{-1}, which kernels.KernelGradients contracts with the
+    derivative of the RBF kernel. Apart from the inverse of the kernel this takes O(N^2 D) time.
+PARAMETERS
+    X *mat.Dense: the latent space, here we differentiate with respect to it
+    Y *mat.Dense: the observed space
+    varSigma float64: parameter of RBF
+    lengthScale float64: parameter of RBF
+    numWorkers int: the number of goroutines of the contraction
+RETURN
+    []float64: the gradient dF(X) / DX
+*/
+func Gradient(X, Y *mat.Dense, varSigma, lengthScale float64, numWorkers int) []float64 {
+    params := kernels.Parameters{Type: kernels.RBF, VarSigma: varSigma, LengthScale: lengthScale}
+    N, _ := X.Dims()
+    Kernel := kernels.Kernel(X, X, params)
+    // add the same noise as NaiveGradient
+    KernelSym := mat.NewSymDense(N, nil)
+    for j:=0; j<N; j++ {
+        for i:=j; i<N; i++ { KernelSym.SetSym(j, i, Kernel.At(j, i)) }
+        KernelSym.SetSym(j, j, KernelSym.At(j, j) + 2.0)
+    }
+    var chol mat.Cholesky
+    if ok := chol.Factorize(KernelSym); !ok { panic("Kernel is not positive definite") }
+    var KernelInv mat.SymDense
+    if err := chol.InverseTo(&KernelInv); err != nil { panic(err) }
+    var alpha mat.Dense
+    if err := chol.SolveTo(&alpha, Y); err != nil { panic(err) }
+    G := mat.NewDense(N, N, nil)
+    G.Mul(&alpha, alpha.T())
+    G.Apply(func (j, i int, v float64) float64 { return float64(N) * KernelInv.At(j, i) - v }, G)
+
+    grad, _ := kernels.KernelGradients(X, G, params, numWorkers)
+    return grad.RawMatrix().Data
+}
+
+
 /*
 SUMMARY
     Generates our sample data for the demonstration. The data forms a spiral.
@@ -150,7 +192,7 @@ func OptimisableGrad(Y *mat.Dense, Sigma, LengthScale float64) func ([]float64)
     return func (X []float64) []float64 {
         N, _ := Y.Dims()
         XMat := mat.NewDense(N, 2, X)
-        return Gradient(XMat, Y, Sigma, LengthScale)
+        return Gradient(XMat, Y, Sigma, LengthScale, runtime.NumCPU())
     }
 }
 
@@ -191,9 +233,48 @@ func F(X []float64, Y *mat.Dense, Sigma, LengthScale float64) float64 {
 }
 
 
+/*
+SUMMARY
+    Splits the rows of a matrix, every Every-th row is held out.
+PARAMETERS
+    Y *mat.Dense: the matrix, each row is a point
+    Every int: the period of the held out rows
+RETURN
+    *mat.Dense: the kept rows
+    *mat.Dense: the held out rows
+    []int: the indices of the kept rows
+    []int: the indices of the held out rows
+*/
+func HoldOut(Y *mat.Dense, Every int) (*mat.Dense, *mat.Dense, []int, []int) {
+    N, D := Y.Dims()
+    var kept, heldOut []int
+    for i:=0; i<N; i++ {
+        if i % Every == Every / 2 {
+            heldOut = append(heldOut, i)
+        } else {
+            kept = append(kept, i)
+        }
+    }
+    rows := func (Indices []int) *mat.Dense {
+        M := mat.NewDense(len(Indices), D, nil)
+        for r, i := range Indices { M.SetRow(r, Y.RawRowView(i)) }
+        return M
+    }
+    return rows(kept), rows(heldOut), kept, heldOut
+}
+
+
 /*
 We generate a spiral, embedd it in the 10d space, and try to recover
 the original space using GPLVM. We use the Adam optimiser in GPLVM.
+First the latent points alone are optimised from a random start with fixed
+hyperparameters, then the gplvm package starts from PCA and learns the kernel
+and the noise as well. Finally the Bayesian GPLVM with 5 latent dimensions
+switches off the superfluous ones with ARD, and the density plot shows the
+variational posteriors of the latent points. Lastly a back constrained GPLVM
+is fitted without every 8th point, the held out points are projected to the
+latent space, and we check whether the nearest training point of each projection
+is a neighbour on the spiral.
 */
 func main() {
     fmt.Println("")
@@ -210,7 +291,8 @@ func main() {
         Mu.Set(y, 0, stat.Mean(mat.Col(nil, y, Y), nil))
     }
 
-    optimiser := optimisers.Adam(0.3, 0.90, 0.999, 1e-8, 0.5e-1)
+    optimiser, err := optimisers.Adam(0.3, 0.90, 0.999, 1e-8, 0.5e-1)
+    if err != nil { panic(err) }
 
     gradientFunc := OptimisableGrad(Y, 1.0, 1.0/2.0)
 
@@ -219,9 +301,11 @@ func main() {
     At = RandomSlice(2*NumPoints, 0, 1)
 
     for i:=0; i<1000 && !Finished; i++ {
-        At, Finished, _ = optimiser(gradientFunc, At)
+        At, Finished, _, err = optimiser(gradientFunc, At)
+        if err != nil { panic(err) }
         fmt.Println("Step", i, "Converged?", Finished, "Loss", F(At, Y, 1.0, 1.0/2.0))
     }
+
     XPred := mat.NewDense(NumPoints, 2, nil)
     for y:=0; y<NumPoints; y++ {
         for x:=0; x<2; x++ {
@@ -229,56 +313,57 @@ func main() {
         }
     }
 
-    density := mat.NewDense(300, 300, nil)
-
-    XMin := -0.5 //floats.Min(mat.Col(nil, 0, XPred))
-    XMax := 1.5 //floats.Max(mat.Col(nil, 0, XPred))
-    YMin := -1.5 //floats.Min(mat.Col(nil, 1, XPred))
-    YMax := 0.5 //floats.Max(mat.Col(nil, 1, XPred))
-    fmt.Println(XMin, XMax, YMin, YMax)
-    for i:=0; i<NumPoints; i++ {
-        pdf, _ := distmv.NewNormal(mat.Row(nil, i, XPred), mat.NewSymDense(2, []float64{0.01,0.0,0.0,0.01}), randSrc)
-        for j:=0; j<300; j++ {
-            for k:=0; k<300; k++ {
-                y := YMin+float64(300-k-1)/300.0*(YMax-YMin)
-                x := XMin+float64(j)/300.0*(XMax-XMin)
-                density.Set(j, k, density.At(j, k) + pdf.Prob([]float64{x, y}))
-            }
-        }
-    }
-
-    m := plt.MatrixHeatMap{
-        Matrix: density,
-        XRange: plt.Range{XMin, XMax},
-        YRange: plt.Range{YMin, YMax},
-    }
-
-    pal := plt.DesignedPalette{Type: plt.BLACK_BODY_PALETTE, Num: 256}
-    heights := make([]float64, 50)
-    for i := range heights { heights[i] = 0.1 * float64(i+1) }
     fonts := font.NewCache(liberation.Collection())
 	plot.DefaultTextHandler = text.Latex{
 		Fonts: fonts,
 	}
-    p := plot.New()
-    p.Title.Text = `Density Plot of the Latent Space (GPLVM)`
-    p.X.Label.Text = `$x$`
-    p.Y.Label.Text = `$y$`
-    img := plt.FillImage(&m, pal)
-    Height, Width := m.Matrix.Dims()
-    pImg := plotter.NewImage(img, 0, 0, float64(Width), float64(Height))
-    p.Add(pImg)
+    p := plt.LatentScatterPlot(XPred, "Fixed Hyperparameters (GPLVM)")
+    p.Save(300, 300, "fixed_hyperparameters_scatter_plot.svg")
+
+    model, result, err := gplvm.Fit(Y, gplvm.Options{LatentDimensions: 2, Iterations: 2000, StepSize: 0.02})
+    if err != nil { panic(err) }
+    fmt.Println("gplvm package: steps", result.Iterations, "converged?", result.Converged, "loss", result.NegLogLikelihoods[len(result.NegLogLikelihoods)-1])
+    fmt.Println("    learnt VarSigma", model.Kernel.VarSigma, "LengthScale", model.Kernel.LengthScale, "noise variance", model.NoiseVariance)
+
+    bayesian, result, err := gplvm.FitBayesian(Y, gplvm.BayesianOptions{LatentDimensions: 5, NumInducing: 20, StepSize: 0.03, Seed: randSeed})
+    if err != nil { panic(err) }
+    bound, err := bayesian.LowerBound()
+    if err != nil { panic(err) }
+    active := bayesian.ActiveDimensions(0.05)
+    fmt.Println("Bayesian GPLVM: steps", result.Iterations, "converged?", result.Converged, "lower bound", bound)
+    fmt.Println("    relevances", bayesian.Kernel.Relevances, "active dimensions", active, "noise variance", bayesian.NoiseVariance)
+
+    // the density is the mixture of the variational posteriors on the two most relevant dimensions,
+    // with a threshold of 0 every dimension is ranked when fewer than two are active
+    plotted := active
+    if len(plotted) < 2 { plotted = bayesian.ActiveDimensions(0) }
+    means, covariances := bayesian.Marginal(plotted[:2])
+    p = plt.LatentDensityPlot(means, covariances, `Density Plot of the Latent Space (Bayesian GPLVM)`)
     p.Save(4*vg.Inch, 4*vg.Inch, "density_plot.png")
 
-    p = plot.New()
-    p.Title.Text = "Scatter Plot of the Latent Space (GPLVM)"
-    p.X.Label.Text = `$x$`
-    p.Y.Label.Text = `$y$`
-    pal = plt.DesignedPalette{Type: plt.KINDLMANN_PALETTE, Num: len(mat.Col(nil, 0, XPred))}
-    sc := plt.MakeScatterUnicorn(mat.Col(nil, 0, XPred), mat.Col(nil, 1, XPred), plt.CIRCLE_POINT_MARKER, 3.0, pal)
-    p.Add(sc)
+    p = plt.LatentScatterPlot(model.X, "Scatter Plot of the Latent Space (GPLVM)")
     p.Save(300, 300, "prediction_scatter_plot.svg")
 
+    YTrain, YTest, trainIndices, testIndices := HoldOut(Y, 8)
+    constrained, result, err := gplvm.Fit(YTrain, gplvm.Options{LatentDimensions: 2, Iterations: 2000, StepSize: 0.02, BackConstrained: true})
+    if err != nil { panic(err) }
+    fmt.Println("back constrained GPLVM: steps", result.Iterations, "loss", result.NegLogLikelihoods[len(result.NegLogLikelihoods)-1])
+    projected, covariances, err := constrained.Project(YTest)
+    if err != nil { panic(err) }
+    retrieved := 0
+    for t, i := range testIndices {
+        nearest, distance := 0, math.Inf(1)
+        for r := range trainIndices {
+            if dist := floats.Distance(projected.RawRowView(t), constrained.X.RawRowView(r), 2); dist < distance { nearest, distance = r, dist }
+        }
+        if math.Abs(float64(trainIndices[nearest] - i)) <= 2 { retrieved++ }
+        fmt.Printf("    held out point %2d: latent mean (%.3f, %.3f), standard deviations (%.3f, %.3f), nearest training point %2d\n", i,
+            projected.At(t, 0), projected.At(t, 1), math.Sqrt(covariances[t].At(0, 0)), math.Sqrt(covariances[t].At(1, 1)), trainIndices[nearest])
+    }
+    fmt.Println("    held out points retrieved next to their neighbours on the spiral:", retrieved, "of", len(testIndices))
 
-
-}
\ No newline at end of file
+    p = plt.LatentScatterPlot(constrained.X, "Back Constrained GPLVM")
+    p.Save(300, 300, "back_constrained_scatter_plot.svg")
+    p = plt.LatentDensityPlot(projected, covariances, `Projected Held Out Points (GPLVM)`)
+    p.Save(4*vg.Inch, 4*vg.Inch, "projection_density_plot.png")
+}
\ No newline at end of file
@@ -0,0 +1,51 @@
+package main
+
+import (
+    "testing"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+)
+
+
+/*
+SUMMARY
+    A random matrix with standard normal entries.
+PARAMETERS
+    Rows int: the number of rows
+    Cols int: the number of columns
+    Src rand.Source: the source of the random numbers
+RETURN
+    *mat.Dense: the matrix
+*/
+func randomMatrix(Rows, Cols int, Src rand.Source) *mat.Dense {
+    randGen := rand.New(Src)
+    A := mat.NewDense(Rows, Cols, nil)
+    A.Apply(func (j, i int, v float64) float64 { return randGen.NormFloat64() }, A)
+    return A
+}
+
+
+func TestGradientAgreesWithNaiveGradient(t *testing.T) {
+    cases := []struct {
+        name string
+        numPoints, numWorkers int
+    }{
+        {name: "one goroutine", numPoints: 20, numWorkers: 1},
+        {name: "odd number of points", numPoints: 17, numWorkers: 3},
+        {name: "fewer points than goroutines", numPoints: 3, numWorkers: 8},
+    }
+    src := rand.NewSource(1)
+    for _, c := range cases {
+        X := randomMatrix(c.numPoints, 2, src)
+        Y := randomMatrix(c.numPoints, 10, src)
+        naive, fast := NaiveGradient(X, Y, 1.0, 1.0/2.0), Gradient(X, Y, 1.0, 1.0/2.0, c.numWorkers)
+        if len(fast) != len(naive) { t.Fatalf("%s: %d derivatives instead of %d", c.name, len(fast), len(naive)) }
+        difference := make([]float64, len(naive))
+        floats.SubTo(difference, naive, fast)
+        if relative := floats.Norm(difference, 2) / floats.Norm(naive, 2); relative > 1e-8 {
+            t.Errorf("%s: relative difference %g between NaiveGradient and Gradient", c.name, relative)
+        }
+    }
+}
@@ -0,0 +1,431 @@
+/*
+This library contains Gaussian mixture models fitted with expectation maximisation
+and with variational Bayes. The data points are the rows of the data matrices.
+*/
+package gmm
+
+import (
+    "math"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+    "gonum.org/v1/gonum/stat/distmv"
+    "gonum.org/v1/gonum/stat/distuv"
+
+    "ml_playground/kmeans"
+)
+
+
+// types of covariance matrices
+const FULL_COVARIANCE = 0
+const DIAGONAL_COVARIANCE = 1
+const SPHERICAL_COVARIANCE = 2
+
+
+/*
+This type stores a Gaussian mixture model.
+    Weights []float64: the mixing proportions, they sum to 1
+    Means [][]float64: the mean of each component
+    Covariances []*mat.SymDense: the covariance of each component, diagonal matrices are stored in full
+    CovarianceType int: FULL_COVARIANCE, DIAGONAL_COVARIANCE or SPHERICAL_COVARIANCE
+*/
+type GMM struct {
+    Weights []float64
+    Means [][]float64
+    Covariances []*mat.SymDense
+    CovarianceType int
+}
+
+
+/*
+This type configures the EM algorithm.
+    NumComponents int: the number of components
+    CovarianceType int: FULL_COVARIANCE, DIAGONAL_COVARIANCE or SPHERICAL_COVARIANCE
+    MaxIterations int: the maximal number of iterations (100 if 0)
+    Tolerance float64: stop when the average log-likelihood improves less than this
+    Regularisation float64: added to the diagonal of the covariances to keep them positive definite
+        (1e-6 times the average variance of the data, or 1e-6 if the data has no variance, if 0)
+    Seed int: seed of the k-means initialisation
+*/
+type EMOptions struct {
+    NumComponents int
+    CovarianceType int
+    MaxIterations int
+    Tolerance float64
+    Regularisation float64
+    Seed int
+}
+
+
+/*
+This type stores the outcome of the EM algorithm.
+    LogLikelihoods []float64: the log-likelihood of the data at the start of each iteration
+    Iterations int: the number of iterations
+    Converged bool: whether the algorithm stopped because of the tolerance
+*/
+type EMResult struct {
+    LogLikelihoods []float64
+    Iterations int
+    Converged bool
+}
+
+
+/*
+SUMMARY
+    The weighted scatter matrix \sum_n Weights_n (x_n-Mean)(x_n-Mean)^T / \sum_n Weights_n.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Weights []float64: the non-negative weight of each point
+    Mean []float64: the centre of the scatter
+RETURN
+    *mat.SymDense: the scatter matrix
+*/
+func weightedScatter(X *mat.Dense, Weights, Mean []float64) *mat.SymDense {
+    n, d := X.Dims()
+    total := floats.Sum(Weights)
+    scaled := mat.NewDense(n, d, nil)
+    scaled.Apply(func (i, j int, v float64) float64 { return math.Sqrt(Weights[i]) * (v - Mean[j]) }, X)
+    scatter := mat.NewSymDense(d, nil)
+    if total > 0 { scatter.SymOuterK(1.0 / total, scaled.T()) }
+    return scatter
+}
+
+
+/*
+SUMMARY
+    The log density log N(x|Mean,Covariance) of every row of X.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Mean []float64: the mean
+    Covariance *mat.SymDense: the covariance
+RETURN
+    []float64: the log densities
+*/
+func logNormal(X *mat.Dense, Mean []float64, Covariance *mat.SymDense) []float64 {
+    n, d := X.Dims()
+    var chol mat.Cholesky
+    if ok := chol.Factorize(Covariance); !ok { panic("Covariance is not positive definite") }
+    var precision mat.SymDense
+    chol.InverseTo(&precision)
+    diff := mat.NewDense(n, d, nil)
+    diff.Apply(func (i, j int, v float64) float64 { return v - Mean[j] }, X)
+    var projected mat.Dense
+    projected.Mul(diff, &precision)
+    constant := -0.5 * (float64(d) * math.Log(2*math.Pi) + chol.LogDet())
+    logs := make([]float64, n)
+    for i := range logs {
+        logs[i] = constant - 0.5 * floats.Dot(diff.RawRowView(i), projected.RawRowView(i))
+    }
+    return logs
+}
+
+
+/*
+SUMMARY
+    The joint log densities log Weights_k + log N(x_n|Means_k,Covariances_k).
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    *mat.Dense: N by K matrix of the joint log densities
+*/
+func (g GMM) LogProbComponents(X *mat.Dense) *mat.Dense {
+    n, _ := X.Dims()
+    logs := mat.NewDense(n, len(g.Weights), nil)
+    for k := range g.Weights {
+        column := logNormal(X, g.Means[k], g.Covariances[k])
+        floats.AddConst(math.Log(g.Weights[k]), column)
+        logs.SetCol(k, column)
+    }
+    return logs
+}
+
+
+/*
+SUMMARY
+    Normalises the rows of a matrix of log densities into probabilities.
+PARAMETERS
+    Logs *mat.Dense: N by K matrix of log densities, overwritten with the probabilities
+RETURN
+    []float64: the log normalising constant of each row
+*/
+func normaliseLogRows(Logs *mat.Dense) []float64 {
+    n, _ := Logs.Dims()
+    normalisers := make([]float64, n)
+    for i := range normalisers {
+        row := Logs.RawRowView(i)
+        normalisers[i] = floats.LogSumExp(row)
+        for k := range row { row[k] = math.Exp(row[k] - normalisers[i]) }
+    }
+    return normalisers
+}
+
+
+/*
+SUMMARY
+    The responsibilities, the posterior probability of each component given each point.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    *mat.Dense: N by K matrix of responsibilities, the rows sum to 1
+*/
+func (g GMM) Responsibilities(X *mat.Dense) *mat.Dense {
+    logs := g.LogProbComponents(X)
+    normaliseLogRows(logs)
+    return logs
+}
+
+
+/*
+SUMMARY
+    The log-likelihood of the data.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    float64: \sum_n log p(x_n)
+*/
+func (g GMM) LogLikelihood(X *mat.Dense) float64 {
+    return floats.Sum(normaliseLogRows(g.LogProbComponents(X)))
+}
+
+
+/*
+SUMMARY
+    The density of the mixture at a point.
+PARAMETERS
+    x []float64: the point
+RETURN
+    float64: p(x)
+*/
+func (g GMM) Prob(x []float64) float64 {
+    return math.Exp(g.LogLikelihood(mat.NewDense(1, len(x), x)))
+}
+
+
+/*
+SUMMARY
+    The most responsible component of each point.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    []int: the labels
+*/
+func (g GMM) Predict(X *mat.Dense) []int {
+    logs := g.LogProbComponents(X)
+    n, _ := logs.Dims()
+    labels := make([]int, n)
+    for i := range labels { labels[i] = floats.MaxIdx(logs.RawRowView(i)) }
+    return labels
+}
+
+
+/*
+SUMMARY
+    The number of free parameters of the model.
+PARAMETERS
+    N/A
+RETURN
+    int: the number of parameters
+*/
+func (g GMM) NumParameters() int {
+    k := len(g.Weights)
+    d := len(g.Means[0])
+    covariance := 0
+    switch g.CovarianceType {
+        case FULL_COVARIANCE:
+            covariance = d * (d + 1) / 2
+        case DIAGONAL_COVARIANCE:
+            covariance = d
+        case SPHERICAL_COVARIANCE:
+            covariance = 1
+        default:
+            panic("Unknown covariance type encountered")
+    }
+    return k - 1 + k * d + k * covariance
+}
+
+
+/*
+SUMMARY
+    The Bayesian information criterion, lower is better.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    float64: -2 log-likelihood + #parameters log N
+*/
+func (g GMM) BIC(X *mat.Dense) float64 {
+    n, _ := X.Dims()
+    return -2 * g.LogLikelihood(X) + float64(g.NumParameters()) * math.Log(float64(n))
+}
+
+
+/*
+SUMMARY
+    The Akaike information criterion, lower is better.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    float64: -2 log-likelihood + 2 #parameters
+*/
+func (g GMM) AIC(X *mat.Dense) float64 {
+    return -2 * g.LogLikelihood(X) + 2 * float64(g.NumParameters())
+}
+
+
+/*
+SUMMARY
+    Draws points from the mixture.
+PARAMETERS
+    N int: the number of points
+    Src rand.Source: the source of randomness
+RETURN
+    *mat.Dense: the points, each row is a point
+    []int: the component each point was drawn from
+*/
+func (g GMM) Sample(N int, Src rand.Source) (*mat.Dense, []int) {
+    d := len(g.Means[0])
+    categorical := distuv.NewCategorical(g.Weights, Src)
+    normals := make([]*distmv.Normal, len(g.Weights))
+    for k := range normals {
+        var ok bool
+        normals[k], ok = distmv.NewNormal(g.Means[k], g.Covariances[k], Src)
+        if !ok { panic("Covariance is not positive definite") }
+    }
+    points := mat.NewDense(N, d, nil)
+    labels := make([]int, N)
+    for i := range labels {
+        labels[i] = int(categorical.Rand())
+        normals[labels[i]].Rand(points.RawRowView(i))
+    }
+    return points, labels
+}
+
+
+/*
+SUMMARY
+    The M-step: the maximum likelihood parameters given the responsibilities.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    R *mat.Dense: N by K matrix of responsibilities
+    CovarianceType int: FULL_COVARIANCE, DIAGONAL_COVARIANCE or SPHERICAL_COVARIANCE
+    Regularisation float64: added to the diagonal of the covariances
+RETURN
+    GMM: the new parameters
+*/
+func maximisation(X, R *mat.Dense, CovarianceType int, Regularisation float64) GMM {
+    n, d := X.Dims()
+    _, numComponents := R.Dims()
+    g := GMM{
+        Weights: make([]float64, numComponents),
+        Means: make([][]float64, numComponents),
+        Covariances: make([]*mat.SymDense, numComponents),
+        CovarianceType: CovarianceType,
+    }
+    weights := make([]float64, n)
+    for k := range g.Weights {
+        mat.Col(weights, k, R)
+        total := floats.Sum(weights) + 10 * math.SmallestNonzeroFloat64
+        g.Weights[k] = total / float64(n)
+        g.Means[k] = make([]float64, d)
+        for i := range weights {
+            floats.AddScaled(g.Means[k], weights[i] / total, X.RawRowView(i))
+        }
+        covariance := weightedScatter(X, weights, g.Means[k])
+        switch CovarianceType {
+            case FULL_COVARIANCE:
+            case DIAGONAL_COVARIANCE:
+                for a:=0; a<d; a++ {
+                    for b:=a+1; b<d; b++ { covariance.SetSym(a, b, 0.0) }
+                }
+            case SPHERICAL_COVARIANCE:
+                variance := mat.Trace(covariance) / float64(d)
+                covariance = mat.NewSymDense(d, nil)
+                for a:=0; a<d; a++ { covariance.SetSym(a, a, variance) }
+            default:
+                panic("Unknown covariance type encountered")
+        }
+        for a:=0; a<d; a++ { covariance.SetSym(a, a, covariance.At(a, a) + Regularisation) }
+        g.Covariances[k] = covariance
+    }
+    return g
+}
+
+
+/*
+SUMMARY
+    Hard responsibilities from the k-means clustering of the data.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    NumComponents int: the number of clusters
+    Seed int: seed of k-means
+RETURN
+    *mat.Dense: N by K matrix of 0s and 1s
+*/
+func kmeansResponsibilities(X *mat.Dense, NumComponents, Seed int) *mat.Dense {
+    n, _ := X.Dims()
+    result := kmeans.KMeans(mat.DenseCopyOf(X.T()), kmeans.KMeansOptions{NumClasses: NumComponents, Tolerance: 1e-6, Seed: Seed})
+    R := mat.NewDense(n, NumComponents, nil)
+    for i, label := range result.Labels { R.Set(i, label, 1.0) }
+    return R
+}
+
+
+/*
+SUMMARY
+    The default regularisation of the covariances, small compared to the spread of the data, so that a
+    component with a single point or with duplicate points still has a positive definite covariance.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    float64: 1e-6 times the average variance of the columns, 1e-6 if the data has no variance
+*/
+func defaultRegularisation(X *mat.Dense) float64 {
+    n, d := X.Dims()
+    mean := make([]float64, d)
+    for i:=0; i<n; i++ { floats.Add(mean, X.RawRowView(i)) }
+    floats.Scale(1.0 / float64(n), mean)
+    variance := 0.0
+    for i:=0; i<n; i++ {
+        distance := floats.Distance(X.RawRowView(i), mean, 2)
+        variance += distance * distance / float64(n * d)
+    }
+    if variance == 0 { return 1e-6 }
+    return 1e-6 * variance
+}
+
+
+/*
+SUMMARY
+    Fits a Gaussian mixture model with the expectation maximisation algorithm,
+    starting from the clusters found by k-means.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Options EMOptions: the settings
+RETURN
+    GMM: the fitted model
+    EMResult: the log-likelihood trace and the convergence
+*/
+func FitEM(X *mat.Dense, Options EMOptions) (GMM, EMResult) {
+    n, _ := X.Dims()
+    if Options.NumComponents <= 0 || Options.NumComponents > n { panic("Number of components outside [1, #points] encountered") }
+    maxIterations := Options.MaxIterations
+    if maxIterations <= 0 { maxIterations = 100 }
+    regularisation := Options.Regularisation
+    if regularisation == 0 { regularisation = defaultRegularisation(X) }
+    g := maximisation(X, kmeansResponsibilities(X, Options.NumComponents, Options.Seed), Options.CovarianceType, regularisation)
+    var result EMResult
+    previous := math.Inf(-1)
+    for result.Iterations < maxIterations {
+        result.Iterations++
+        R := g.LogProbComponents(X)
+        logLikelihood := floats.Sum(normaliseLogRows(R))
+        g = maximisation(X, R, Options.CovarianceType, regularisation)
+        result.LogLikelihoods = append(result.LogLikelihoods, logLikelihood)
+        if (logLikelihood - previous) / float64(n) < Options.Tolerance {
+            result.Converged = true
+            break
+        }
+        previous = logLikelihood
+    }
+    return g, result
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+    "fmt"
+    "image/color"
+    "gonum.org/v1/gonum/mat"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/plot"
+    "gonum.org/v1/plot/plotter"
+    "gonum.org/v1/plot/vg"
+
+    "ml_playground/gmm"
+    "ml_playground/plt"
+)
+
+// random number seed and source
+var randSeed = 12
+var randSrc = rand.NewSource(uint64(randSeed))
+
+
+/*
+SUMMARY
+    The mixture the data is drawn from: four components of different shapes in the plane.
+PARAMETERS
+    N/A
+RETURN
+    gmm.GMM: the true mixture
+*/
+func TrueMixture() gmm.GMM {
+    return gmm.GMM{
+        Weights: []float64{0.3, 0.3, 0.2, 0.2},
+        Means: [][]float64{{-2.0, -2.0}, {2.0, -1.5}, {-1.5, 2.5}, {2.5, 2.5}},
+        Covariances: []*mat.SymDense{
+            mat.NewSymDense(2, []float64{0.8, 0.6, 0.6, 0.8}),
+            mat.NewSymDense(2, []float64{0.6, -0.3, -0.3, 0.4}),
+            mat.NewSymDense(2, []float64{0.2, 0.0, 0.0, 0.2}),
+            mat.NewSymDense(2, []float64{0.5, 0.0, 0.0, 0.1}),
+        },
+        CovarianceType: gmm.FULL_COVARIANCE,
+    }
+}
+
+
+/*
+SUMMARY
+    Renders the density of a mixture with a heatmap and puts the points on top of it.
+PARAMETERS
+    Model gmm.GMM: the mixture
+    X *mat.Dense: the points, each row is a point
+    Labels []int: the colour index of each point
+    Title string: the title of the plot
+    Filename string: the output file
+RETURN
+    N/A
+*/
+func SaveDensity(Model gmm.GMM, X *mat.Dense, Labels []int, Title, Filename string) {
+    XRang := plt.Range{Min: -5.0, Max: 5.0}
+    YRang := plt.Range{Min: -5.0, Max: 5.0}
+    m := plt.FuncHeatMap{
+        Function: func (x, y float64) float64 { return Model.Prob([]float64{x, y}) },
+        Height: 300,
+        Width: 300,
+        XRange: XRang,
+        YRange: YRang,
+    }
+    pal := plt.DesignedPalette{Type: plt.BLACK_BODY_PALETTE, Num: 300}
+    img := plt.FillImage(&m, pal)
+    p := plot.New()
+    p.Title.Text = Title
+    p.X.Label.Text, p.Y.Label.Text = "x", "y"
+    p.Add(plotter.NewImage(img, XRang.Min, YRang.Min, XRang.Max, YRang.Max))
+
+    classColours := plt.DesignedPalette{Type: plt.RANDOM_PALETTE, Num: len(Model.Weights) + 1, Extra: 5}.Colors()
+    pointColours := make([]color.Color, len(Labels))
+    for i, label := range Labels { pointColours[i] = classColours[label] }
+    scatter := plt.MakeScatterUnicorn(mat.Col(nil, 0, X), mat.Col(nil, 1, X), plt.CIRCLE_POINT_MARKER, 2.0, plt.CustomPalette{Colours: pointColours})
+    p.Add(scatter)
+    p.Save(5*vg.Inch, 5*vg.Inch, Filename)
+}
+
+
+/*
+We draw points from a known mixture, fit mixtures with EM for every covariance type,
+select the number of components with BIC, let variational Bayes prune a surplus of components,
+and render the fitted densities.
+*/
+func main() {
+    truth := TrueMixture()
+    X, _ := truth.Sample(800, randSrc)
+    fmt.Printf("true mixture: log-likelihood %.1f\n", truth.LogLikelihood(X))
+
+    for _, covariance := range []struct{ name string; kind int }{
+        {name: "full", kind: gmm.FULL_COVARIANCE},
+        {name: "diagonal", kind: gmm.DIAGONAL_COVARIANCE},
+        {name: "spherical", kind: gmm.SPHERICAL_COVARIANCE},
+    } {
+        model, result := gmm.FitEM(X, gmm.EMOptions{NumComponents: 4, CovarianceType: covariance.kind, Tolerance: 1e-6, Regularisation: 1e-6, Seed: randSeed})
+        fmt.Printf("EM, %-9s covariances: log-likelihood %.1f, BIC %.1f, AIC %.1f after %d iterations\n",
+            covariance.name, model.LogLikelihood(X), model.BIC(X), model.AIC(X), result.Iterations)
+    }
+
+    var best gmm.GMM
+    bestBIC := 0.0
+    for k:=1; k<=8; k++ {
+        model, _ := gmm.FitEM(X, gmm.EMOptions{NumComponents: k, Tolerance: 1e-6, Regularisation: 1e-6, Seed: randSeed})
+        bic := model.BIC(X)
+        fmt.Printf("EM with %d components: BIC %.1f\n", k, bic)
+        if k == 1 || bic < bestBIC { best, bestBIC = model, bic }
+    }
+    fmt.Printf("BIC selects %d components\n", len(best.Weights))
+    SaveDensity(best, X, best.Predict(X), "EM, components chosen by BIC", "density_em.png")
+
+    vb := gmm.FitVB(X, gmm.VBOptions{NumComponents: 10, WeightConcentration: 1e-3, Tolerance: 1e-6, Seed: randSeed})
+    fmt.Printf("variational Bayes keeps %d of 10 components after %d iterations, expected weights %.3f\n",
+        len(vb.ActiveComponents(0.01)), vb.Iterations, vb.ExpectedWeights())
+    vbModel := vb.ToGMM(0.01)
+    SaveDensity(vbModel, X, vbModel.Predict(X), "Variational Bayes with 10 components", "density_vb.png")
+
+    samples, labels := vbModel.Sample(800, randSrc)
+    SaveDensity(vbModel, samples, labels, "Samples from the variational Bayes mixture", "samples_vb.png")
+}
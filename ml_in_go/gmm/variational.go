@@ -0,0 +1,233 @@
+package gmm
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+    "gonum.org/v1/gonum/mathext"
+)
+
+
+/*
+This type configures the variational Bayesian Gaussian mixture model (Bishop, Pattern Recognition
+and Machine Learning, 10.2). The prior is a symmetric Dirichlet on the weights and a Gauss-Wishart
+on the mean and precision of each component, centred on the mean and the covariance of the data.
+    NumComponents int: the maximal number of components
+    WeightConcentration float64: the parameter of the Dirichlet prior, values below 1 prune unused components
+    MeanPrecision float64: beta_0, the scale of the precision of the prior on the means (1 if 0)
+    MaxIterations int: the maximal number of iterations (200 if 0)
+    Tolerance float64: stop when no effective component size changes more than this
+    Seed int: seed of the k-means initialisation
+*/
+type VBOptions struct {
+    NumComponents int
+    WeightConcentration float64
+    MeanPrecision float64
+    MaxIterations int
+    Tolerance float64
+    Seed int
+}
+
+
+/*
+This type stores the variational posterior q(pi)q(mu,Lambda).
+    Alpha []float64: the Dirichlet parameters of the weights
+    Beta []float64: the precision scales of the means
+    Means [][]float64: the means of the means
+    Nu []float64: the degrees of freedom of the Wishart distributions
+    W []*mat.SymDense: the scale matrices of the Wishart distributions
+    Iterations int: the number of iterations
+    Converged bool: whether the algorithm stopped because of the tolerance
+*/
+type VBGMM struct {
+    Alpha []float64
+    Beta []float64
+    Means [][]float64
+    Nu []float64
+    W []*mat.SymDense
+    Iterations int
+    Converged bool
+}
+
+
+/*
+SUMMARY
+    Inverts a positive definite matrix.
+PARAMETERS
+    A *mat.SymDense: the matrix
+RETURN
+    *mat.SymDense: its inverse
+*/
+func inverseSym(A *mat.SymDense) *mat.SymDense {
+    var chol mat.Cholesky
+    if ok := chol.Factorize(A); !ok { panic("Matrix is not positive definite") }
+    var inverse mat.SymDense
+    chol.InverseTo(&inverse)
+    return &inverse
+}
+
+
+/*
+SUMMARY
+    The expected mixing proportions E[pi_k] = Alpha_k / \sum_j Alpha_j.
+PARAMETERS
+    N/A
+RETURN
+    []float64: the expected weights
+*/
+func (v VBGMM) ExpectedWeights() []float64 {
+    weights := make([]float64, len(v.Alpha))
+    floats.ScaleTo(weights, 1.0 / floats.Sum(v.Alpha), v.Alpha)
+    return weights
+}
+
+
+/*
+SUMMARY
+    The responsibilities, the variational distribution q(z_n=k).
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    *mat.Dense: N by K matrix of responsibilities, the rows sum to 1
+*/
+func (v VBGMM) Responsibilities(X *mat.Dense) *mat.Dense {
+    n, d := X.Dims()
+    logs := mat.NewDense(n, len(v.Alpha), nil)
+    digammaSum := mathext.Digamma(floats.Sum(v.Alpha))
+    for k := range v.Alpha {
+        var chol mat.Cholesky
+        if ok := chol.Factorize(v.W[k]); !ok { panic("Matrix is not positive definite") }
+        // E[ln |Lambda_k|]
+        logDet := float64(d) * math.Ln2 + chol.LogDet()
+        for i:=1; i<=d; i++ { logDet += mathext.Digamma(0.5 * (v.Nu[k] + 1 - float64(i))) }
+        constant := mathext.Digamma(v.Alpha[k]) - digammaSum + 0.5 * logDet - 0.5 * float64(d) * (math.Log(2*math.Pi) + 1.0 / v.Beta[k])
+        diff := mat.NewDense(n, d, nil)
+        diff.Apply(func (i, j int, value float64) float64 { return value - v.Means[k][j] }, X)
+        var projected mat.Dense
+        projected.Mul(diff, v.W[k])
+        for i:=0; i<n; i++ {
+            logs.Set(i, k, constant - 0.5 * v.Nu[k] * floats.Dot(diff.RawRowView(i), projected.RawRowView(i)))
+        }
+    }
+    normaliseLogRows(logs)
+    return logs
+}
+
+
+/*
+SUMMARY
+    The components whose expected weight is at least Threshold.
+PARAMETERS
+    Threshold float64: the smallest weight of an active component
+RETURN
+    []int: the indices of the active components
+*/
+func (v VBGMM) ActiveComponents(Threshold float64) []int {
+    var active []int
+    for k, weight := range v.ExpectedWeights() {
+        if weight >= Threshold { active = append(active, k) }
+    }
+    return active
+}
+
+
+/*
+SUMMARY
+    A point estimate of the mixture from the active components, the covariances are the
+    inverses of the expected precisions (Nu W)^{-1}.
+PARAMETERS
+    Threshold float64: the smallest weight of an active component
+RETURN
+    GMM: the mixture with renormalised weights
+*/
+func (v VBGMM) ToGMM(Threshold float64) GMM {
+    g := GMM{CovarianceType: FULL_COVARIANCE}
+    weights := v.ExpectedWeights()
+    for _, k := range v.ActiveComponents(Threshold) {
+        g.Weights = append(g.Weights, weights[k])
+        g.Means = append(g.Means, append([]float64{}, v.Means[k]...))
+        precision := mat.NewSymDense(len(v.Means[k]), nil)
+        precision.ScaleSym(v.Nu[k], v.W[k])
+        g.Covariances = append(g.Covariances, inverseSym(precision))
+    }
+    floats.Scale(1.0 / floats.Sum(g.Weights), g.Weights)
+    return g
+}
+
+
+/*
+SUMMARY
+    Fits a variational Bayesian Gaussian mixture model with full covariances by coordinate ascent,
+    starting from the clusters found by k-means. With a small WeightConcentration the surplus
+    components lose their points and their expected weights go to 0.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Options VBOptions: the settings
+RETURN
+    VBGMM: the variational posterior
+*/
+func FitVB(X *mat.Dense, Options VBOptions) VBGMM {
+    n, d := X.Dims()
+    numComponents := Options.NumComponents
+    if numComponents <= 0 || numComponents > n { panic("Number of components outside [1, #points] encountered") }
+    if Options.WeightConcentration <= 0 { panic("Non-positive weight concentration encountered") }
+    beta0 := Options.MeanPrecision
+    if beta0 <= 0 { beta0 = 1.0 }
+    maxIterations := Options.MaxIterations
+    if maxIterations <= 0 { maxIterations = 200 }
+
+    // the prior is centred on the data: m_0 is the mean, W_0^{-1} = nu_0 * covariance
+    ones := make([]float64, n)
+    for i := range ones { ones[i] = 1.0 }
+    mean0 := make([]float64, d)
+    for i:=0; i<n; i++ { floats.AddScaled(mean0, 1.0 / float64(n), X.RawRowView(i)) }
+    nu0 := float64(d)
+    inverseW0 := weightedScatter(X, ones, mean0)
+    inverseW0.ScaleSym(nu0, inverseW0)
+
+    v := VBGMM{
+        Alpha: make([]float64, numComponents),
+        Beta: make([]float64, numComponents),
+        Means: make([][]float64, numComponents),
+        Nu: make([]float64, numComponents),
+        W: make([]*mat.SymDense, numComponents),
+    }
+    R := kmeansResponsibilities(X, numComponents, Options.Seed)
+    counts := make([]float64, numComponents)
+    weights := make([]float64, n)
+    for v.Iterations < maxIterations {
+        v.Iterations++
+        change := 0.0
+        for k := range counts {
+            mat.Col(weights, k, R)
+            count := floats.Sum(weights)
+            change = math.Max(change, math.Abs(count - counts[k]))
+            counts[k] = count
+            centre := make([]float64, d)
+            if count > 0 {
+                for i := range weights { floats.AddScaled(centre, weights[i] / count, X.RawRowView(i)) }
+            }
+            v.Alpha[k] = Options.WeightConcentration + count
+            v.Beta[k] = beta0 + count
+            v.Nu[k] = nu0 + count
+            v.Means[k] = make([]float64, d)
+            floats.AddScaled(v.Means[k], beta0 / v.Beta[k], mean0)
+            floats.AddScaled(v.Means[k], count / v.Beta[k], centre)
+            // W_k^{-1} = W_0^{-1} + N_k S_k + beta_0 N_k / (beta_0 + N_k) (xbar_k - m_0)(xbar_k - m_0)^T
+            inverseW := weightedScatter(X, weights, centre)
+            inverseW.ScaleSym(count, inverseW)
+            inverseW.AddSym(inverseW, inverseW0)
+            shift := make([]float64, d)
+            floats.SubTo(shift, centre, mean0)
+            inverseW.SymRankOne(inverseW, beta0 * count / (beta0 + count), mat.NewVecDense(d, shift))
+            v.W[k] = inverseSym(inverseW)
+        }
+        if v.Iterations > 1 && change < Options.Tolerance {
+            v.Converged = true
+            break
+        }
+        R = v.Responsibilities(X)
+    }
+    return v
+}
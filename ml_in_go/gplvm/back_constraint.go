@@ -0,0 +1,55 @@
+package gplvm
+
+import (
+    "gonum.org/v1/gonum/mat"
+)
+
+
+/*
+SUMMARY
+    The weights of the back constraint reproducing given latent points, the solution of k(Y,Y) A = X.
+    A jitter of 1e-4 on the diagonal keeps the factorisation stable, so the latent points are
+    reproduced up to the jitter.
+PARAMETERS
+    BackKernel *mat.Dense: N by N matrix, k(Y,Y) of the back constraint
+    X *mat.Dense: N by Q matrix, the latent points
+RETURN
+    *mat.Dense: N by Q matrix, the weights A
+    error: *FactorisationError if the kernel matrix is not positive definite
+*/
+func backWeights(BackKernel, X *mat.Dense) (*mat.Dense, error) {
+    n, q := X.Dims()
+    sym := mat.NewSymDense(n, nil)
+    for i:=0; i<n; i++ {
+        for j:=i; j<n; j++ { sym.SetSym(i, j, 0.5 * (BackKernel.At(i, j) + BackKernel.At(j, i))) }
+        sym.SetSym(i, i, sym.At(i, i) + 1e-4)
+    }
+    var chol mat.Cholesky
+    if ok := chol.Factorize(sym); !ok { return nil, &FactorisationError{Factorisation: "Cholesky"} }
+    A := mat.NewDense(n, q, nil)
+    if err := chol.SolveTo(A, X); err != nil { return nil, err }
+    return A, nil
+}
+
+
+/*
+SUMMARY
+    Maps observations to the latent space with the back constraint, x = k(y,Y) A.
+PARAMETERS
+    YStar *mat.Dense: M by D matrix, the observations
+RETURN
+    *mat.Dense: M by Q matrix, the latent points
+    error: *ParameterError if the model is not back constrained
+*/
+func (m GPLVM) Encode(YStar *mat.Dense) (*mat.Dense, error) {
+    if m.BackWeights == nil {
+        return nil, &ParameterError{Parameter: "BackConstrained", Value: 0, Expected: "true for Encode"}
+    }
+    mStar, _ := YStar.Dims()
+    _, q := m.BackWeights.Dims()
+    centred := mat.DenseCopyOf(YStar)
+    centred.Apply(func (i, j int, v float64) float64 { return v - m.Mean[j] }, centred)
+    X := mat.NewDense(mStar, q, nil)
+    X.Mul(crossKernel(centred, m.Y, m.BackKernel), m.BackWeights)
+    return X, nil
+}
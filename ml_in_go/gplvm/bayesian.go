@@ -0,0 +1,491 @@
+package gplvm
+
+import (
+    "math"
+    "sort"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+
+    "ml_playground/optimisers"
+    "ml_playground/pca"
+)
+
+
+/*
+This type is the RBF kernel with automatic relevance determination,
+    k(x,x') = VarSigma exp(-1/2 \sum_q Relevances_q (x_q - x'_q)^2),
+a latent dimension with small relevance hardly changes the kernel, so it is switched off.
+    VarSigma float64: the variance of the function values
+    Relevances []float64: the inverse squared length scales of the latent dimensions
+*/
+type ARDKernel struct {
+    VarSigma float64
+    Relevances []float64
+}
+
+
+/*
+This type configures FitBayesian, the zero fields take the default values.
+    LatentDimensions int: the dimension of the latent space, ARD switches off the superfluous
+        dimensions (5, at most the dimension of the data)
+    NumInducing int: the number of inducing points (20, at most the number of points)
+    Iterations int: the maximal number of Adam steps (2000)
+    StepSize float64: the step size of Adam (0.01)
+    Seed int: seed of the choice of the initial inducing points
+*/
+type BayesianOptions struct {
+    LatentDimensions int
+    NumInducing int
+    Iterations int
+    StepSize float64
+    Seed int
+}
+
+
+/*
+This type stores a fitted Bayesian GPLVM, the variational posterior of the latent points is
+q(X) = \prod_n N(x_n | Means_n, diag(Variances_n)).
+    Mean []float64: the mean of the observed data
+    Y *mat.Dense: N by D matrix, the centred observed data
+    Means *mat.Dense: N by Q matrix, the means of the latent points
+    Variances *mat.Dense: N by Q matrix, the variances of the latent points
+    Inducing *mat.Dense: M by Q matrix, the inducing inputs
+    Kernel ARDKernel: the kernel of the Gaussian processes
+    NoiseVariance float64: the variance of the noise of the observations
+*/
+type BayesianGPLVM struct {
+    Mean []float64
+    Y *mat.Dense
+    Means *mat.Dense
+    Variances *mat.Dense
+    Inducing *mat.Dense
+    Kernel ARDKernel
+    NoiseVariance float64
+}
+
+
+/*
+This type collects the derivatives of the lower bound with respect to the variational
+parameters and the hyperparameters.
+    Means *mat.Dense: N by Q matrix
+    Variances *mat.Dense: N by Q matrix
+    Inducing *mat.Dense: M by Q matrix
+    VarSigma float64: the derivative with respect to the variance of the kernel
+    Relevances []float64: the derivatives with respect to the relevances
+    Beta float64: the derivative with respect to the precision of the noise
+*/
+type boundGradients struct {
+    Means *mat.Dense
+    Variances *mat.Dense
+    Inducing *mat.Dense
+    VarSigma float64
+    Relevances []float64
+    Beta float64
+}
+
+
+/*
+SUMMARY
+    The ARD kernel matrix between the rows of two matrices.
+PARAMETERS
+    A *mat.Dense: N by Q matrix, each row is a point
+    B *mat.Dense: M by Q matrix, each row is a point
+    Kernel ARDKernel: the kernel
+RETURN
+    *mat.Dense: N by M matrix, k(A,B)
+*/
+func ardKernel(A, B *mat.Dense, Kernel ARDKernel) *mat.Dense {
+    n, q := A.Dims()
+    m, _ := B.Dims()
+    K := mat.NewDense(n, m, nil)
+    for i:=0; i<n; i++ {
+        a := A.RawRowView(i)
+        for j:=0; j<m; j++ {
+            b := B.RawRowView(j)
+            exponent := 0.0
+            for c:=0; c<q; c++ { exponent += Kernel.Relevances[c] * (a[c] - b[c]) * (a[c] - b[c]) }
+            K.Set(i, j, Kernel.VarSigma * math.Exp(-0.5 * exponent))
+        }
+    }
+    return K
+}
+
+
+/*
+SUMMARY
+    The kernel matrix of the inducing inputs with a small jitter on the diagonal.
+PARAMETERS
+    Inducing *mat.Dense: M by Q matrix, the inducing inputs
+    Kernel ARDKernel: the kernel
+RETURN
+    *mat.SymDense: k(Z,Z) + 1e-6 VarSigma I
+*/
+func inducingCovariance(Inducing *mat.Dense, Kernel ARDKernel) *mat.SymDense {
+    m, _ := Inducing.Dims()
+    K := ardKernel(Inducing, Inducing, Kernel)
+    sym := mat.NewSymDense(m, nil)
+    for i:=0; i<m; i++ {
+        for j:=i; j<m; j++ { sym.SetSym(i, j, K.At(i, j)) }
+        sym.SetSym(i, i, sym.At(i, i) + 1e-6 * Kernel.VarSigma)
+    }
+    return sym
+}
+
+
+/*
+SUMMARY
+    The term of Psi_2 = <k(Z,x_n) k(x_n,Z)> belonging to a pair of inducing inputs and a latent point,
+        VarSigma^2 \prod_q (2 a_q S_q + 1)^{-1/2} exp(-1/4 a_q (z_q - z'_q)^2 - a_q (mu_q - (z_q + z'_q)/2)^2 / (2 a_q S_q + 1)),
+    where a are the relevances and mu, S are the mean and the variances of the latent point.
+PARAMETERS
+    Mu []float64: the mean of the latent point
+    S []float64: the variances of the latent point
+    Z1 []float64: the first inducing input
+    Z2 []float64: the second inducing input
+    Kernel ARDKernel: the kernel
+RETURN
+    float64: the term
+*/
+func psi2Term(Mu, S, Z1, Z2 []float64, Kernel ARDKernel) float64 {
+    logValue := 2.0 * math.Log(Kernel.VarSigma)
+    for c, a := range Kernel.Relevances {
+        denominator := 2.0 * a * S[c] + 1.0
+        dz, db := Z1[c] - Z2[c], Mu[c] - 0.5 * (Z1[c] + Z2[c])
+        logValue -= 0.5 * math.Log(denominator) + 0.25 * a * dz * dz + a * db * db / denominator
+    }
+    return math.Exp(logValue)
+}
+
+
+/*
+SUMMARY
+    The term of Psi_1 = <k(x_n,Z)> belonging to a latent point and an inducing input,
+        VarSigma \prod_q (a_q S_q + 1)^{-1/2} exp(-1/2 a_q (mu_q - z_q)^2 / (a_q S_q + 1)).
+PARAMETERS
+    Mu []float64: the mean of the latent point
+    S []float64: the variances of the latent point
+    Z []float64: the inducing input
+    Kernel ARDKernel: the kernel
+RETURN
+    float64: the term
+*/
+func psi1Term(Mu, S, Z []float64, Kernel ARDKernel) float64 {
+    logValue := math.Log(Kernel.VarSigma)
+    for c, a := range Kernel.Relevances {
+        denominator := a * S[c] + 1.0
+        d := Mu[c] - Z[c]
+        logValue -= 0.5 * math.Log(denominator) + 0.5 * a * d * d / denominator
+    }
+    return math.Exp(logValue)
+}
+
+
+/*
+SUMMARY
+    The variational lower bound of the log marginal likelihood of the Bayesian GPLVM (Titsias & Lawrence,
+    Bayesian Gaussian Process Latent Variable Model) with the inducing outputs integrated out,
+        F = -ND/2 log(2 Pi / beta) - D/2 log|Kmm + beta Psi_2| + D/2 log|Kmm| - beta/2 tr(Y^T Y)
+            + beta^2/2 tr(Y^T Psi_1 (Kmm + beta Psi_2)^{-1} Psi_1^T Y) - beta D/2 (psi_0 - tr(Kmm^{-1} Psi_2)) - KL(q(X) || p(X)),
+    and its derivatives. The derivatives with respect to the statistics psi_0, Psi_1, Psi_2 and Kmm are
+    contracted with the derivatives of the statistics in O(N M^2 Q).
+PARAMETERS
+    Means *mat.Dense: N by Q matrix, the means of the latent points
+    Variances *mat.Dense: N by Q matrix, the variances of the latent points
+    Inducing *mat.Dense: M by Q matrix, the inducing inputs
+    Y *mat.Dense: N by D matrix, the centred observed data
+    Kernel ARDKernel: the kernel
+    Beta float64: the precision of the noise
+RETURN
+    float64: F
+    boundGradients: the derivatives of F
+    error: *FactorisationError if a covariance is not positive definite
+*/
+func lowerBound(Means, Variances, Inducing, Y *mat.Dense, Kernel ARDKernel, Beta float64) (float64, boundGradients, error) {
+    n, d := Y.Dims()
+    m, q := Inducing.Dims()
+    fn, fd := float64(n), float64(d)
+
+    psi0 := fn * Kernel.VarSigma
+    psi1 := mat.NewDense(n, m, nil)
+    psi2 := mat.NewSymDense(m, nil)
+    for i:=0; i<n; i++ {
+        mu, s := Means.RawRowView(i), Variances.RawRowView(i)
+        for j:=0; j<m; j++ {
+            psi1.Set(i, j, psi1Term(mu, s, Inducing.RawRowView(j), Kernel))
+            for k:=j; k<m; k++ { psi2.SetSym(j, k, psi2.At(j, k) + psi2Term(mu, s, Inducing.RawRowView(j), Inducing.RawRowView(k), Kernel)) }
+        }
+    }
+    Kmm := inducingCovariance(Inducing, Kernel)
+    C := mat.NewSymDense(m, nil)
+    for j:=0; j<m; j++ {
+        for k:=j; k<m; k++ { C.SetSym(j, k, Kmm.At(j, k) + Beta * psi2.At(j, k)) }
+    }
+    var cholK, cholC mat.Cholesky
+    if ok := cholK.Factorize(Kmm); !ok { return 0, boundGradients{}, &FactorisationError{Factorisation: "Cholesky"} }
+    if ok := cholC.Factorize(C); !ok { return 0, boundGradients{}, &FactorisationError{Factorisation: "Cholesky"} }
+    var KInv, CInv mat.SymDense
+    if err := cholK.InverseTo(&KInv); err != nil { return 0, boundGradients{}, err }
+    if err := cholC.InverseTo(&CInv); err != nil { return 0, boundGradients{}, err }
+    var P, CInvP, E, KInvPsi2, CInvPsi2, KInvPsi2KInv mat.Dense
+    P.Mul(psi1.T(), Y)
+    CInvP.Mul(&CInv, &P)
+    E.Mul(&CInvP, CInvP.T())
+    KInvPsi2.Mul(&KInv, psi2)
+    CInvPsi2.Mul(&CInv, psi2)
+    KInvPsi2KInv.Mul(&KInvPsi2, &KInv)
+    norm := mat.Norm(Y, 2)
+    yy, fit := norm * norm, floats.Dot(P.RawMatrix().Data, CInvP.RawMatrix().Data)
+    EPsi2 := 0.0
+    for j:=0; j<m; j++ {
+        for k:=0; k<m; k++ { EPsi2 += E.At(j, k) * psi2.At(j, k) }
+    }
+    kl := 0.0
+    for i:=0; i<n; i++ {
+        for c:=0; c<q; c++ {
+            mu, s := Means.At(i, c), Variances.At(i, c)
+            kl += 0.5 * (mu * mu + s - math.Log(s) - 1.0)
+        }
+    }
+    value := -0.5 * fn * fd * math.Log(2.0 * math.Pi / Beta) - 0.5 * fd * (cholC.LogDet() - cholK.LogDet()) - 0.5 * Beta * yy +
+        0.5 * Beta * Beta * fit - 0.5 * Beta * fd * (psi0 - mat.Trace(&KInvPsi2)) - kl
+
+    // the derivatives with respect to the statistics
+    G2 := mat.NewDense(m, m, nil)
+    G2.Apply(func (j, k int, v float64) float64 {
+        return 0.5 * Beta * fd * (KInv.At(j, k) - CInv.At(j, k)) - 0.5 * Beta * Beta * Beta * E.At(j, k)
+    }, G2)
+    GK := mat.NewDense(m, m, nil)
+    GK.Apply(func (j, k int, v float64) float64 {
+        return -0.5 * fd * (CInv.At(j, k) - KInv.At(j, k)) - 0.5 * Beta * Beta * E.At(j, k) - 0.5 * Beta * fd * KInvPsi2KInv.At(j, k)
+    }, GK)
+    G1 := mat.NewDense(n, m, nil)
+    G1.Mul(Y, CInvP.T())
+    G1.Scale(Beta * Beta, G1)
+    grads := boundGradients{
+        Means: mat.NewDense(n, q, nil),
+        Variances: mat.NewDense(n, q, nil),
+        Inducing: mat.NewDense(m, q, nil),
+        VarSigma: -0.5 * Beta * fd * fn,
+        Relevances: make([]float64, q),
+        Beta: 0.5 * fn * fd / Beta - 0.5 * fd * mat.Trace(&CInvPsi2) - 0.5 * yy + Beta * fit - 0.5 * Beta * Beta * EPsi2 -
+            0.5 * fd * psi0 + 0.5 * fd * mat.Trace(&KInvPsi2),
+    }
+
+    // the chain rule through the statistics, with the derivatives of their logarithms
+    for i:=0; i<n; i++ {
+        mu, s := Means.RawRowView(i), Variances.RawRowView(i)
+        gradMu, gradS := grads.Means.RawRowView(i), grads.Variances.RawRowView(i)
+        for j:=0; j<m; j++ {
+            z := Inducing.RawRowView(j)
+            gradZ := grads.Inducing.RawRowView(j)
+            w := G1.At(i, j) * psi1.At(i, j)
+            grads.VarSigma += w / Kernel.VarSigma
+            for c, a := range Kernel.Relevances {
+                denominator := a * s[c] + 1.0
+                diff := mu[c] - z[c]
+                gradMu[c] -= w * a * diff / denominator
+                gradZ[c] += w * a * diff / denominator
+                gradS[c] += w * 0.5 * a * (a * diff * diff / denominator - 1.0) / denominator
+                grads.Relevances[c] -= w * 0.5 * (s[c] / denominator + diff * diff / (denominator * denominator))
+            }
+            for k:=j; k<m; k++ {
+                z2 := Inducing.RawRowView(k)
+                gradZ2 := grads.Inducing.RawRowView(k)
+                w := G2.At(j, k) * psi2Term(mu, s, z, z2, Kernel)
+                // the off diagonal terms appear twice in Psi_2
+                if k != j { w *= 2.0 }
+                grads.VarSigma += 2.0 * w / Kernel.VarSigma
+                for c, a := range Kernel.Relevances {
+                    denominator := 2.0 * a * s[c] + 1.0
+                    dz, db := z[c] - z2[c], mu[c] - 0.5 * (z[c] + z2[c])
+                    gradMu[c] -= w * 2.0 * a * db / denominator
+                    gradS[c] += w * a * (2.0 * a * db * db / denominator - 1.0) / denominator
+                    gradZ[c] += w * (a * db / denominator - 0.5 * a * dz)
+                    gradZ2[c] += w * (a * db / denominator + 0.5 * a * dz)
+                    grads.Relevances[c] -= w * (s[c] / denominator + 0.25 * dz * dz + db * db / (denominator * denominator))
+                }
+            }
+        }
+        for c:=0; c<q; c++ {
+            gradMu[c] -= mu[c]
+            gradS[c] -= 0.5 * (1.0 - 1.0 / s[c])
+        }
+    }
+    for j:=0; j<m; j++ {
+        z := Inducing.RawRowView(j)
+        gradZ := grads.Inducing.RawRowView(j)
+        for k:=j; k<m; k++ {
+            z2 := Inducing.RawRowView(k)
+            gradZ2 := grads.Inducing.RawRowView(k)
+            exponent := 0.0
+            for c, a := range Kernel.Relevances { exponent += a * (z[c] - z2[c]) * (z[c] - z2[c]) }
+            w := GK.At(j, k) * Kernel.VarSigma * math.Exp(-0.5 * exponent)
+            if k != j { w *= 2.0 }
+            // the jitter is proportional to VarSigma
+            if k == j { w += 1e-6 * GK.At(j, j) * Kernel.VarSigma }
+            grads.VarSigma += w / Kernel.VarSigma
+            for c, a := range Kernel.Relevances {
+                dz := z[c] - z2[c]
+                gradZ[c] -= w * a * dz
+                gradZ2[c] += w * a * dz
+                grads.Relevances[c] -= w * 0.5 * dz * dz
+            }
+        }
+    }
+    return value, grads, nil
+}
+
+
+/*
+SUMMARY
+    Fits a Bayesian GPLVM by maximising the variational lower bound with Adam. The means of the latent
+    points are initialised with the principal components of the data scaled so that the leading one
+    has unit variance, the variances with 1/2 and the inducing inputs with randomly chosen means.
+    The variances, the kernel parameters and the noise variance are optimised through their logarithms.
+PARAMETERS
+    Y *mat.Dense: N by D matrix, the observed data
+    Options BayesianOptions: the settings
+RETURN
+    BayesianGPLVM: the fitted model
+    Result: the trace of the negative lower bound and the convergence
+    error: *ParameterError if the options are invalid, *FactorisationError if a covariance
+        is not positive definite, *optimisers.DivergenceError if Adam diverges
+*/
+func FitBayesian(Y *mat.Dense, Options BayesianOptions) (BayesianGPLVM, Result, error) {
+    n, d := Y.Dims()
+    q := Options.LatentDimensions
+    if q == 0 { q = int(math.Min(5, float64(d))) }
+    if q < 0 || q > d || q >= n {
+        return BayesianGPLVM{}, Result{}, &ParameterError{Parameter: "LatentDimensions", Value: float64(q), Expected: "in [1, #dimensions] and less than #points"}
+    }
+    m := Options.NumInducing
+    if m == 0 { m = int(math.Min(20, float64(n))) }
+    if m < 0 || m > n {
+        return BayesianGPLVM{}, Result{}, &ParameterError{Parameter: "NumInducing", Value: float64(m), Expected: "in [1, #points]"}
+    }
+    iterations := Options.Iterations
+    if iterations == 0 { iterations = 2000 }
+    stepSize := Options.StepSize
+    if stepSize == 0 { stepSize = 0.01 }
+
+    principal, err := pca.Fit(Y, pca.Options{NumComponents: q})
+    if err != nil { return BayesianGPLVM{}, Result{}, err }
+    model := BayesianGPLVM{Mean: principal.Mean, Means: principal.Transform(Y), Variances: mat.NewDense(n, q, nil), Inducing: mat.NewDense(m, q, nil)}
+    model.Means.Scale(1.0 / math.Sqrt(principal.ExplainedVariance[0]), model.Means)
+    model.Y = mat.NewDense(n, d, nil)
+    model.Y.Apply(func (i, j int, v float64) float64 { return v - model.Mean[j] }, Y)
+    variance := averageVariance(model.Y)
+    model.Kernel = ARDKernel{VarSigma: variance, Relevances: make([]float64, q)}
+    for c := range model.Kernel.Relevances { model.Kernel.Relevances[c] = 1.0 }
+    model.NoiseVariance = 0.1 * variance
+    for i:=0; i<n; i++ {
+        for c:=0; c<q; c++ { model.Variances.Set(i, c, 0.5) }
+    }
+    for j, i := range rand.New(rand.NewSource(uint64(Options.Seed))).Perm(n)[:m] { model.Inducing.SetRow(j, model.Means.RawRowView(i)) }
+
+    optimiser, err := optimisers.Adam(stepSize, 0.9, 0.999, 1e-8, 1e-6)
+    if err != nil { return BayesianGPLVM{}, Result{}, err }
+    var result Result
+    var boundErr error
+    // the position is [Means, log(Variances), Inducing, log(Relevances), log(VarSigma), log(NoiseVariance)]
+    unpack := func (At []float64) BayesianGPLVM {
+        unpacked := BayesianGPLVM{Mean: model.Mean, Y: model.Y, Kernel: ARDKernel{Relevances: make([]float64, q)}}
+        unpacked.Means = mat.NewDense(n, q, At[:n*q])
+        unpacked.Variances = mat.NewDense(n, q, nil)
+        unpacked.Variances.Apply(func (i, c int, v float64) float64 { return math.Exp(At[n*q + i*q + c]) }, unpacked.Variances)
+        unpacked.Inducing = mat.NewDense(m, q, At[2*n*q:2*n*q + m*q])
+        rest := At[2*n*q + m*q:]
+        for c := range unpacked.Kernel.Relevances { unpacked.Kernel.Relevances[c] = math.Exp(rest[c]) }
+        unpacked.Kernel.VarSigma, unpacked.NoiseVariance = math.Exp(rest[q]), math.Exp(rest[q+1])
+        return unpacked
+    }
+    gradient := func (At []float64) []float64 {
+        current := unpack(At)
+        beta := 1.0 / current.NoiseVariance
+        value, grads, err := lowerBound(current.Means, current.Variances, current.Inducing, current.Y, current.Kernel, beta)
+        if err != nil {
+            boundErr = err
+            return []float64{math.NaN()}
+        }
+        result.NegLogLikelihoods = append(result.NegLogLikelihoods, -value)
+        grad := make([]float64, 0, len(At))
+        for _, g := range grads.Means.RawMatrix().Data { grad = append(grad, -g) }
+        for i, g := range grads.Variances.RawMatrix().Data { grad = append(grad, -g * current.Variances.RawMatrix().Data[i]) }
+        for _, g := range grads.Inducing.RawMatrix().Data { grad = append(grad, -g) }
+        for c, g := range grads.Relevances { grad = append(grad, -g * current.Kernel.Relevances[c]) }
+        // d/dlog(NoiseVariance) = -beta d/dbeta
+        return append(grad, -grads.VarSigma * current.Kernel.VarSigma, grads.Beta * beta)
+    }
+    At := append([]float64{}, model.Means.RawMatrix().Data...)
+    for _, v := range model.Variances.RawMatrix().Data { At = append(At, math.Log(v)) }
+    At = append(At, model.Inducing.RawMatrix().Data...)
+    for _, a := range model.Kernel.Relevances { At = append(At, math.Log(a)) }
+    At = append(At, math.Log(model.Kernel.VarSigma), math.Log(model.NoiseVariance))
+    for !result.Converged && result.Iterations < iterations {
+        At, result.Converged, result.Iterations, err = optimiser(gradient, At)
+        if boundErr != nil { return unpack(At), result, boundErr }
+        if err != nil { return unpack(At), result, err }
+    }
+    return unpack(At), result, nil
+}
+
+
+/*
+SUMMARY
+    The variational lower bound of the log marginal likelihood of the fitted model.
+PARAMETERS
+    N/A
+RETURN
+    float64: the lower bound
+    error: *FactorisationError if a covariance is not positive definite
+*/
+func (m BayesianGPLVM) LowerBound() (float64, error) {
+    value, _, err := lowerBound(m.Means, m.Variances, m.Inducing, m.Y, m.Kernel, 1.0 / m.NoiseVariance)
+    return value, err
+}
+
+
+/*
+SUMMARY
+    The latent dimensions switched on by ARD, those whose relevance is at least a fraction of the largest one.
+PARAMETERS
+    Threshold float64: the fraction of the largest relevance, e.g. 0.05
+RETURN
+    []int: the dimensions in decreasing order of relevance
+*/
+func (m BayesianGPLVM) ActiveDimensions(Threshold float64) []int {
+    largest := floats.Max(m.Kernel.Relevances)
+    var active []int
+    for c, a := range m.Kernel.Relevances {
+        if a >= Threshold * largest { active = append(active, c) }
+    }
+    sort.Slice(active, func (i, j int) bool { return m.Kernel.Relevances[active[i]] > m.Kernel.Relevances[active[j]] })
+    return active
+}
+
+
+/*
+SUMMARY
+    The marginal of the variational posterior of every latent point on some latent dimensions,
+    e.g. on the two most relevant ones for plotting.
+PARAMETERS
+    Dimensions []int: the latent dimensions
+RETURN
+    *mat.Dense: N by len(Dimensions) matrix, the means
+    []*mat.SymDense: the diagonal covariance of each point
+*/
+func (m BayesianGPLVM) Marginal(Dimensions []int) (*mat.Dense, []*mat.SymDense) {
+    n, _ := m.Means.Dims()
+    means := mat.NewDense(n, len(Dimensions), nil)
+    covariances := make([]*mat.SymDense, n)
+    for i:=0; i<n; i++ {
+        covariances[i] = mat.NewSymDense(len(Dimensions), nil)
+        for c, dim := range Dimensions {
+            means.Set(i, c, m.Means.At(i, dim))
+            covariances[i].SetSym(c, c, m.Variances.At(i, dim))
+        }
+    }
+    return means, covariances
+}
@@ -0,0 +1,61 @@
+package gplvm
+
+import (
+    "testing"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+)
+
+
+func TestLowerBoundGradients(t *testing.T) {
+    randGen := rand.New(rand.NewSource(2))
+    means, inducing, Y := randomMatrix(6, 2, randGen), randomMatrix(3, 2, randGen), randomMatrix(6, 3, randGen)
+    variances := mat.NewDense(6, 2, nil)
+    variances.Apply(func (i, j int, v float64) float64 { return 0.2 + 0.5 * randGen.Float64() }, variances)
+    kernel := ARDKernel{VarSigma: 1.4, Relevances: []float64{0.9, 0.3}}
+    beta := 2.0
+    value := func (Means, Variances, Inducing *mat.Dense, Kernel ARDKernel, Beta float64) float64 {
+        v, _, err := lowerBound(Means, Variances, Inducing, Y, Kernel, Beta)
+        if err != nil { t.Fatal(err) }
+        return v
+    }
+    _, grads, err := lowerBound(means, variances, inducing, Y, kernel, beta)
+    if err != nil { t.Fatal(err) }
+
+    for _, matrix := range []struct {
+        name string
+        A, gradient *mat.Dense
+    }{
+        {name: "dF/dMeans", A: means, gradient: grads.Means},
+        {name: "dF/dVariances", A: variances, gradient: grads.Variances},
+        {name: "dF/dInducing", A: inducing, gradient: grads.Inducing},
+    } {
+        rows, cols := matrix.A.Dims()
+        for i:=0; i<rows; i++ {
+            for a:=0; a<cols; a++ {
+                numeric := centralDifference(func (x float64) float64 {
+                    original := matrix.A.At(i, a)
+                    matrix.A.Set(i, a, x)
+                    v := value(means, variances, inducing, kernel, beta)
+                    matrix.A.Set(i, a, original)
+                    return v
+                }, matrix.A.At(i, a))
+                checkDerivative(t, matrix.name, matrix.gradient.At(i, a), numeric)
+            }
+        }
+    }
+    checkDerivative(t, "dF/dVarSigma", grads.VarSigma, centralDifference(func (s float64) float64 {
+        return value(means, variances, inducing, ARDKernel{VarSigma: s, Relevances: kernel.Relevances}, beta)
+    }, kernel.VarSigma))
+    for c := range kernel.Relevances {
+        checkDerivative(t, "dF/dRelevances", grads.Relevances[c], centralDifference(func (r float64) float64 {
+            relevances := append([]float64{}, kernel.Relevances...)
+            relevances[c] = r
+            return value(means, variances, inducing, ARDKernel{VarSigma: kernel.VarSigma, Relevances: relevances}, beta)
+        }, kernel.Relevances[c]))
+    }
+    checkDerivative(t, "dF/dBeta", grads.Beta, centralDifference(func (b float64) float64 {
+        return value(means, variances, inducing, kernel, b)
+    }, beta))
+}
@@ -0,0 +1,35 @@
+package gplvm
+
+import (
+    "fmt"
+)
+
+
+/*
+This type is returned when an option or the shape of the data is invalid.
+    Parameter string: the name of the offending option
+    Value float64: the value we received
+    Expected string: the description of the valid values
+*/
+type ParameterError struct {
+    Parameter string
+    Value float64
+    Expected string
+}
+
+func (e *ParameterError) Error() string {
+    return fmt.Sprintf("gplvm: %s must be %s, got %g", e.Parameter, e.Expected, e.Value)
+}
+
+
+/*
+This type is returned when a matrix factorisation fails.
+    Factorisation string: the name of the factorisation, e.g. "SVD"
+*/
+type FactorisationError struct {
+    Factorisation string
+}
+
+func (e *FactorisationError) Error() string {
+    return fmt.Sprintf("gplvm: %s has failed", e.Factorisation)
+}
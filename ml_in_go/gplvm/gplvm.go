@@ -0,0 +1,393 @@
+/*
+This library contains the Gaussian process latent variable model (Lawrence, Probabilistic Non-linear
+Principal Component Analysis with Gaussian Process Latent Variable Models). Every dimension of the
+observed points is an independent Gaussian process of the latent points. The data points are the
+rows of the data matrices.
+*/
+package gplvm
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+
+    "ml_playground/kernels"
+    "ml_playground/optimisers"
+    "ml_playground/pca"
+)
+
+
+/*
+This type configures Fit, the zero fields take the default values.
+    LatentDimensions int: the dimension of the latent space (2)
+    Kernel kernels.Parameters: the initial kernel, an RBF kernel with the average variance of the
+        data as VarSigma and LengthScale 2 if VarSigma is 0
+    NoiseVariance float64: the initial variance of the noise (10% of the average variance of the data)
+    FixHyperparameters bool: whether only the latent points are optimised
+    Iterations int: the maximal number of Adam steps (1000)
+    StepSize float64: the step size of Adam (0.01)
+    BackConstrained bool: whether the latent points are a kernel regression of the data, X = k(Y,Y) A,
+        so that nearby observations stay nearby in the latent space and new observations can be encoded
+    BackKernel kernels.Parameters: the kernel of the back constraint, an RBF kernel with VarSigma 1 and
+        a tenth of the average squared distance of the points as LengthScale if VarSigma is 0
+*/
+type Options struct {
+    LatentDimensions int
+    Kernel kernels.Parameters
+    NoiseVariance float64
+    FixHyperparameters bool
+    Iterations int
+    StepSize float64
+    BackConstrained bool
+    BackKernel kernels.Parameters
+}
+
+
+/*
+This type stores the outcome of the optimisation.
+    NegLogLikelihoods []float64: the objective at the start of each step
+    Iterations int: the number of steps
+    Converged bool: whether Adam stopped because of its tolerance
+*/
+type Result struct {
+    NegLogLikelihoods []float64
+    Iterations int
+    Converged bool
+}
+
+
+/*
+This type stores a fitted GPLVM.
+    Mean []float64: the mean of the observed data
+    Y *mat.Dense: N by D matrix, the centred observed data
+    X *mat.Dense: N by Q matrix, the latent points
+    Kernel kernels.Parameters: the kernel of the Gaussian processes
+    NoiseVariance float64: the variance of the noise of the observations
+    BackKernel kernels.Parameters: the kernel of the back constraint
+    BackWeights *mat.Dense: N by Q matrix, the weights A of the back constraint X = k(Y,Y) A,
+        nil if the model is not back constrained
+*/
+type GPLVM struct {
+    Mean []float64
+    Y *mat.Dense
+    X *mat.Dense
+    Kernel kernels.Parameters
+    NoiseVariance float64
+    BackKernel kernels.Parameters
+    BackWeights *mat.Dense
+}
+
+
+/*
+SUMMARY
+    The logarithms of the kernel parameters used by the kernel type and of the noise variance,
+    the optimisation works with logarithms so that the parameters stay positive.
+PARAMETERS
+    Params kernels.Parameters: the kernel
+    NoiseVariance float64: the variance of the noise
+RETURN
+    []float64: the logarithms
+*/
+func packHyperparameters(Params kernels.Parameters, NoiseVariance float64) []float64 {
+    switch Params.Type {
+        case kernels.RBF:
+            return []float64{math.Log(Params.VarSigma), math.Log(Params.LengthScale), math.Log(NoiseVariance)}
+        case kernels.LINEAR:
+            return []float64{math.Log(Params.VarSigma), math.Log(NoiseVariance)}
+        case kernels.PERIODIC:
+            return []float64{math.Log(Params.VarSigma), math.Log(Params.LengthScale), math.Log(Params.Period), math.Log(NoiseVariance)}
+    }
+    panic("Unknown kernel encountered")
+}
+
+
+/*
+SUMMARY
+    The inverse of packHyperparameters.
+PARAMETERS
+    Logs []float64: the logarithms
+    Type int: the type of the kernel
+RETURN
+    kernels.Parameters: the kernel
+    float64: the variance of the noise
+*/
+func unpackHyperparameters(Logs []float64, Type int) (kernels.Parameters, float64) {
+    params := kernels.Parameters{Type: Type, VarSigma: math.Exp(Logs[0])}
+    switch Type {
+        case kernels.RBF:
+            params.LengthScale = math.Exp(Logs[1])
+        case kernels.PERIODIC:
+            params.LengthScale, params.Period = math.Exp(Logs[1]), math.Exp(Logs[2])
+    }
+    return params, math.Exp(Logs[len(Logs)-1])
+}
+
+
+/*
+SUMMARY
+    The derivatives with respect to the logarithms of the hyperparameters, d/dlog(t) = t d/dt.
+PARAMETERS
+    Params kernels.Parameters: the kernel
+    Grads kernels.Parameters: the derivatives with respect to the kernel parameters
+    NoiseVariance float64: the variance of the noise
+    NoiseGrad float64: the derivative with respect to the noise variance
+RETURN
+    []float64: the derivatives in the order of packHyperparameters
+*/
+func packGradients(Params, Grads kernels.Parameters, NoiseVariance, NoiseGrad float64) []float64 {
+    var logs []float64
+    switch Params.Type {
+        case kernels.RBF:
+            logs = []float64{Params.VarSigma * Grads.VarSigma, Params.LengthScale * Grads.LengthScale}
+        case kernels.LINEAR:
+            logs = []float64{Params.VarSigma * Grads.VarSigma}
+        case kernels.PERIODIC:
+            logs = []float64{Params.VarSigma * Grads.VarSigma, Params.LengthScale * Grads.LengthScale, Params.Period * Grads.Period}
+    }
+    return append(logs, NoiseVariance * NoiseGrad)
+}
+
+
+/*
+SUMMARY
+    The kernel matrix between the rows of two matrices. Unlike kernels.Kernel, no jitter is added,
+    so the rows agree with the kernel of single points.
+PARAMETERS
+    A *mat.Dense: N by Q matrix, each row is a point
+    B *mat.Dense: M by Q matrix, each row is a point
+    Params kernels.Parameters: the kernel
+RETURN
+    *mat.Dense: N by M matrix, k(A,B)
+*/
+func crossKernel(A, B *mat.Dense, Params kernels.Parameters) *mat.Dense {
+    n, _ := A.Dims()
+    m, _ := B.Dims()
+    K := mat.NewDense(n, m, nil)
+    for i:=0; i<n; i++ {
+        values, _ := kernels.PointGradients(A.RawRowView(i), B, Params)
+        K.SetRow(i, values)
+    }
+    return K
+}
+
+
+/*
+SUMMARY
+    The covariance of the observations, k(X,X) + NoiseVariance I, and its Cholesky factorisation.
+PARAMETERS
+    X *mat.Dense: the latent points, each row is a point
+    Params kernels.Parameters: the kernel
+    NoiseVariance float64: the variance of the noise
+RETURN
+    *mat.Cholesky: the factorisation
+    error: *FactorisationError if the covariance is not positive definite
+*/
+func covariance(X *mat.Dense, Params kernels.Parameters, NoiseVariance float64) (*mat.Cholesky, error) {
+    n, _ := X.Dims()
+    K := kernels.Kernel(X, X, Params)
+    sym := mat.NewSymDense(n, nil)
+    for i:=0; i<n; i++ {
+        for j:=i; j<n; j++ { sym.SetSym(i, j, 0.5 * (K.At(i, j) + K.At(j, i))) }
+        sym.SetSym(i, i, sym.At(i, i) + NoiseVariance)
+    }
+    var chol mat.Cholesky
+    if ok := chol.Factorize(sym); !ok { return nil, &FactorisationError{Factorisation: "Cholesky"} }
+    return &chol, nil
+}
+
+
+/*
+SUMMARY
+    The negative log posterior of the latent points and the hyperparameters,
+        L = D/2 log|K| + 1/2 tr(K^{-1} Y Y^T) + ND/2 log(2 Pi) + 1/2 |X|^2,
+    where the last term is the standard normal prior of the latent points, and its derivatives
+    through dL/dK = 1/2 (D K^{-1} - K^{-1} Y Y^T K^{-1}).
+PARAMETERS
+    X *mat.Dense: N by Q matrix, the latent points
+    Y *mat.Dense: N by D matrix, the centred observed data
+    Params kernels.Parameters: the kernel
+    NoiseVariance float64: the variance of the noise
+RETURN
+    float64: L
+    *mat.Dense: dL/dX
+    kernels.Parameters: the derivatives with respect to the kernel parameters
+    float64: the derivative with respect to the noise variance
+    error: *FactorisationError if the covariance is not positive definite
+*/
+func objective(X, Y *mat.Dense, Params kernels.Parameters, NoiseVariance float64) (float64, *mat.Dense, kernels.Parameters, float64, error) {
+    n, d := Y.Dims()
+    chol, err := covariance(X, Params, NoiseVariance)
+    if err != nil { return 0, nil, kernels.Parameters{}, 0, err }
+    var inverse mat.SymDense
+    if err := chol.InverseTo(&inverse); err != nil { return 0, nil, kernels.Parameters{}, 0, err }
+    var alpha mat.Dense
+    if err := chol.SolveTo(&alpha, Y); err != nil { return 0, nil, kernels.Parameters{}, 0, err }
+
+    value := 0.5 * float64(d) * chol.LogDet() + 0.5 * float64(n * d) * math.Log(2 * math.Pi)
+    for i:=0; i<n; i++ {
+        value += 0.5 * floats.Dot(Y.RawRowView(i), alpha.RawRowView(i))
+        value += 0.5 * floats.Dot(X.RawRowView(i), X.RawRowView(i))
+    }
+    G := mat.NewDense(n, n, nil)
+    G.Mul(&alpha, alpha.T())
+    G.Apply(func (i, j int, v float64) float64 { return 0.5 * (float64(d) * inverse.At(i, j) - v) }, G)
+    gradX, grads := kernels.KernelGradients(X, G, Params, 1)
+    gradX.Add(gradX, X)
+    return value, gradX, grads, mat.Trace(G), nil
+}
+
+
+/*
+SUMMARY
+    The average variance of the columns of a matrix.
+PARAMETERS
+    Y *mat.Dense: the centred data, each row is a point
+RETURN
+    float64: the average variance
+*/
+func averageVariance(Y *mat.Dense) float64 {
+    n, d := Y.Dims()
+    norm := mat.Norm(Y, 2)
+    return norm * norm / float64(n * d)
+}
+
+
+/*
+SUMMARY
+    Fits a GPLVM. The latent points are initialised with the whitened principal components of the
+    data, then the latent points, the kernel parameters and the noise variance are optimised jointly
+    by Adam on the negative log marginal likelihood plus a standard normal prior on the latent points.
+    A back constrained model (Lawrence & Quinonero-Candela, Local Distance Preservation in the GP-LVM
+    through Back Constraints) optimises the weights of the back constraint instead of the latent points,
+    dL/dA = k(Y,Y) dL/dX, starting from the weights reproducing the principal components.
+PARAMETERS
+    Y *mat.Dense: N by D matrix, the observed data
+    Options Options: the settings
+RETURN
+    GPLVM: the fitted model
+    Result: the trace of the objective and the convergence
+    error: *ParameterError if the options are invalid, *FactorisationError if a covariance
+        is not positive definite, *optimisers.DivergenceError if Adam diverges
+*/
+func Fit(Y *mat.Dense, Options Options) (GPLVM, Result, error) {
+    n, d := Y.Dims()
+    q := Options.LatentDimensions
+    if q == 0 { q = 2 }
+    if q < 0 || q > d || q >= n {
+        return GPLVM{}, Result{}, &ParameterError{Parameter: "LatentDimensions", Value: float64(q), Expected: "in [1, #dimensions] and less than #points"}
+    }
+    iterations := Options.Iterations
+    if iterations == 0 { iterations = 1000 }
+    stepSize := Options.StepSize
+    if stepSize == 0 { stepSize = 0.01 }
+
+    principal, err := pca.Fit(Y, pca.Options{NumComponents: q, Whiten: true})
+    if err != nil { return GPLVM{}, Result{}, err }
+    model := GPLVM{Mean: principal.Mean, X: principal.Transform(Y), Kernel: Options.Kernel, NoiseVariance: Options.NoiseVariance}
+    model.Y = mat.NewDense(n, d, nil)
+    model.Y.Apply(func (i, j int, v float64) float64 { return v - model.Mean[j] }, Y)
+    variance := averageVariance(model.Y)
+    if model.Kernel.VarSigma == 0 { model.Kernel = kernels.Parameters{Type: kernels.RBF, VarSigma: variance, LengthScale: 2.0} }
+    if model.NoiseVariance == 0 { model.NoiseVariance = 0.1 * variance }
+
+    var backKernel *mat.Dense
+    if Options.BackConstrained {
+        model.BackKernel = Options.BackKernel
+        if model.BackKernel.VarSigma == 0 {
+            // the average squared distance of centred points is twice their average squared norm
+            model.BackKernel = kernels.Parameters{Type: kernels.RBF, VarSigma: 1.0, LengthScale: 0.1 * 2.0 * float64(d) * variance}
+        }
+        // without jitter, so that Encode maps the training data to the latent points
+        backKernel = crossKernel(model.Y, model.Y, model.BackKernel)
+        if model.BackWeights, err = backWeights(backKernel, model.X); err != nil { return GPLVM{}, Result{}, err }
+    }
+    // the latent points, or the weights of the back constraint
+    latent := func (At []float64) *mat.Dense {
+        X := mat.NewDense(n, q, append([]float64{}, At[:n*q]...))
+        if backKernel != nil { X.Mul(backKernel, X) }
+        return X
+    }
+
+    optimiser, err := optimisers.Adam(stepSize, 0.9, 0.999, 1e-8, 1e-6)
+    if err != nil { return GPLVM{}, Result{}, err }
+    var result Result
+    var objectiveErr error
+    numLatent := n * q
+    gradient := func (At []float64) []float64 {
+        X := latent(At)
+        params, noise := model.Kernel, model.NoiseVariance
+        if !Options.FixHyperparameters { params, noise = unpackHyperparameters(At[numLatent:], model.Kernel.Type) }
+        value, gradX, grads, noiseGrad, err := objective(X, model.Y, params, noise)
+        if err != nil {
+            objectiveErr = err
+            return []float64{math.NaN()}
+        }
+        result.NegLogLikelihoods = append(result.NegLogLikelihoods, value)
+        if backKernel != nil { gradX.Mul(backKernel, gradX) }
+        grad := append([]float64{}, gradX.RawMatrix().Data...)
+        if !Options.FixHyperparameters { grad = append(grad, packGradients(params, grads, noise, noiseGrad)...) }
+        return grad
+    }
+    At := append([]float64{}, model.X.RawMatrix().Data...)
+    if backKernel != nil { At = append([]float64{}, model.BackWeights.RawMatrix().Data...) }
+    if !Options.FixHyperparameters { At = append(At, packHyperparameters(model.Kernel, model.NoiseVariance)...) }
+    for !result.Converged && result.Iterations < iterations {
+        At, result.Converged, result.Iterations, err = optimiser(gradient, At)
+        if objectiveErr != nil { return model, result, objectiveErr }
+        if err != nil { return model, result, err }
+    }
+    model.X = latent(At)
+    if backKernel != nil { model.BackWeights = mat.NewDense(n, q, At[:numLatent]) }
+    if !Options.FixHyperparameters { model.Kernel, model.NoiseVariance = unpackHyperparameters(At[numLatent:], model.Kernel.Type) }
+    return model, result, nil
+}
+
+
+/*
+SUMMARY
+    The negative log posterior of the fitted model, the objective minimised by Fit.
+PARAMETERS
+    N/A
+RETURN
+    float64: the objective
+    error: *FactorisationError if the covariance is not positive definite
+*/
+func (m GPLVM) NegLogLikelihood() (float64, error) {
+    value, _, _, _, err := objective(m.X, m.Y, m.Kernel, m.NoiseVariance)
+    return value, err
+}
+
+
+/*
+SUMMARY
+    Maps latent points to the observed space with the posterior of the Gaussian processes.
+PARAMETERS
+    XStar *mat.Dense: M by Q matrix, the latent points
+RETURN
+    *mat.Dense: M by D matrix, the posterior means of the observations
+    []float64: the posterior variances of the noise-free function values, shared by the D dimensions
+    error: *FactorisationError if the covariance is not positive definite
+*/
+func (m GPLVM) Predict(XStar *mat.Dense) (*mat.Dense, []float64, error) {
+    mStar, q := XStar.Dims()
+    _, d := m.Y.Dims()
+    chol, err := covariance(m.X, m.Kernel, m.NoiseVariance)
+    if err != nil { return nil, nil, err }
+    // the jitter of Kernel belongs to the training covariance only, not to the cross-covariance
+    KStar := crossKernel(XStar, m.X, m.Kernel)
+    var alpha mat.Dense
+    if err := chol.SolveTo(&alpha, m.Y); err != nil { return nil, nil, err }
+    means := mat.NewDense(mStar, d, nil)
+    means.Mul(KStar, &alpha)
+    means.Apply(func (i, j int, v float64) float64 { return v + m.Mean[j] }, means)
+    var solved mat.Dense
+    if err := chol.SolveTo(&solved, KStar.T()); err != nil { return nil, nil, err }
+    variances := make([]float64, mStar)
+    for i:=0; i<mStar; i++ {
+        point := XStar.RawRowView(i)
+        prior, _ := kernels.PointGradients(point, mat.NewDense(1, q, point), m.Kernel)
+        variances[i] = math.Max(prior[0] - floats.Dot(KStar.RawRowView(i), mat.Col(nil, i, &solved)), 0.0)
+    }
+    return means, variances, nil
+}
@@ -0,0 +1,130 @@
+package gplvm
+
+import (
+    "math"
+    "testing"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+
+    "ml_playground/kernels"
+)
+
+
+/*
+SUMMARY
+    A random matrix with standard normal entries.
+PARAMETERS
+    Rows int: the number of rows
+    Cols int: the number of columns
+    RandGen *rand.Rand: the random number generator
+RETURN
+    *mat.Dense: the matrix
+*/
+func randomMatrix(Rows, Cols int, RandGen *rand.Rand) *mat.Dense {
+    A := mat.NewDense(Rows, Cols, nil)
+    A.Apply(func (i, j int, v float64) float64 { return RandGen.NormFloat64() }, A)
+    return A
+}
+
+
+/*
+SUMMARY
+    The derivative of a function of one variable by central differences.
+PARAMETERS
+    F func(float64) float64: the function
+    At float64: the point
+RETURN
+    float64: the derivative
+*/
+func centralDifference(F func(float64) float64, At float64) float64 {
+    h := 1e-5 * math.Max(1.0, math.Abs(At))
+    return (F(At + h) - F(At - h)) / (2.0 * h)
+}
+
+
+/*
+SUMMARY
+    Fails the test if an analytic derivative is far from its finite difference estimate.
+PARAMETERS
+    t *testing.T: the test
+    Name string: the name of the derivative in the message
+    Analytic float64: the analytic derivative
+    Numeric float64: the finite difference estimate
+RETURN
+    N/A
+*/
+func checkDerivative(t *testing.T, Name string, Analytic, Numeric float64) {
+    t.Helper()
+    if math.Abs(Analytic - Numeric) > 1e-5 * (1.0 + math.Abs(Numeric)) {
+        t.Errorf("%s: analytic %g, finite difference %g", Name, Analytic, Numeric)
+    }
+}
+
+
+func TestObjectiveGradients(t *testing.T) {
+    randGen := rand.New(rand.NewSource(1))
+    X, Y := randomMatrix(7, 2, randGen), randomMatrix(7, 3, randGen)
+    noise := 1.0
+    for _, params := range []kernels.Parameters{
+        {Type: kernels.RBF, VarSigma: 1.3, LengthScale: 1.7},
+        {Type: kernels.LINEAR, VarSigma: 0.8},
+        {Type: kernels.PERIODIC, VarSigma: 1.1, LengthScale: 0.9, Period: 2.5},
+    } {
+        value := func (X *mat.Dense, Params kernels.Parameters, Noise float64) float64 {
+            v, _, _, _, err := objective(X, Y, Params, Noise)
+            if err != nil { t.Fatal(err) }
+            return v
+        }
+        _, gradX, grads, noiseGrad, err := objective(X, Y, params, noise)
+        if err != nil { t.Fatal(err) }
+        n, q := X.Dims()
+        for i:=0; i<n; i++ {
+            for a:=0; a<q; a++ {
+                numeric := centralDifference(func (x float64) float64 {
+                    moved := mat.DenseCopyOf(X)
+                    moved.Set(i, a, x)
+                    return value(moved, params, noise)
+                }, X.At(i, a))
+                checkDerivative(t, "dL/dX", gradX.At(i, a), numeric)
+            }
+        }
+        checkDerivative(t, "dL/dVarSigma", grads.VarSigma, centralDifference(func (s float64) float64 {
+            moved := params
+            moved.VarSigma = s
+            return value(X, moved, noise)
+        }, params.VarSigma))
+        if params.Type != kernels.LINEAR {
+            checkDerivative(t, "dL/dLengthScale", grads.LengthScale, centralDifference(func (l float64) float64 {
+                moved := params
+                moved.LengthScale = l
+                return value(X, moved, noise)
+            }, params.LengthScale))
+        }
+        if params.Type == kernels.PERIODIC {
+            checkDerivative(t, "dL/dPeriod", grads.Period, centralDifference(func (p float64) float64 {
+                moved := params
+                moved.Period = p
+                return value(X, moved, noise)
+            }, params.Period))
+        }
+        checkDerivative(t, "dL/dNoiseVariance", noiseGrad, centralDifference(func (s float64) float64 {
+            return value(X, params, s)
+        }, noise))
+    }
+}
+
+
+func TestHyperparameterPacking(t *testing.T) {
+    for _, params := range []kernels.Parameters{
+        {Type: kernels.RBF, VarSigma: 1.3, LengthScale: 1.7},
+        {Type: kernels.LINEAR, VarSigma: 0.8},
+        {Type: kernels.PERIODIC, VarSigma: 1.1, LengthScale: 0.9, Period: 2.5},
+    } {
+        unpacked, noise := unpackHyperparameters(packHyperparameters(params, 0.3), params.Type)
+        if math.Abs(noise - 0.3) > 1e-12 || math.Abs(unpacked.VarSigma - params.VarSigma) > 1e-12 ||
+           math.Abs(unpacked.LengthScale - params.LengthScale) > 1e-12 || math.Abs(unpacked.Period - params.Period) > 1e-12 {
+            t.Errorf("kernel %d: %+v and noise %g unpacked instead of %+v and 0.3", params.Type, unpacked, noise, params)
+        }
+    }
+}
@@ -0,0 +1,135 @@
+package gplvm
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+
+    "ml_playground/kernels"
+    "ml_playground/optimisers"
+)
+
+
+/*
+SUMMARY
+    The negative log posterior of the latent point of a new observation, up to a constant,
+        L(x) = D/2 log v(x) + |y - mu(x)|^2 / (2 v(x)) + 1/2 |x|^2,
+    where mu(x) and v(x) - NoiseVariance are the predictive mean and variance of the Gaussian processes,
+    and its derivative.
+PARAMETERS
+    X []float64: the latent point
+    Y []float64: the centred observation
+    m GPLVM: the fitted model
+    KInv *mat.SymDense: the inverse of the covariance of the training observations
+    Alpha *mat.Dense: N by D matrix, the inverse of the covariance times the centred training data
+RETURN
+    float64: L(x)
+    []float64: dL/dx
+*/
+func projectionObjective(X, Y []float64, m GPLVM, KInv *mat.SymDense, Alpha *mat.Dense) (float64, []float64) {
+    d := len(Y)
+    k, G := kernels.PointGradients(X, m.X, m.Kernel)
+    self, selfGrad := kernels.PointGradients(X, mat.NewDense(1, len(X), X), m.Kernel)
+    kVec := mat.NewVecDense(len(k), k)
+    var KInvK, mu, residual, dv, dmu mat.VecDense
+    KInvK.MulVec(KInv, kVec)
+    v := math.Max(self[0] - mat.Dot(kVec, &KInvK), 0.0) + m.NoiseVariance
+    mu.MulVec(Alpha.T(), kVec)
+    residual.SubVec(mat.NewVecDense(d, Y), &mu)
+    squared := mat.Dot(&residual, &residual)
+    value := 0.5 * float64(d) * math.Log(v) + 0.5 * squared / v + 0.5 * floats.Dot(X, X)
+
+    // the self kernel depends on x through both arguments
+    dv.MulVec(G.T(), &KInvK)
+    dv.AddScaledVec(mat.NewVecDense(len(X), selfGrad.RawRowView(0)), -1.0, &dv)
+    dv.ScaleVec(2.0, &dv)
+    var alphaResidual mat.VecDense
+    alphaResidual.MulVec(Alpha, &residual)
+    dmu.MulVec(G.T(), &alphaResidual)
+    grad := make([]float64, len(X))
+    for a := range grad {
+        grad[a] = (0.5 * float64(d) / v - 0.5 * squared / (v * v)) * dv.AtVec(a) - dmu.AtVec(a) / v + X[a]
+    }
+    return value, grad
+}
+
+
+/*
+SUMMARY
+    Projects new observations to the latent space. The latent point of each observation maximises its
+    posterior given the fitted Gaussian processes and the prior, found by Adam from the back constraint,
+    or from the latent point of the nearest training observation if the model is not back constrained.
+    The covariance is the inverse of the Hessian of the negative log posterior at the maximum
+    (Laplace approximation), the Hessian is computed by finite differences of the gradient.
+PARAMETERS
+    YStar *mat.Dense: M by D matrix, the observations
+RETURN
+    *mat.Dense: M by Q matrix, the latent means
+    []*mat.SymDense: the latent covariances
+    error: *FactorisationError if a covariance is not positive definite,
+        *optimisers.DivergenceError if Adam diverges
+*/
+func (m GPLVM) Project(YStar *mat.Dense) (*mat.Dense, []*mat.SymDense, error) {
+    mStar, d := YStar.Dims()
+    n, q := m.X.Dims()
+    chol, err := covariance(m.X, m.Kernel, m.NoiseVariance)
+    if err != nil { return nil, nil, err }
+    var KInv mat.SymDense
+    if err := chol.InverseTo(&KInv); err != nil { return nil, nil, err }
+    var alpha mat.Dense
+    if err := chol.SolveTo(&alpha, m.Y); err != nil { return nil, nil, err }
+    // the steps of Adam and the finite differences follow the extent of the latent points
+    scale := math.Sqrt(averageVariance(m.X))
+
+    var starts *mat.Dense
+    if m.BackWeights != nil {
+        if starts, err = m.Encode(YStar); err != nil { return nil, nil, err }
+    } else {
+        starts = mat.NewDense(mStar, q, nil)
+    }
+    means := mat.NewDense(mStar, q, nil)
+    covariances := make([]*mat.SymDense, mStar)
+    y := make([]float64, d)
+    for i:=0; i<mStar; i++ {
+        for j := range y { y[j] = YStar.At(i, j) - m.Mean[j] }
+        if m.BackWeights == nil {
+            nearest, distance := 0, math.Inf(1)
+            for j:=0; j<n; j++ {
+                if dist := floats.Distance(y, m.Y.RawRowView(j), 2); dist < distance { nearest, distance = j, dist }
+            }
+            starts.SetRow(i, m.X.RawRowView(nearest))
+        }
+        gradient := func (At []float64) []float64 {
+            _, grad := projectionObjective(At, y, m, &KInv, &alpha)
+            return grad
+        }
+        optimiser, err := optimisers.Adam(0.01 * scale, 0.9, 0.999, 1e-8, 1e-5 * scale)
+        if err != nil { return nil, nil, err }
+        At := append([]float64{}, starts.RawRowView(i)...)
+        for converged, steps := false, 0; !converged && steps < 1000; {
+            if At, converged, steps, err = optimiser(gradient, At); err != nil { return nil, nil, err }
+        }
+        means.SetRow(i, At)
+
+        h := 1e-4 * scale
+        hessian := mat.NewDense(q, q, nil)
+        for a:=0; a<q; a++ {
+            At[a] += h
+            plus := gradient(At)
+            At[a] -= 2.0 * h
+            minus := gradient(At)
+            At[a] += h
+            for b:=0; b<q; b++ { hessian.Set(a, b, (plus[b] - minus[b]) / (2.0 * h)) }
+        }
+        sym := mat.NewSymDense(q, nil)
+        for a:=0; a<q; a++ {
+            for b:=a; b<q; b++ { sym.SetSym(a, b, 0.5 * (hessian.At(a, b) + hessian.At(b, a))) }
+        }
+        var cholH mat.Cholesky
+        if ok := cholH.Factorize(sym); !ok { return nil, nil, &FactorisationError{Factorisation: "Cholesky"} }
+        covariances[i] = mat.NewSymDense(q, nil)
+        if err := cholH.InverseTo(covariances[i]); err != nil { return nil, nil, err }
+    }
+    return means, covariances, nil
+}
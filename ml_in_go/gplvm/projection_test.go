@@ -0,0 +1,97 @@
+package gplvm
+
+import (
+    "math"
+    "testing"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+
+    "ml_playground/kernels"
+)
+
+
+/*
+SUMMARY
+    Noisy observations of a curve embedded in a higher dimensional space.
+PARAMETERS
+    N int: the number of points
+    D int: the dimension of the observations
+    Seed int: seed of the random numbers
+RETURN
+    *mat.Dense: N by D matrix, each row is a point
+*/
+func curveData(N, D int, Seed int) *mat.Dense {
+    randGen := rand.New(rand.NewSource(uint64(Seed)))
+    W := randomMatrix(2, D, randGen)
+    Y := mat.NewDense(N, D, nil)
+    for i:=0; i<N; i++ {
+        t := 3.0 * math.Pi * float64(i) / float64(N)
+        var row mat.VecDense
+        row.MulVec(W.T(), mat.NewVecDense(2, []float64{t * math.Cos(t), t * math.Sin(t)}))
+        for j:=0; j<D; j++ { Y.Set(i, j, row.AtVec(j) + 0.05 * randGen.NormFloat64()) }
+    }
+    return Y
+}
+
+
+func TestProjectionObjectiveGradient(t *testing.T) {
+    randGen := rand.New(rand.NewSource(3))
+    m := GPLVM{X: randomMatrix(8, 2, randGen), Y: randomMatrix(8, 3, randGen), Kernel: kernels.Parameters{Type: kernels.RBF, VarSigma: 1.2, LengthScale: 1.5}, NoiseVariance: 0.2}
+    chol, err := covariance(m.X, m.Kernel, m.NoiseVariance)
+    if err != nil { t.Fatal(err) }
+    var KInv mat.SymDense
+    if err := chol.InverseTo(&KInv); err != nil { t.Fatal(err) }
+    var alpha mat.Dense
+    if err := chol.SolveTo(&alpha, m.Y); err != nil { t.Fatal(err) }
+    y := []float64{0.3, -0.7, 1.1}
+    x := []float64{0.4, -0.2}
+    _, grad := projectionObjective(x, y, m, &KInv, &alpha)
+    for a := range x {
+        numeric := centralDifference(func (v float64) float64 {
+            moved := append([]float64{}, x...)
+            moved[a] = v
+            value, _ := projectionObjective(moved, y, m, &KInv, &alpha)
+            return value
+        }, x[a])
+        checkDerivative(t, "dL/dx", grad[a], numeric)
+    }
+}
+
+
+func TestBackConstrainedLatentsAreEncodedData(t *testing.T) {
+    Y := curveData(30, 5, 4)
+    model, _, err := Fit(Y, Options{BackConstrained: true, Iterations: 100, StepSize: 0.03})
+    if err != nil { t.Fatal(err) }
+    encoded, err := model.Encode(Y)
+    if err != nil { t.Fatal(err) }
+    if !mat.EqualApprox(encoded, model.X, 1e-8) { t.Error("the latent points differ from the back constraint of the data") }
+
+    unconstrained := GPLVM{X: model.X, Y: model.Y, Mean: model.Mean}
+    if _, err := unconstrained.Encode(Y); err == nil { t.Error("Encode accepted a model without back constraint") }
+}
+
+
+func TestProjectRecoversTrainingLatents(t *testing.T) {
+    Y := curveData(30, 5, 5)
+    for _, backConstrained := range []bool{false, true} {
+        model, _, err := Fit(Y, Options{BackConstrained: backConstrained, Iterations: 300, StepSize: 0.03})
+        if err != nil { t.Fatal(err) }
+        means, covariances, err := model.Project(Y)
+        if err != nil { t.Fatal(err) }
+        n, _ := Y.Dims()
+        // the projections must be closer to their own latent point than half the gap to the nearest other one
+        for i:=0; i<n; i++ {
+            gap := math.Inf(1)
+            for j:=0; j<n; j++ {
+                if j != i { gap = math.Min(gap, floats.Distance(model.X.RawRowView(i), model.X.RawRowView(j), 2)) }
+            }
+            if distance := floats.Distance(means.RawRowView(i), model.X.RawRowView(i), 2); distance > 0.5 * gap {
+                t.Errorf("back constrained %t: point %d projected %g away from its latent point, the nearest other one is %g away",
+                         backConstrained, i, distance, gap)
+            }
+            if covariances[i].At(0, 0) <= 0 || covariances[i].At(1, 1) <= 0 { t.Errorf("point %d: covariance not positive", i) }
+        }
+    }
+}
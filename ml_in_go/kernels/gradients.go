@@ -0,0 +1,134 @@
+package kernels
+
+import (
+    "math"
+    "sync"
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+)
+
+
+/*
+SUMMARY
+    Given the derivative G = dL/dK of a scalar L with respect to the symmetric kernel matrix K = k(X,X),
+    computes the derivatives of L with respect to the points and to the kernel parameters by the chain
+    rule, dL/dx_i = 2 \sum_j G_ij dk(x_i,x_j)/dx_i, in O(N^2 Q) without forming dK/dx_i.
+    The rows are split between goroutines, each of them keeps its own derivatives of the kernel
+    parameters which are added up at the end.
+PARAMETERS
+    X *mat.Dense: N by Q matrix, each row is a point
+    G *mat.Dense: N by N symmetric matrix, dL/dK
+    Params Parameters: struct containing the type of kernel and respective kernel parameters
+    NumWorkers int: the number of goroutines (1 if 0)
+RETURN
+    *mat.Dense: N by Q matrix, dL/dX
+    Parameters: the derivatives of L with respect to VarSigma, LengthScale and Period in the
+        respective fields, the fields not used by the kernel are 0
+*/
+func KernelGradients(X, G *mat.Dense, Params Parameters, NumWorkers int) (*mat.Dense, Parameters) {
+    n, q := X.Dims()
+    gradX := mat.NewDense(n, q, nil)
+    workers := NumWorkers
+    if workers <= 0 { workers = 1 }
+    if workers > n { workers = n }
+    partial := make([]Parameters, workers)
+    contract := func (first, last int, grads *Parameters) {
+        for i:=first; i<last; i++ {
+            xi := X.RawRowView(i)
+            row := gradX.RawRowView(i)
+            for j:=0; j<n; j++ {
+                xj := X.RawRowView(j)
+                g := G.At(i, j)
+                switch Params.Type {
+                    case RBF:
+                        squared := floats.Distance(xi, xj, 2)
+                        squared *= squared
+                        k := Params.VarSigma * math.Exp(-squared / Params.LengthScale)
+                        // dk/dx_i = -2 k (x_i - x_j) / LengthScale
+                        scale := 2.0 * g * (-2.0 * k / Params.LengthScale)
+                        for a:=0; a<q; a++ { row[a] += scale * (xi[a] - xj[a]) }
+                        grads.VarSigma += g * k / Params.VarSigma
+                        grads.LengthScale += g * k * squared / (Params.LengthScale * Params.LengthScale)
+                    case LINEAR:
+                        floats.AddScaled(row, 2.0 * g * Params.VarSigma, xj)
+                        grads.VarSigma += g * floats.Dot(xi, xj)
+                    case PERIODIC:
+                        r := floats.Distance(xi, xj, 2)
+                        u := math.Pi * r / Params.Period
+                        sine := math.Sin(u)
+                        l2 := Params.LengthScale * Params.LengthScale
+                        k := Params.VarSigma * math.Exp(-2.0 * sine * sine / l2)
+                        // dk/dr = -2 k sin(2u) Pi / (Period LengthScale^2)
+                        dr := -2.0 * k * math.Sin(2.0 * u) * math.Pi / (Params.Period * l2)
+                        if r > 0 {
+                            scale := 2.0 * g * dr / r
+                            for a:=0; a<q; a++ { row[a] += scale * (xi[a] - xj[a]) }
+                        }
+                        grads.VarSigma += g * k / Params.VarSigma
+                        grads.LengthScale += g * k * 4.0 * sine * sine / (l2 * Params.LengthScale)
+                        grads.Period += g * dr * (-r / Params.Period)
+                }
+            }
+        }
+    }
+    var wg sync.WaitGroup
+    for w:=0; w<workers; w++ {
+        wg.Add(1)
+        go func(w int) {
+            defer wg.Done()
+            contract(w * n / workers, (w + 1) * n / workers, &partial[w])
+        }(w)
+    }
+    wg.Wait()
+    grads := Parameters{Type: Params.Type}
+    for _, p := range partial {
+        grads.VarSigma += p.VarSigma
+        grads.LengthScale += p.LengthScale
+        grads.Period += p.Period
+    }
+    return gradX, grads
+}
+
+
+/*
+SUMMARY
+    The kernel between one point and the rows of a matrix, k(x,x_i), and its derivatives with respect
+    to the point. Unlike Kernel, no jitter is added.
+PARAMETERS
+    x []float64: the point, Q long
+    X *mat.Dense: N by Q matrix, each row is a point
+    Params Parameters: struct containing the type of kernel and respective kernel parameters
+RETURN
+    []float64: k(x,x_i), N long
+    *mat.Dense: N by Q matrix, the i-th row is dk(x,x_i)/dx
+*/
+func PointGradients(x []float64, X *mat.Dense, Params Parameters) ([]float64, *mat.Dense) {
+    n, q := X.Dims()
+    values := make([]float64, n)
+    grads := mat.NewDense(n, q, nil)
+    for i:=0; i<n; i++ {
+        xi := X.RawRowView(i)
+        row := grads.RawRowView(i)
+        switch Params.Type {
+            case RBF:
+                squared := floats.Distance(x, xi, 2)
+                squared *= squared
+                values[i] = Params.VarSigma * math.Exp(-squared / Params.LengthScale)
+                for a:=0; a<q; a++ { row[a] = -2.0 * values[i] * (x[a] - xi[a]) / Params.LengthScale }
+            case LINEAR:
+                values[i] = Params.VarSigma * floats.Dot(x, xi)
+                floats.ScaleTo(row, Params.VarSigma, xi)
+            case PERIODIC:
+                r := floats.Distance(x, xi, 2)
+                u := math.Pi * r / Params.Period
+                sine := math.Sin(u)
+                l2 := Params.LengthScale * Params.LengthScale
+                values[i] = Params.VarSigma * math.Exp(-2.0 * sine * sine / l2)
+                if r > 0 {
+                    dr := -2.0 * values[i] * math.Sin(2.0 * u) * math.Pi / (Params.Period * l2)
+                    for a:=0; a<q; a++ { row[a] = dr * (x[a] - xi[a]) / r }
+                }
+        }
+    }
+    return values, grads
+}
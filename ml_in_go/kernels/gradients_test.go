@@ -0,0 +1,149 @@
+package kernels
+
+import (
+    "math"
+    "testing"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+)
+
+
+func TestKernelGradientsWorkers(t *testing.T) {
+    randGen := rand.New(rand.NewSource(1))
+    X := mat.NewDense(13, 3, nil)
+    X.Apply(func (i, j int, v float64) float64 { return randGen.NormFloat64() }, X)
+    G := mat.NewDense(13, 13, nil)
+    for i:=0; i<13; i++ {
+        for j:=i; j<13; j++ {
+            g := randGen.NormFloat64()
+            G.Set(i, j, g)
+            G.Set(j, i, g)
+        }
+    }
+    for _, params := range []Parameters{
+        {Type: RBF, VarSigma: 1.5, LengthScale: 2.0},
+        {Type: LINEAR, VarSigma: 0.7},
+        {Type: PERIODIC, VarSigma: 1.2, LengthScale: 0.8, Period: 3.0},
+    } {
+        serialX, serial := KernelGradients(X, G, params, 1)
+        for _, workers := range []int{0, 3, 4, 20} {
+            parallelX, parallel := KernelGradients(X, G, params, workers)
+            if !mat.EqualApprox(serialX, parallelX, 1e-12) {
+                t.Errorf("kernel %d, %d goroutines: dL/dX differs", params.Type, workers)
+            }
+            for _, pair := range [][2]float64{{serial.VarSigma, parallel.VarSigma}, {serial.LengthScale, parallel.LengthScale}, {serial.Period, parallel.Period}} {
+                if math.Abs(pair[0] - pair[1]) > 1e-10 * (1.0 + math.Abs(pair[0])) {
+                    t.Errorf("kernel %d, %d goroutines: parameter derivative %g instead of %g", params.Type, workers, pair[1], pair[0])
+                }
+            }
+        }
+    }
+}
+
+
+/*
+SUMMARY
+    The contraction L = sum_ij G_ij k(x_i,x_j) whose derivatives KernelGradients computes.
+PARAMETERS
+    X *mat.Dense: N by Q matrix, each row is a point
+    G *mat.Dense: N by N matrix, dL/dK
+    Params Parameters: the kernel
+RETURN
+    float64: L
+*/
+func contraction(X, G *mat.Dense, Params Parameters) float64 {
+    var product mat.Dense
+    product.MulElem(Kernel(X, X, Params), G)
+    return mat.Sum(&product)
+}
+
+
+/*
+SUMMARY
+    The derivative of a function of one variable by central differences.
+PARAMETERS
+    F func(float64) float64: the function
+    At float64: the point
+RETURN
+    float64: the derivative
+*/
+func centralDifference(F func(float64) float64, At float64) float64 {
+    h := 1e-6 * math.Max(1.0, math.Abs(At))
+    return (F(At + h) - F(At - h)) / (2.0 * h)
+}
+
+
+func TestKernelGradientsFiniteDifferences(t *testing.T) {
+    randGen := rand.New(rand.NewSource(2))
+    X := mat.NewDense(6, 2, nil)
+    X.Apply(func (i, j int, v float64) float64 { return randGen.NormFloat64() }, X)
+    G := mat.NewDense(6, 6, nil)
+    for i:=0; i<6; i++ {
+        for j:=i; j<6; j++ {
+            g := randGen.NormFloat64()
+            G.Set(i, j, g)
+            G.Set(j, i, g)
+        }
+    }
+    check := func (Name string, Analytic, Numeric float64) {
+        if math.Abs(Analytic - Numeric) > 1e-6 * (1.0 + math.Abs(Numeric)) {
+            t.Errorf("%s: analytic %g, finite difference %g", Name, Analytic, Numeric)
+        }
+    }
+    for _, params := range []Parameters{
+        {Type: RBF, VarSigma: 1.5, LengthScale: 2.0},
+        {Type: LINEAR, VarSigma: 0.7},
+        {Type: PERIODIC, VarSigma: 1.2, LengthScale: 0.8, Period: 3.0},
+    } {
+        gradX, grads := KernelGradients(X, G, params, 1)
+        for i:=0; i<6; i++ {
+            for a:=0; a<2; a++ {
+                numeric := centralDifference(func (x float64) float64 {
+                    moved := mat.DenseCopyOf(X)
+                    moved.Set(i, a, x)
+                    return contraction(moved, G, params)
+                }, X.At(i, a))
+                check("dL/dX", gradX.At(i, a), numeric)
+            }
+        }
+        check("dL/dVarSigma", grads.VarSigma, centralDifference(func (s float64) float64 {
+            moved := params
+            moved.VarSigma = s
+            return contraction(X, G, moved)
+        }, params.VarSigma))
+        if params.Type != LINEAR {
+            check("dL/dLengthScale", grads.LengthScale, centralDifference(func (l float64) float64 {
+                moved := params
+                moved.LengthScale = l
+                return contraction(X, G, moved)
+            }, params.LengthScale))
+        }
+        if params.Type == PERIODIC {
+            check("dL/dPeriod", grads.Period, centralDifference(func (p float64) float64 {
+                moved := params
+                moved.Period = p
+                return contraction(X, G, moved)
+            }, params.Period))
+        }
+
+        x := []float64{0.3, -0.4}
+        values, pointGrads := PointGradients(x, X, params)
+        K := Kernel(mat.NewDense(1, 2, x), X, params)
+        for i:=0; i<6; i++ {
+            // Kernel adds the jitter to the first entry only
+            jitter := 0.0
+            if i == 0 { jitter = 1e-4 }
+            check("k(x,x_i)", values[i], K.At(0, i) - jitter)
+            for a:=0; a<2; a++ {
+                numeric := centralDifference(func (v float64) float64 {
+                    moved := append([]float64{}, x...)
+                    moved[a] = v
+                    values, _ := PointGradients(moved, X, params)
+                    return values[i]
+                }, x[a])
+                check("dk(x,x_i)/dx", pointGrads.At(i, a), numeric)
+            }
+        }
+    }
+}
@@ -0,0 +1,155 @@
+package kmeans
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+)
+
+
+/*
+SUMMARY
+    Runs Elkan's iterations (Elkan, Using the Triangle Inequality to Accelerate k-Means) from the given
+    centres. Every point keeps an upper bound on the distance from its own centre and a lower bound on
+    the distance from each of the other centres; by the triangle inequality most distances need not be
+    computed. The centres are the same as the ones of Lloyd's iterations, but it stores #points * #centres
+    bounds. A cluster that loses every point is re-seeded with the point farthest from its own centre.
+PARAMETERS
+    points *mat.Dense: the points, each column is a point
+    centres [][]float64: the initial centres, updated in place
+    tolerance float64: stop when no centre moves more than this
+    maxIterations int: the maximal number of iterations
+    numWorkers int: the number of goroutines
+RETURN
+    KMeansResult: the outcome of the run
+*/
+func elkan(points *mat.Dense, centres [][]float64, tolerance float64, maxIterations, numWorkers int) KMeansResult {
+    p := newPointSet(points)
+    numClasses := len(centres)
+    result := KMeansResult{Centres: centres, Labels: make([]int, p.N)}
+    upper := make([]float64, p.N)
+    lower := make([]float64, p.N * numClasses)
+    parallelFor(p.N, numWorkers, func (_, first, last int) {
+        for i:=first; i<last; i++ {
+            bounds := lower[i*numClasses : (i+1)*numClasses]
+            for k, centre := range centres {
+                bounds[k] = math.Sqrt(p.squaredDistance(i, centre))
+            }
+            result.Labels[i] = floats.MinIdx(bounds)
+            upper[i] = bounds[result.Labels[i]]
+        }
+    })
+
+    centreDistances := make([][]float64, numClasses)
+    for k := range centreDistances { centreDistances[k] = make([]float64, numClasses) }
+    separation := make([]float64, numClasses)
+    shifts := make([]float64, numClasses)
+    for result.Iterations < maxIterations {
+        result.Iterations++
+        if result.Iterations > 1 {
+            // half the distance to the closest other centre, a point within it cannot change cluster
+            for k := range centres {
+                separation[k] = math.Inf(1)
+                for j := range centres {
+                    if j < k { centreDistances[k][j] = centreDistances[j][k] }
+                    if j > k { centreDistances[k][j] = floats.Distance(centres[k], centres[j], 2) }
+                    if j != k { separation[k] = math.Min(separation[k], 0.5 * centreDistances[k][j]) }
+                }
+            }
+            parallelFor(p.N, numWorkers, func (_, first, last int) {
+                for i:=first; i<last; i++ {
+                    label := result.Labels[i]
+                    if upper[i] <= separation[label] { continue }
+                    bounds := lower[i*numClasses : (i+1)*numClasses]
+                    tight := false
+                    for k := range centres {
+                        if k == label || upper[i] <= bounds[k] || upper[i] <= 0.5 * centreDistances[label][k] {
+                            continue
+                        }
+                        if !tight {
+                            upper[i] = math.Sqrt(p.squaredDistance(i, centres[label]))
+                            bounds[label] = upper[i]
+                            tight = true
+                            if upper[i] <= bounds[k] || upper[i] <= 0.5 * centreDistances[label][k] { continue }
+                        }
+                        bounds[k] = math.Sqrt(p.squaredDistance(i, centres[k]))
+                        if bounds[k] < upper[i] {
+                            label, upper[i] = k, bounds[k]
+                        }
+                    }
+                    result.Labels[i] = label
+                }
+            })
+        }
+
+        sums, counts := clusterSums(p, result.Labels, numClasses, numWorkers)
+        reseeded := reseedEmptyClusters(p, centres, result.Labels, sums, counts)
+        shift := 0.0
+        for k := range centres {
+            floats.Scale(1.0 / float64(counts[k]), sums[k])
+            shifts[k] = floats.Distance(sums[k], centres[k], 2)
+            shift = math.Max(shift, shifts[k])
+            copy(centres[k], sums[k])
+        }
+        parallelFor(p.N, numWorkers, func (_, first, last int) {
+            for i:=first; i<last; i++ {
+                upper[i] += shifts[result.Labels[i]]
+                bounds := lower[i*numClasses : (i+1)*numClasses]
+                for k := range bounds {
+                    bounds[k] = math.Max(bounds[k] - shifts[k], 0.0)
+                }
+            }
+        })
+        // a re-seeded point is the centre of its new cluster
+        for _, far := range reseeded {
+            upper[far] = 0.0
+            lower[far*numClasses + result.Labels[far]] = 0.0
+        }
+        if shift <= tolerance {
+            result.Converged = true
+            break
+        }
+    }
+    _, _, result.Inertia = assign(p, centres, result.Labels, numWorkers)
+    return result
+}
+
+
+/*
+SUMMARY
+    Sums the points of each cluster on several goroutines.
+PARAMETERS
+    p pointSet: the points
+    labels []int: the label of each point
+    numClasses int: the number of clusters
+    numWorkers int: the number of goroutines
+RETURN
+    [][]float64: the sum of the points of each cluster
+    []int: the size of each cluster
+*/
+func clusterSums(p pointSet, labels []int, numClasses, numWorkers int) ([][]float64, []int) {
+    partialSums := make([][][]float64, maxWorkers(p.N, numWorkers))
+    partialCounts := make([][]int, len(partialSums))
+    parallelFor(p.N, numWorkers, func (worker, first, last int) {
+        sums := make([][]float64, numClasses)
+        for k := range sums { sums[k] = make([]float64, p.Dims) }
+        counts := make([]int, numClasses)
+        for i:=first; i<last; i++ {
+            counts[labels[i]]++
+            for d := range sums[labels[i]] { sums[labels[i]][d] += p.at(i, d) }
+        }
+        partialSums[worker], partialCounts[worker] = sums, counts
+    })
+    sums := make([][]float64, numClasses)
+    for k := range sums { sums[k] = make([]float64, p.Dims) }
+    counts := make([]int, numClasses)
+    for w := range partialSums {
+        if partialSums[w] == nil { continue }
+        for k := range sums {
+            floats.Add(sums[k], partialSums[w][k])
+            counts[k] += partialCounts[w][k]
+        }
+    }
+    return sums, counts
+}
@@ -1,8 +1,12 @@
-package main
-
+/*
+This library contains the KMeans clustering algorithm together with
+a few helpers for image segmentation and plotting.
+*/
+package kmeans
 
 import (
-    "fmt"
+    "math"
+    "runtime"
     "image/color"
 
     "gonum.org/v1/gonum/mat"
@@ -72,91 +76,314 @@ RETURN
     []int: the labels for each point
 */
 func LabelPairwiseDistances(points *mat.Dense, centres [][]float64) []int {
-    dims, n := points.Dims()
-    var labels []int = make([]int, n)
-    var distances []float64 = make([]float64, len(centres))
-    var distance_idx []int = make([]int, len(centres))
-    var point []float64 = make([]float64, dims)
-    for i:=0; i<n; i++ {
-        point = mat.Col(nil, i, points)
-        for j, centre := range centres {
-            distances[j] = floats.Distance(centre, point, 2)
-        }
-        floats.Argsort(distances, distance_idx)
-        labels[i] = distance_idx[0]
+    p := newPointSet(points)
+    labels := make([]int, p.N)
+    for i := range labels {
+        labels[i], _ = p.nearest(i, centres)
     }
     return labels
 }
 
 
+// types of centre initialisation
+const PLUS_PLUS_INIT = 0
+const RANDOM_INIT = 1
+
+// types of iterations
+const LLOYD_ALGORITHM = 0
+const ELKAN_ALGORITHM = 1
+
+
+/*
+This type configures KMeans.
+    NumClasses int: the number of clusters we would like to find
+    Init int: PLUS_PLUS_INIT (k-means++ seeding) or RANDOM_INIT (uniformly chosen points)
+    Restarts int: the number of independent runs, the one with the lowest inertia is kept (1 if 0)
+    Tolerance float64: a run stops when no centre moves more than this
+    MaxIterations int: a run stops after this many iterations (300 if 0)
+    Algorithm int: LLOYD_ALGORITHM or ELKAN_ALGORITHM (same result, fewer distance computations)
+    NumWorkers int: the number of goroutines computing the assignments (1 if 0)
+    Seed int: seed of the random number generator
+*/
+type KMeansOptions struct {
+    NumClasses int
+    Init int
+    Restarts int
+    Tolerance float64
+    MaxIterations int
+    Algorithm int
+    NumWorkers int
+    Seed int
+}
+
+
+/*
+This type stores the outcome of KMeans.
+    Labels []int: the label of each point
+    Centres [][]float64: the centres of the clusters
+    Inertia float64: the sum of squared distances of the points from their centres
+    Iterations int: the number of iterations of the kept run
+    Converged bool: whether the kept run stopped because of the tolerance
+*/
+type KMeansResult struct {
+    Labels []int
+    Centres [][]float64
+    Inertia float64
+    Iterations int
+    Converged bool
+}
+
+
 /*
 SUMMARY
-    Implements the KMeans unsupervised algorithm.
+    Chooses the initial centres with k-means++ (Arthur & Vassilvitskii, k-means++: The Advantages of
+    Careful Seeding): each new centre is a point drawn with probability proportional to its squared
+    distance from the closest centre chosen so far.
 PARAMETERS
-    points *mat.Dense: the points we would like to find a cluster
-    numClasses int: the number of clusters we would like to find
+    points *mat.Dense: the points, each column is a point
+    numClasses int: the number of centres
+    randGen *rand.Rand: the random number generator
 RETURN
-    []int: the labels for each point
-    [][]float64: the centres KMeans converged into
+    [][]float64: the centres
 */
-func KMeansClassify(points *mat.Dense, numClasses int) ([]int, [][]float64) {
-    dims, n := points.Dims()
-    uniform := distuv.Uniform{0, float64(n), rand.NewSource(uint64(69))}
-    var centres, oldCentres [][]float64
-    for i:=0; i<numClasses; i++ {
-        centres = append(centres, mat.Col(nil, int(uniform.Rand()), points))
-        oldCentres = append(oldCentres, make([]float64, dims))
-    }
-    labels := make([]int, n)
-    steps := 0
-    for !Equal2dSlice(centres, oldCentres) {
-        steps++
-        fmt.Println(steps)
-        for idxToCopy := range centres {
-            copy(oldCentres[idxToCopy], centres[idxToCopy])
+func PlusPlusCentres(points *mat.Dense, numClasses int, randGen *rand.Rand) [][]float64 {
+    p := newPointSet(points)
+    n := p.N
+    centres := [][]float64{p.copyPoint(randGen.Intn(n))}
+    closest := make([]float64, n)
+    for i := range closest { closest[i] = math.Inf(1) }
+    for len(centres) < numClasses {
+        newest := centres[len(centres)-1]
+        total := 0.0
+        for i:=0; i<n; i++ {
+            closest[i] = math.Min(closest[i], p.squaredDistance(i, newest))
+            total += closest[i]
         }
-        labels = LabelPairwiseDistances(points, centres)
-        for i:=0; i<numClasses; i++ {
-            classSize := 0
-            for j:=0; j<n; j++ {
-                if labels[j] == i {
-                    floats.Add(centres[i], mat.Col(nil, j, points))
-                    classSize++
+        chosen := randGen.Intn(n)
+        if total > 0 {
+            t := randGen.Float64() * total
+            for i:=0; i<n; i++ {
+                t -= closest[i]
+                if t < 0 {
+                    chosen = i
+                    break
                 }
             }
-            if classSize > 0 {
-                for k, c := range centres[i] {
-                    centres[i][k] = c / float64(classSize)
-                }
+        }
+        centres = append(centres, p.copyPoint(chosen))
+    }
+    return centres
+}
+
+
+/*
+SUMMARY
+    Chooses distinct points uniformly at random as initial centres.
+PARAMETERS
+    points *mat.Dense: the points, each column is a point
+    numClasses int: the number of centres
+    randGen *rand.Rand: the random number generator
+RETURN
+    [][]float64: the centres
+*/
+func RandomCentres(points *mat.Dense, numClasses int, randGen *rand.Rand) [][]float64 {
+    p := newPointSet(points)
+    var centres [][]float64
+    for _, i := range randGen.Perm(p.N)[:numClasses] {
+        centres = append(centres, p.copyPoint(i))
+    }
+    return centres
+}
+
+
+/*
+SUMMARY
+    Runs Lloyd's iterations from the given centres. A cluster that loses every point is re-seeded
+    with the point farthest from its own centre. The assignment step runs on several goroutines.
+PARAMETERS
+    points *mat.Dense: the points, each column is a point
+    centres [][]float64: the initial centres, updated in place
+    tolerance float64: stop when no centre moves more than this
+    maxIterations int: the maximal number of iterations
+    numWorkers int: the number of goroutines
+RETURN
+    KMeansResult: the outcome of the run
+*/
+func lloyd(points *mat.Dense, centres [][]float64, tolerance float64, maxIterations, numWorkers int) KMeansResult {
+    p := newPointSet(points)
+    result := KMeansResult{Centres: centres, Labels: make([]int, p.N)}
+    for result.Iterations < maxIterations {
+        result.Iterations++
+        sums, counts, _ := assign(p, centres, result.Labels, numWorkers)
+        reseedEmptyClusters(p, centres, result.Labels, sums, counts)
+        shift := 0.0
+        for k := range centres {
+            floats.Scale(1.0 / float64(counts[k]), sums[k])
+            shift = math.Max(shift, floats.Distance(sums[k], centres[k], 2))
+            copy(centres[k], sums[k])
+        }
+        if shift <= tolerance {
+            result.Converged = true
+            break
+        }
+    }
+    _, _, result.Inertia = assign(p, centres, result.Labels, numWorkers)
+    return result
+}
+
+
+/*
+SUMMARY
+    Re-seeds every empty cluster with the point farthest from its centre. The point leaves its old
+    cluster, whose sum and count lose the point, so the old centre is averaged over its remaining
+    points. Only points of clusters with at least 2 points are chosen, hence no cluster becomes
+    empty and no point is chosen twice.
+PARAMETERS
+    p pointSet: the points
+    centres [][]float64: the centres
+    labels []int: the label of each point, the re-seeded points are relabelled
+    sums [][]float64: the sum of the points of each cluster, updated in place
+    counts []int: the number of points in each cluster, updated in place
+RETURN
+    []int: the re-seeded points
+*/
+func reseedEmptyClusters(p pointSet, centres [][]float64, labels []int, sums [][]float64, counts []int) []int {
+    var reseeded []int
+    for k := range centres {
+        if counts[k] > 0 { continue }
+        far, farDistance := -1, -1.0
+        for i, label := range labels {
+            if counts[label] < 2 { continue }
+            if d := p.squaredDistance(i, centres[label]); d > farDistance {
+                far, farDistance = i, d
             }
         }
+        old := labels[far]
+        point := p.copyPoint(far)
+        floats.Sub(sums[old], point)
+        counts[old]--
+        sums[k], counts[k] = point, 1
+        labels[far] = k
+        reseeded = append(reseeded, far)
     }
-    return labels, centres
+    return reseeded
 }
 
 
 /*
 SUMMARY
-    Applies KMeans for image segmentation. Note that this algorithm is quite slow.
+    The sum of squared distances of the points from their centres.
 PARAMETERS
-    img pic.RGBImg: the input image we would like to segment;
-        the output is saved into this variable
-    numClasses int: the number of colours after segmentation
+    points *mat.Dense: the points, each column is a point
+    centres [][]float64: the centres
+    labels []int: the label of each point
 RETURN
-    N/A
+    float64: the inertia
 */
-func SegmentImage(img pic.RGBImg, numClasses int) {
-    height, width := img[0].Dims()
-    r_flat := utils.Flatten(&img[0], true)
-    g_flat := utils.Flatten(&img[1], true)
-    b_flat := utils.Flatten(&img[2], true)
+func Inertia(points *mat.Dense, centres [][]float64, labels []int) float64 {
+    p := newPointSet(points)
+    inertia := 0.0
+    for i, label := range labels {
+        inertia += p.squaredDistance(i, centres[label])
+    }
+    return inertia
+}
 
+
+/*
+SUMMARY
+    Implements the KMeans unsupervised algorithm with k-means++ or random seeding, restarts,
+    and Lloyd's or Elkan's iterations.
+PARAMETERS
+    points *mat.Dense: the points we would like to find a cluster, each column is a point
+    Options KMeansOptions: the settings
+RETURN
+    KMeansResult: the run with the lowest inertia
+*/
+func KMeans(points *mat.Dense, Options KMeansOptions) KMeansResult {
+    _, n := points.Dims()
+    if Options.NumClasses <= 0 || Options.NumClasses > n { panic("Number of classes outside [1, #points] encountered") }
+    restarts := Options.Restarts
+    if restarts <= 0 { restarts = 1 }
+    maxIterations := Options.MaxIterations
+    if maxIterations <= 0 { maxIterations = 300 }
+    randGen := rand.New(rand.NewSource(uint64(Options.Seed)))
+    var best KMeansResult
+    for run:=0; run<restarts; run++ {
+        var centres [][]float64
+        switch Options.Init {
+            case PLUS_PLUS_INIT:
+                centres = PlusPlusCentres(points, Options.NumClasses, randGen)
+            case RANDOM_INIT:
+                centres = RandomCentres(points, Options.NumClasses, randGen)
+            default:
+                panic("Unknown initialisation encountered")
+        }
+        var result KMeansResult
+        switch Options.Algorithm {
+            case LLOYD_ALGORITHM:
+                result = lloyd(points, centres, Options.Tolerance, maxIterations, Options.NumWorkers)
+            case ELKAN_ALGORITHM:
+                result = elkan(points, centres, Options.Tolerance, maxIterations, Options.NumWorkers)
+            default:
+                panic("Unknown algorithm encountered")
+        }
+        if run == 0 || result.Inertia < best.Inertia {
+            best = result
+        }
+    }
+    return best
+}
+
+
+/*
+SUMMARY
+    Implements the KMeans unsupervised algorithm with the default settings:
+    k-means++ seeding, a single run, tolerance 1e-6 and at most 300 iterations.
+PARAMETERS
+    points *mat.Dense: the points we would like to find a cluster, each column is a point
+    numClasses int: the number of clusters we would like to find
+    seed int: seed of the k-means++ seeding
+RETURN
+    []int: the labels for each point
+    [][]float64: the centres KMeans converged into
+*/
+func KMeansClassify(points *mat.Dense, numClasses, seed int) ([]int, [][]float64) {
+    result := KMeans(points, KMeansOptions{NumClasses: numClasses, Tolerance: 1e-6, Seed: seed})
+    return result.Labels, result.Centres
+}
+
+
+/*
+SUMMARY
+    Puts the pixels of an image into the columns of a 3 by #pixels matrix.
+PARAMETERS
+    img pic.RGBImg: the image
+RETURN
+    *mat.Dense: the pixels as points
+*/
+func imagePoints(img pic.RGBImg) *mat.Dense {
+    height, width := img[0].Dims()
     points := mat.NewDense(3, width*height, nil)
-    points.SetRow(0, r_flat)
-    points.SetRow(1, g_flat)
-    points.SetRow(2, b_flat)
-    labels, centres := KMeansClassify(points, numClasses)
+    for c := 0; c < 3; c++ {
+        points.SetRow(c, utils.Flatten(&img[c], true))
+    }
+    return points
+}
 
+
+/*
+SUMMARY
+    Paints every pixel with the colour of its centre.
+PARAMETERS
+    img pic.RGBImg: the image, the output is saved into this variable
+    labels []int: the label of each pixel
+    centres [][]float64: the colours of the clusters
+RETURN
+    N/A
+*/
+func paintSegments(img pic.RGBImg, labels []int, centres [][]float64) {
+    _, width := img[0].Dims()
     for i, label := range labels {
         img[0].Set(i/width, i%width, centres[label][0])
         img[1].Set(i/width, i%width, centres[label][1])
@@ -165,6 +392,47 @@ func SegmentImage(img pic.RGBImg, numClasses int) {
 }
 
 
+/*
+SUMMARY
+    Applies KMeans for image segmentation. It uses k-means++ seeding and Elkan's iterations with
+    one goroutine per CPU. For very large images SegmentImageMiniBatch is faster and uses less memory.
+PARAMETERS
+    img pic.RGBImg: the input image we would like to segment;
+        the output is saved into this variable
+    numClasses int: the number of colours after segmentation
+    seed int: seed of the k-means++ seeding
+RETURN
+    N/A
+*/
+func SegmentImage(img pic.RGBImg, numClasses, seed int) {
+    result := KMeans(imagePoints(img), KMeansOptions{
+        NumClasses: numClasses,
+        Tolerance: 1e-6,
+        Algorithm: ELKAN_ALGORITHM,
+        NumWorkers: runtime.NumCPU(),
+        Seed: seed,
+    })
+    paintSegments(img, result.Labels, result.Centres)
+}
+
+
+/*
+SUMMARY
+    Applies mini-batch KMeans for image segmentation, suited to images of many megapixels.
+PARAMETERS
+    img pic.RGBImg: the input image we would like to segment;
+        the output is saved into this variable
+    Options MiniBatchOptions: the settings of MiniBatchKMeans
+RETURN
+    KMeansResult: the outcome of the clustering
+*/
+func SegmentImageMiniBatch(img pic.RGBImg, Options MiniBatchOptions) KMeansResult {
+    result := MiniBatchKMeans(imagePoints(img), Options)
+    paintSegments(img, result.Labels, result.Centres)
+    return result
+}
+
+
 /*
 SUMMARY
     Computes the maximum in an integer slice.
@@ -205,23 +473,3 @@ func KMeansPlot(xs, ys []float64, cs []int) *plot.Plot {
     p.Add(scatter)
     return p
 }
-
-
-/*
-We create random points, apply KMeans and visualise it.
-We also segment an image with KMeans and save the result.
-*/
-func main() {
-    points := CreateRandomPoints(300, 2, 6, 0, 255)
-    cs, _ := KMeansClassify(points, 10)
-    xs := mat.Row(nil, 0, points)
-    ys := mat.Row(nil, 1, points)
-    p := KMeansPlot(xs, ys, cs)
-    p.Title.Text, p.X.Label.Text, p.Y.Label.Text = "Kmeans Scatter Plot", "x", "Y"
-    p.Save(300, 200, "kmeans.svg")
-
-    var img pic.RGBImg = make([]mat.Dense, 3)
-    img.LoadPixels("image.jpg")
-    SegmentImage(img, 10)
-    img.SaveImage("image_segmented.jpg")
-}
@@ -0,0 +1,109 @@
+package main
+
+import (
+    "fmt"
+    "math"
+    "runtime"
+    "time"
+
+    "gonum.org/v1/gonum/mat"
+
+    "ml_playground/kmeans"
+    "ml_playground/pic"
+)
+
+
+/*
+SUMMARY
+    Enlarges an image by repeating every pixel Factor by Factor times.
+PARAMETERS
+    img pic.RGBImg: the input image
+    Factor int: the enlargement
+RETURN
+    pic.RGBImg: the enlarged image
+*/
+func Upscale(img pic.RGBImg, Factor int) pic.RGBImg {
+    height, width := img[0].Dims()
+    var large pic.RGBImg = make([]mat.Dense, 3)
+    for c := range large {
+        large[c] = *mat.NewDense(height*Factor, width*Factor, nil)
+        for j:=0; j<height*Factor; j++ {
+            for i:=0; i<width*Factor; i++ {
+                large[c].Set(j, i, img[c].At(j/Factor, i/Factor))
+            }
+        }
+    }
+    return large
+}
+
+
+/*
+SUMMARY
+    Times Lloyd's and Elkan's iterations on the pixels of an image and checks that they agree,
+    then segments a 12 megapixel enlargement of the image with mini-batch KMeans.
+PARAMETERS
+    img pic.RGBImg: the image
+RETURN
+    N/A
+*/
+func CompareAlgorithms(img pic.RGBImg) {
+    height, width := img[0].Dims()
+    points := mat.NewDense(3, height*width, nil)
+    for c := 0; c < 3; c++ {
+        for j:=0; j<height; j++ {
+            for i:=0; i<width; i++ { points.Set(c, j*width + i, img[c].At(j, i)) }
+        }
+    }
+    var results []kmeans.KMeansResult
+    for _, run := range []struct{ name string; algorithm, workers int }{
+        {name: "Lloyd, 1 goroutine", algorithm: kmeans.LLOYD_ALGORITHM, workers: 1},
+        {name: "Elkan, 1 goroutine", algorithm: kmeans.ELKAN_ALGORITHM, workers: 1},
+        {name: fmt.Sprintf("Elkan, %d goroutines", runtime.NumCPU()), algorithm: kmeans.ELKAN_ALGORITHM, workers: runtime.NumCPU()},
+    } {
+        start := time.Now()
+        result := kmeans.KMeans(points, kmeans.KMeansOptions{NumClasses: 10, Tolerance: 1e-6, Algorithm: run.algorithm, NumWorkers: run.workers, Seed: 69})
+        fmt.Printf("%-24s %v, inertia %.1f after %d iterations\n", run.name, time.Since(start), result.Inertia, result.Iterations)
+        results = append(results, result)
+    }
+    for _, result := range results[1:] {
+        if math.Abs(result.Inertia - results[0].Inertia) > 1e-6 * results[0].Inertia {
+            panic("Elkan's iterations disagree with Lloyd's iterations")
+        }
+    }
+
+    large := Upscale(img, int(math.Ceil(math.Sqrt(12e6 / float64(height*width)))))
+    largeHeight, largeWidth := large[0].Dims()
+    start := time.Now()
+    result := kmeans.SegmentImageMiniBatch(large, kmeans.MiniBatchOptions{NumClasses: 10, BatchSize: 2048, Iterations: 300, NumWorkers: runtime.NumCPU(), Seed: 69})
+    fmt.Printf("mini-batch on %dx%d pixels %v, inertia per pixel %.2f (full batch on the original %.2f)\n",
+        largeWidth, largeHeight, time.Since(start), result.Inertia / float64(largeHeight*largeWidth), results[0].Inertia / float64(height*width))
+}
+
+
+/*
+We create random points, apply KMeans with different initialisations and visualise it.
+We also compare the scalable variants on an image, segment it with KMeans and save the result.
+*/
+func main() {
+    points := kmeans.CreateRandomPoints(300, 2, 6, 0, 255)
+    for _, run := range []struct{ name string; options kmeans.KMeansOptions }{
+        {name: "random seeding", options: kmeans.KMeansOptions{NumClasses: 10, Init: kmeans.RANDOM_INIT, Tolerance: 1e-6, Seed: 69}},
+        {name: "k-means++", options: kmeans.KMeansOptions{NumClasses: 10, Init: kmeans.PLUS_PLUS_INIT, Tolerance: 1e-6, Seed: 69}},
+        {name: "k-means++, 10 restarts", options: kmeans.KMeansOptions{NumClasses: 10, Init: kmeans.PLUS_PLUS_INIT, Restarts: 10, Tolerance: 1e-6, Seed: 69}},
+    } {
+        result := kmeans.KMeans(points, run.options)
+        fmt.Printf("%-24s inertia %.1f after %d iterations, converged %t\n", run.name, result.Inertia, result.Iterations, result.Converged)
+    }
+    cs, _ := kmeans.KMeansClassify(points, 10, 69)
+    xs := mat.Row(nil, 0, points)
+    ys := mat.Row(nil, 1, points)
+    p := kmeans.KMeansPlot(xs, ys, cs)
+    p.Title.Text, p.X.Label.Text, p.Y.Label.Text = "Kmeans Scatter Plot", "x", "Y"
+    p.Save(300, 200, "kmeans.svg")
+
+    var img pic.RGBImg = make([]mat.Dense, 3)
+    img.LoadPixels("image.jpg")
+    CompareAlgorithms(img)
+    kmeans.SegmentImage(img, 10, 69)
+    img.SaveImage("image_segmented.jpg")
+}
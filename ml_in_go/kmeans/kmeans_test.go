@@ -0,0 +1,54 @@
+package kmeans
+
+import (
+    "testing"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+)
+
+
+func TestReseedEmptyClusters(t *testing.T) {
+    // one dimensional points, each column is a point
+    points := mat.NewDense(1, 5, []float64{0, 1, 2, 10, 40})
+    p := newPointSet(points)
+    // the lonely point 40 is the farthest from its centre, but it must not leave its cluster
+    centres := [][]float64{{1}, {0}, {100}, {200}}
+    labels := []int{0, 0, 0, 1, 1}
+    sums := [][]float64{{3}, {50}, {0}, {0}}
+    counts := []int{3, 2, 0, 0}
+    reseeded := reseedEmptyClusters(p, centres, labels, sums, counts)
+    if len(reseeded) != 2 { t.Fatalf("%d points re-seeded instead of 2", len(reseeded)) }
+    seen := map[int]bool{}
+    for _, i := range reseeded {
+        if seen[i] { t.Errorf("point %d re-seeded twice", i) }
+        seen[i] = true
+    }
+    // the sums and counts must be those of the new labels
+    expectedSums, expectedCounts := make([][]float64, 4), make([]int, 4)
+    for k := range expectedSums { expectedSums[k] = []float64{0} }
+    for i, label := range labels {
+        expectedSums[label][0] += points.At(0, i)
+        expectedCounts[label]++
+    }
+    for k := range centres {
+        if counts[k] != expectedCounts[k] || !floats.EqualApprox(sums[k], expectedSums[k], 1e-12) {
+            t.Errorf("cluster %d: sum %v and count %d instead of %v and %d", k, sums[k], counts[k], expectedSums[k], expectedCounts[k])
+        }
+        if counts[k] == 0 { t.Errorf("cluster %d is empty", k) }
+    }
+}
+
+
+func TestLloydReseededCentresDiffer(t *testing.T) {
+    points := mat.NewDense(1, 4, []float64{0, 1, 2, 10})
+    // the third centre is far from every point, so its cluster is empty after the first assignment
+    centres := [][]float64{{1}, {10}, {1000}}
+    result := lloyd(points, centres, 1e-9, 100, 1)
+    for a := range centres {
+        for b:=a+1; b<len(centres); b++ {
+            if centres[a][0] == centres[b][0] { t.Errorf("centres %d and %d are both %g", a, b, centres[a][0]) }
+        }
+    }
+    if result.Inertia > 0.5 + 1e-9 { t.Errorf("inertia %g, expected 0.5", result.Inertia) }
+}
@@ -0,0 +1,95 @@
+package kmeans
+
+import (
+    "math"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+)
+
+
+/*
+This type configures MiniBatchKMeans.
+    NumClasses int: the number of clusters we would like to find
+    BatchSize int: the number of points drawn in each iteration (1024 if 0)
+    Iterations int: the maximal number of mini-batches (100 if 0)
+    Tolerance float64: stop when no centre moves more than this during a mini-batch
+    NumWorkers int: the number of goroutines computing the assignments (1 if 0)
+    Seed int: seed of the random number generator
+*/
+type MiniBatchOptions struct {
+    NumClasses int
+    BatchSize int
+    Iterations int
+    Tolerance float64
+    NumWorkers int
+    Seed int
+}
+
+
+/*
+SUMMARY
+    Implements mini-batch KMeans (Sculley, Web-Scale K-Means Clustering). The centres are seeded with
+    k-means++ on a random subset of the points, then every iteration draws a mini-batch, assigns it to
+    the closest centres and moves each centre towards its points with the learning rate
+    1/(number of points the centre has seen). Finally every point is labelled with its closest centre.
+PARAMETERS
+    points *mat.Dense: the points we would like to find a cluster, each column is a point
+    Options MiniBatchOptions: the settings
+RETURN
+    KMeansResult: the outcome, Iterations counts the mini-batches
+*/
+func MiniBatchKMeans(points *mat.Dense, Options MiniBatchOptions) KMeansResult {
+    p := newPointSet(points)
+    if Options.NumClasses <= 0 || Options.NumClasses > p.N { panic("Number of classes outside [1, #points] encountered") }
+    batchSize := Options.BatchSize
+    if batchSize <= 0 { batchSize = 1024 }
+    iterations := Options.Iterations
+    if iterations <= 0 { iterations = 100 }
+    randGen := rand.New(rand.NewSource(uint64(Options.Seed)))
+
+    // k-means++ on a subset, it would need NumClasses passes over all the points
+    sampleSize := int(math.Min(float64(p.N), math.Max(float64(3 * batchSize), float64(10 * Options.NumClasses))))
+    sample := mat.NewDense(p.Dims, sampleSize, nil)
+    for j, i := range randGen.Perm(p.N)[:sampleSize] {
+        for d:=0; d<p.Dims; d++ { sample.Set(d, j, p.at(i, d)) }
+    }
+    centres := PlusPlusCentres(sample, Options.NumClasses, randGen)
+
+    result := KMeansResult{Centres: centres}
+    seen := make([]int, Options.NumClasses)
+    batch := make([]int, batchSize)
+    batchLabels := make([]int, batchSize)
+    previous := make([][]float64, Options.NumClasses)
+    for k := range previous { previous[k] = make([]float64, p.Dims) }
+    for result.Iterations < iterations {
+        result.Iterations++
+        for b := range batch { batch[b] = randGen.Intn(p.N) }
+        parallelFor(batchSize, Options.NumWorkers, func (_, first, last int) {
+            for b:=first; b<last; b++ {
+                batchLabels[b], _ = p.nearest(batch[b], centres)
+            }
+        })
+        for k := range centres { copy(previous[k], centres[k]) }
+        for b, i := range batch {
+            label := batchLabels[b]
+            seen[label]++
+            rate := 1.0 / float64(seen[label])
+            for d := range centres[label] {
+                centres[label][d] += rate * (p.at(i, d) - centres[label][d])
+            }
+        }
+        shift := 0.0
+        for k := range centres {
+            shift = math.Max(shift, floats.Distance(previous[k], centres[k], 2))
+        }
+        if shift <= Options.Tolerance {
+            result.Converged = true
+            break
+        }
+    }
+    result.Labels = make([]int, p.N)
+    _, _, result.Inertia = assign(p, centres, result.Labels, Options.NumWorkers)
+    return result
+}
@@ -0,0 +1,202 @@
+package kmeans
+
+import (
+    "math"
+    "sync"
+
+    "gonum.org/v1/gonum/mat"
+)
+
+
+/*
+This type gives copy-free access to the columns of a points matrix.
+    data []float64: the backing slice of the matrix
+    stride int: the stride of the matrix
+    Dims int: the dimension of the points
+    N int: the number of points
+*/
+type pointSet struct {
+    data []float64
+    stride int
+    Dims int
+    N int
+}
+
+
+/*
+SUMMARY
+    Wraps a points matrix without copying it.
+PARAMETERS
+    points *mat.Dense: the points, each column is a point
+RETURN
+    pointSet: the wrapper
+*/
+func newPointSet(points *mat.Dense) pointSet {
+    raw := points.RawMatrix()
+    return pointSet{data: raw.Data, stride: raw.Stride, Dims: raw.Rows, N: raw.Cols}
+}
+
+
+/*
+SUMMARY
+    A coordinate of a point.
+PARAMETERS
+    I int: the index of the point
+    D int: the index of the coordinate
+RETURN
+    float64: the coordinate
+*/
+func (p pointSet) at(I, D int) float64 {
+    return p.data[D*p.stride + I]
+}
+
+
+/*
+SUMMARY
+    The squared Euclidean distance of a point from a centre.
+PARAMETERS
+    I int: the index of the point
+    Centre []float64: the centre
+RETURN
+    float64: the squared distance
+*/
+func (p pointSet) squaredDistance(I int, Centre []float64) float64 {
+    d := 0.0
+    for k, c := range Centre {
+        diff := p.data[k*p.stride + I] - c
+        d += diff * diff
+    }
+    return d
+}
+
+
+/*
+SUMMARY
+    Copies a point into a new slice.
+PARAMETERS
+    I int: the index of the point
+RETURN
+    []float64: the point
+*/
+func (p pointSet) copyPoint(I int) []float64 {
+    point := make([]float64, p.Dims)
+    for d := range point { point[d] = p.at(I, d) }
+    return point
+}
+
+
+/*
+SUMMARY
+    Finds the closest centre of a point.
+PARAMETERS
+    I int: the index of the point
+    Centres [][]float64: the centres
+RETURN
+    int: the index of the closest centre
+    float64: the squared distance from it
+*/
+func (p pointSet) nearest(I int, Centres [][]float64) (int, float64) {
+    label, best := 0, math.Inf(1)
+    for k, centre := range Centres {
+        if d := p.squaredDistance(I, centre); d < best {
+            label, best = k, d
+        }
+    }
+    return label, best
+}
+
+
+/*
+SUMMARY
+    Splits [0,N) into contiguous chunks and runs Body on them in separate goroutines.
+PARAMETERS
+    N int: the number of items
+    NumWorkers int: the number of goroutines (1 if 0)
+    Body func(worker, first, last int): processes the items first, ..., last-1
+RETURN
+    int: the number of goroutines used
+*/
+func parallelFor(N, NumWorkers int, Body func(worker, first, last int)) int {
+    workers := NumWorkers
+    if workers <= 0 { workers = 1 }
+    if workers > N { workers = N }
+    if workers <= 1 {
+        Body(0, 0, N)
+        return 1
+    }
+    var wg sync.WaitGroup
+    for w:=0; w<workers; w++ {
+        wg.Add(1)
+        go func(w int) {
+            defer wg.Done()
+            Body(w, w * N / workers, (w + 1) * N / workers)
+        }(w)
+    }
+    wg.Wait()
+    return workers
+}
+
+
+/*
+SUMMARY
+    Labels every point with its closest centre and accumulates the cluster sums on several goroutines.
+    Each goroutine keeps its own partial sums which are added up at the end.
+PARAMETERS
+    p pointSet: the points
+    centres [][]float64: the centres
+    labels []int: the labels, overwritten
+    numWorkers int: the number of goroutines
+RETURN
+    [][]float64: the sum of the points of each cluster
+    []int: the size of each cluster
+    float64: the inertia
+*/
+func assign(p pointSet, centres [][]float64, labels []int, numWorkers int) ([][]float64, []int, float64) {
+    type partial struct {
+        sums [][]float64
+        counts []int
+        inertia float64
+    }
+    partials := make([]partial, maxWorkers(p.N, numWorkers))
+    parallelFor(p.N, numWorkers, func (worker, first, last int) {
+        part := partial{sums: make([][]float64, len(centres)), counts: make([]int, len(centres))}
+        for k := range part.sums { part.sums[k] = make([]float64, p.Dims) }
+        for i:=first; i<last; i++ {
+            label, d := p.nearest(i, centres)
+            labels[i] = label
+            part.inertia += d
+            part.counts[label]++
+            for k := range part.sums[label] { part.sums[label][k] += p.at(i, k) }
+        }
+        partials[worker] = part
+    })
+    sums := make([][]float64, len(centres))
+    for k := range sums { sums[k] = make([]float64, p.Dims) }
+    counts := make([]int, len(centres))
+    inertia := 0.0
+    for _, part := range partials {
+        if part.sums == nil { continue }
+        for k := range sums {
+            for d := range sums[k] { sums[k][d] += part.sums[k][d] }
+            counts[k] += part.counts[k]
+        }
+        inertia += part.inertia
+    }
+    return sums, counts, inertia
+}
+
+
+/*
+SUMMARY
+    The number of goroutines parallelFor will use.
+PARAMETERS
+    N int: the number of items
+    NumWorkers int: the requested number of goroutines
+RETURN
+    int: the number of goroutines
+*/
+func maxWorkers(N, NumWorkers int) int {
+    if NumWorkers <= 1 || N <= 1 { return 1 }
+    if NumWorkers > N { return N }
+    return NumWorkers
+}
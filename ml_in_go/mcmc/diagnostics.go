@@ -0,0 +1,477 @@
+package mcmc
+
+import (
+    "math"
+    "sort"
+    "sync"
+
+    "gonum.org/v1/gonum/dsp/fourier"
+    "gonum.org/v1/gonum/stat/distuv"
+)
+
+
+/*
+This type summarises the draws of one parameter over several chains, following
+Vehtari, Gelman, Simpson, Carpenter & Burkner, Rank-normalization, folding, and localization:
+An improved R-hat for assessing convergence of MCMC, https://arxiv.org/pdf/1903.08008.pdf
+    Mean float64: the mean over every draw
+    SD float64: the standard deviation over every draw
+    MCSE float64: the Monte Carlo standard error of the mean
+    RHat float64: the rank-normalised split R-hat (values above 1.01 signal trouble)
+    BulkESS float64: the effective sample size of the rank-normalised draws
+    TailESS float64: the smaller effective sample size of the 5% and 95% quantiles
+*/
+type Summary struct {
+    Mean float64
+    SD float64
+    MCSE float64
+    RHat float64
+    BulkESS float64
+    TailESS float64
+}
+
+
+/*
+SUMMARY
+    Runs several chains in parallel goroutines. Each chain should use its own seed, e.g. Seed+Index.
+PARAMETERS
+    NumChains int: the number of chains
+    Sampler func(int) Chain: runs the chain with the given index
+RETURN
+    []Chain: the chains in the order of their indices
+*/
+func RunChains(NumChains int, Sampler func(int) Chain) []Chain {
+    if NumChains <= 0 { panic("Negative/0 number of chains encountered") }
+    chains := make([]Chain, NumChains)
+    var wg sync.WaitGroup
+    for c:=0; c<NumChains; c++ {
+        wg.Add(1)
+        go func(c int) {
+            defer wg.Done()
+            chains[c] = Sampler(c)
+        }(c)
+    }
+    wg.Wait()
+    return chains
+}
+
+
+/*
+SUMMARY
+    Extracts the draws of one parameter from every chain.
+PARAMETERS
+    Chains []Chain: the chains
+    Param int: the index of the parameter
+RETURN
+    [][]float64: chains by draws
+*/
+func Draws(Chains []Chain, Param int) [][]float64 {
+    draws := make([][]float64, len(Chains))
+    for c, chain := range Chains {
+        N, _ := chain.Samples.Dims()
+        draws[c] = make([]float64, N)
+        for i:=0; i<N; i++ {
+            draws[c][i] = chain.Samples.At(i, Param)
+        }
+    }
+    return draws
+}
+
+
+/*
+SUMMARY
+    The autocorrelation function of one chain, computed with the fast Fourier transform
+    on the zero padded sequence.
+PARAMETERS
+    X []float64: the draws of one chain
+    MaxLag int: the largest lag (at most len(X)-1)
+RETURN
+    []float64: the autocorrelations at lags 0..MaxLag, the first is 1
+*/
+func Autocorrelation(X []float64, MaxLag int) []float64 {
+    acov := autocovariance(X)
+    if MaxLag >= len(X) { MaxLag = len(X) - 1 }
+    acf := make([]float64, MaxLag+1)
+    for t := range acf {
+        if acov[0] > 0 {
+            acf[t] = acov[t] / acov[0]
+        }
+    }
+    acf[0] = 1.0
+    return acf
+}
+
+
+/*
+SUMMARY
+    The biased (divided by N) autocovariance of a sequence at every lag.
+PARAMETERS
+    X []float64: the sequence
+RETURN
+    []float64: the autocovariances at lags 0..len(X)-1
+*/
+func autocovariance(X []float64) []float64 {
+    N := len(X)
+    size := 1
+    for size < 2*N { size *= 2 }
+    mean := 0.0
+    for _, x := range X { mean += x / float64(N) }
+    padded := make([]float64, size)
+    for i, x := range X { padded[i] = x - mean }
+    fft := fourier.NewFFT(size)
+    coeff := fft.Coefficients(nil, padded)
+    for i := range coeff {
+        coeff[i] = complex(real(coeff[i])*real(coeff[i]) + imag(coeff[i])*imag(coeff[i]), 0)
+    }
+    power := fft.Sequence(nil, coeff)
+    acov := make([]float64, N)
+    for t := range acov {
+        // the inverse transform is unnormalised
+        acov[t] = power[t] / float64(size) / float64(N)
+    }
+    return acov
+}
+
+
+/*
+SUMMARY
+    Splits every chain into its first and second half, dropping the middle draw of odd chains.
+PARAMETERS
+    Draws [][]float64: chains by draws
+RETURN
+    [][]float64: twice as many chains of half length
+*/
+func splitChains(Draws [][]float64) [][]float64 {
+    split := make([][]float64, 0, 2*len(Draws))
+    for _, chain := range Draws {
+        half := len(chain) / 2
+        split = append(split, chain[:half], chain[len(chain)-half:])
+    }
+    return split
+}
+
+
+/*
+SUMMARY
+    Replaces the draws by their normal scores z = Phi^-1((r-3/8)/(S+1/4)), where r is the
+    rank over every chain (ties get the average rank) and S is the total number of draws.
+PARAMETERS
+    Draws [][]float64: chains by draws
+RETURN
+    [][]float64: the rank-normalised draws
+*/
+func rankNormalise(Draws [][]float64) [][]float64 {
+    ranks := poolRanks(Draws)
+    S := 0
+    for _, chain := range Draws { S += len(chain) }
+    normal := distuv.UnitNormal
+    z := make([][]float64, len(Draws))
+    for c := range Draws {
+        z[c] = make([]float64, len(Draws[c]))
+        for i := range Draws[c] {
+            z[c][i] = normal.Quantile((ranks[c][i] - 0.375) / (float64(S) + 0.25))
+        }
+    }
+    return z
+}
+
+
+/*
+SUMMARY
+    Ranks (starting from 1) of the draws pooled over every chain, ties get the average rank.
+PARAMETERS
+    Draws [][]float64: chains by draws
+RETURN
+    [][]float64: the ranks in the shape of Draws
+*/
+func poolRanks(Draws [][]float64) [][]float64 {
+    type entry struct {
+        value float64
+        chain int
+        index int
+    }
+    var pooled []entry
+    for c, chain := range Draws {
+        for i, x := range chain {
+            pooled = append(pooled, entry{value: x, chain: c, index: i})
+        }
+    }
+    sort.Slice(pooled, func(a, b int) bool { return pooled[a].value < pooled[b].value })
+    ranks := make([][]float64, len(Draws))
+    for c := range Draws { ranks[c] = make([]float64, len(Draws[c])) }
+    for start:=0; start<len(pooled); {
+        end := start + 1
+        for end < len(pooled) && pooled[end].value == pooled[start].value { end++ }
+        rank := 0.5 * float64(start + 1 + end)
+        for k:=start; k<end; k++ {
+            ranks[pooled[k].chain][pooled[k].index] = rank
+        }
+        start = end
+    }
+    return ranks
+}
+
+
+/*
+SUMMARY
+    The R-hat of Gelman et al., Bayesian Data Analysis (3rd edition), on chains that are already split.
+PARAMETERS
+    Draws [][]float64: chains by draws, every chain of the same length
+RETURN
+    float64: sqrt(var_plus / W)
+*/
+func rHat(Draws [][]float64) float64 {
+    M := float64(len(Draws))
+    N := float64(len(Draws[0]))
+    means := make([]float64, len(Draws))
+    grandMean, W := 0.0, 0.0
+    for c, chain := range Draws {
+        for _, x := range chain { means[c] += x / N }
+        grandMean += means[c] / M
+        variance := 0.0
+        for _, x := range chain { variance += (x - means[c]) * (x - means[c]) / (N - 1) }
+        W += variance / M
+    }
+    B := 0.0
+    for _, m := range means { B += N * (m - grandMean) * (m - grandMean) / (M - 1) }
+    if W == 0 { return math.NaN() }
+    return math.Sqrt(((N - 1) / N * W + B / N) / W)
+}
+
+
+/*
+SUMMARY
+    The classic split R-hat of the raw draws. Use RHat for the more robust rank-normalised version.
+PARAMETERS
+    Draws [][]float64: chains by draws, every chain of the same length
+RETURN
+    float64: the split R-hat
+*/
+func SplitRHat(Draws [][]float64) float64 {
+    return rHat(splitChains(Draws))
+}
+
+
+/*
+SUMMARY
+    The rank-normalised split R-hat: the larger of the split R-hat of the rank-normalised draws
+    (detects different locations) and of the rank-normalised folded draws |x - median| (detects different scales).
+PARAMETERS
+    Draws [][]float64: chains by draws, every chain of the same length
+RETURN
+    float64: R-hat
+*/
+func RHat(Draws [][]float64) float64 {
+    split := splitChains(Draws)
+    median := quantile(split, 0.5)
+    folded := make([][]float64, len(split))
+    for c, chain := range split {
+        folded[c] = make([]float64, len(chain))
+        for i, x := range chain { folded[c][i] = math.Abs(x - median) }
+    }
+    return math.Max(rHat(rankNormalise(split)), rHat(rankNormalise(folded)))
+}
+
+
+/*
+SUMMARY
+    The effective sample size of chains that are already split, using the multi-chain autocorrelation
+    estimate and Geyer's initial monotone sequence to truncate the sum of autocorrelations (as in Stan).
+PARAMETERS
+    Draws [][]float64: chains by draws, every chain of the same length
+RETURN
+    float64: the effective sample size
+*/
+func ess(Draws [][]float64) float64 {
+    M := len(Draws)
+    N := len(Draws[0])
+    if N < 4 { return math.NaN() }
+    acovs := make([][]float64, M)
+    means := make([]float64, M)
+    meanVar := 0.0
+    for c, chain := range Draws {
+        acovs[c] = autocovariance(chain)
+        for _, x := range chain { means[c] += x / float64(N) }
+        meanVar += acovs[c][0] * float64(N) / float64(N - 1) / float64(M)
+    }
+    varPlus := meanVar * float64(N - 1) / float64(N)
+    if M > 1 {
+        grandMean := 0.0
+        for _, m := range means { grandMean += m / float64(M) }
+        B := 0.0
+        for _, m := range means { B += (m - grandMean) * (m - grandMean) / float64(M - 1) }
+        varPlus += B
+    }
+    if varPlus == 0 { return math.NaN() }
+    rhoAt := func(t int) float64 {
+        acov := 0.0
+        for c := range acovs { acov += acovs[c][t] / float64(M) }
+        return 1.0 - (meanVar - acov) / varPlus
+    }
+
+    rho := make([]float64, N)
+    rho[0] = 1.0
+    rhoEven, rhoOdd := 1.0, rhoAt(1)
+    rho[1] = rhoOdd
+    t := 1
+    for t < N - 4 && rhoEven + rhoOdd > 0 {
+        rhoEven, rhoOdd = rhoAt(t + 1), rhoAt(t + 2)
+        if rhoEven + rhoOdd >= 0 {
+            rho[t+1], rho[t+2] = rhoEven, rhoOdd
+        }
+        t += 2
+    }
+    maxT := t
+    if rhoEven > 0 { rho[maxT+1] = rhoEven }
+    // the sums of consecutive pairs must be non-increasing
+    for t=1; t<=maxT-3; t+=2 {
+        if rho[t+1] + rho[t+2] > rho[t-1] + rho[t] {
+            rho[t+1] = 0.5 * (rho[t-1] + rho[t])
+            rho[t+2] = rho[t+1]
+        }
+    }
+    tau := -1.0 + rho[maxT+1]
+    for t=0; t<=maxT; t++ { tau += 2.0 * rho[t] }
+    S := float64(M * N)
+    tau = math.Max(tau, 1.0 / math.Log10(S))
+    return S / tau
+}
+
+
+/*
+SUMMARY
+    The effective sample size of the split raw draws, it applies to the mean of the parameter.
+PARAMETERS
+    Draws [][]float64: chains by draws, every chain of the same length
+RETURN
+    float64: the effective sample size
+*/
+func EffectiveSampleSize(Draws [][]float64) float64 {
+    return ess(splitChains(Draws))
+}
+
+
+/*
+SUMMARY
+    The bulk effective sample size: the effective sample size of the split rank-normalised draws.
+PARAMETERS
+    Draws [][]float64: chains by draws, every chain of the same length
+RETURN
+    float64: the bulk effective sample size
+*/
+func BulkESS(Draws [][]float64) float64 {
+    return ess(rankNormalise(splitChains(Draws)))
+}
+
+
+/*
+SUMMARY
+    The tail effective sample size: the smaller effective sample size of the indicators
+    I(x <= q5%) and I(x <= q95%).
+PARAMETERS
+    Draws [][]float64: chains by draws, every chain of the same length
+RETURN
+    float64: the tail effective sample size
+*/
+func TailESS(Draws [][]float64) float64 {
+    split := splitChains(Draws)
+    result := math.Inf(1)
+    for _, prob := range []float64{0.05, 0.95} {
+        q := quantile(split, prob)
+        indicator := make([][]float64, len(split))
+        for c, chain := range split {
+            indicator[c] = make([]float64, len(chain))
+            for i, x := range chain {
+                if x <= q { indicator[c][i] = 1.0 }
+            }
+        }
+        result = math.Min(result, ess(indicator))
+    }
+    return result
+}
+
+
+/*
+SUMMARY
+    The Monte Carlo standard error of the posterior mean estimate, sd / sqrt(ESS).
+PARAMETERS
+    Draws [][]float64: chains by draws, every chain of the same length
+RETURN
+    float64: the standard error
+*/
+func MCSE(Draws [][]float64) float64 {
+    _, sd := meanSD(Draws)
+    return sd / math.Sqrt(EffectiveSampleSize(Draws))
+}
+
+
+/*
+SUMMARY
+    Computes every diagnostic for every parameter, the parameters are processed in parallel.
+PARAMETERS
+    Chains []Chain: the chains, each with the same number of draws
+RETURN
+    []Summary: one summary per parameter
+*/
+func Summarise(Chains []Chain) []Summary {
+    if len(Chains) == 0 { panic("No chains encountered") }
+    _, D := Chains[0].Samples.Dims()
+    summaries := make([]Summary, D)
+    var wg sync.WaitGroup
+    for d:=0; d<D; d++ {
+        wg.Add(1)
+        go func(d int) {
+            defer wg.Done()
+            draws := Draws(Chains, d)
+            mean, sd := meanSD(draws)
+            summaries[d] = Summary{Mean: mean, SD: sd, MCSE: MCSE(draws), RHat: RHat(draws), BulkESS: BulkESS(draws), TailESS: TailESS(draws)}
+        }(d)
+    }
+    wg.Wait()
+    return summaries
+}
+
+
+/*
+SUMMARY
+    Mean and standard deviation of the draws pooled over every chain.
+PARAMETERS
+    Draws [][]float64: chains by draws
+RETURN
+    float64: the mean
+    float64: the standard deviation
+*/
+func meanSD(Draws [][]float64) (float64, float64) {
+    S := 0
+    mean := 0.0
+    for _, chain := range Draws {
+        for _, x := range chain { mean += x; S++ }
+    }
+    mean /= float64(S)
+    variance := 0.0
+    for _, chain := range Draws {
+        for _, x := range chain { variance += (x - mean) * (x - mean) / float64(S - 1) }
+    }
+    return mean, math.Sqrt(variance)
+}
+
+
+/*
+SUMMARY
+    The empirical quantile of the draws pooled over every chain (linear interpolation).
+PARAMETERS
+    Draws [][]float64: chains by draws
+    Prob float64: the probability in [0,1]
+RETURN
+    float64: the quantile
+*/
+func quantile(Draws [][]float64, Prob float64) float64 {
+    var pooled []float64
+    for _, chain := range Draws { pooled = append(pooled, chain...) }
+    sort.Float64s(pooled)
+    position := Prob * float64(len(pooled) - 1)
+    lower := int(math.Floor(position))
+    if lower >= len(pooled) - 1 { return pooled[len(pooled)-1] }
+    fraction := position - float64(lower)
+    return (1 - fraction) * pooled[lower] + fraction * pooled[lower+1]
+}
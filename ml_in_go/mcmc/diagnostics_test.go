@@ -0,0 +1,79 @@
+package mcmc
+
+import (
+    "math"
+    "testing"
+    "golang.org/x/exp/rand"
+)
+
+
+/*
+SUMMARY
+    Independent standard normal draws, optionally shifted per chain.
+PARAMETERS
+    NumChains int: the number of chains
+    NumDraws int: the number of draws per chain
+    Shift float64: chain c has mean c*Shift
+    Seed int: seed of the random numbers
+RETURN
+    [][]float64: chains by draws
+*/
+func whiteNoise(NumChains, NumDraws int, Shift float64, Seed int) [][]float64 {
+    randGen := rand.New(rand.NewSource(uint64(Seed)))
+    draws := make([][]float64, NumChains)
+    for c := range draws {
+        draws[c] = make([]float64, NumDraws)
+        for i := range draws[c] { draws[c][i] = float64(c) * Shift + randGen.NormFloat64() }
+    }
+    return draws
+}
+
+
+func TestRHat(t *testing.T) {
+    for seed:=1; seed<=5; seed++ {
+        if r := RHat(whiteNoise(4, 1000, 0.0, seed)); math.Abs(r - 1.0) > 0.01 {
+            t.Errorf("seed %d: R-hat %g on independent chains", seed, r)
+        }
+        if r := RHat(whiteNoise(4, 1000, 1.0, seed)); r < 1.1 {
+            t.Errorf("seed %d: R-hat %g on chains with shifted means", seed, r)
+        }
+        if r := SplitRHat(whiteNoise(4, 1000, 1.0, seed)); r < 1.1 {
+            t.Errorf("seed %d: split R-hat %g on chains with shifted means", seed, r)
+        }
+    }
+    // chains with the same location but different scales are caught by the folded draws
+    scaled := whiteNoise(4, 1000, 0.0, 6)
+    for i := range scaled[0] { scaled[0][i] *= 4.0 }
+    if r := RHat(scaled); r < 1.1 { t.Errorf("R-hat %g on chains with different scales", r) }
+}
+
+
+func TestEffectiveSampleSize(t *testing.T) {
+    draws := whiteNoise(4, 1000, 0.0, 7)
+    total := 4000.0
+    for _, estimate := range []struct {
+        name string
+        value float64
+    }{
+        {name: "ESS", value: EffectiveSampleSize(draws)},
+        {name: "bulk ESS", value: BulkESS(draws)},
+        {name: "tail ESS", value: TailESS(draws)},
+    } {
+        if math.Abs(estimate.value - total) > 0.15 * total {
+            t.Errorf("%s %g on white noise, expected about %g", estimate.name, estimate.value, total)
+        }
+    }
+
+    // an AR(1) chain with coefficient phi has ESS N (1 - phi) / (1 + phi)
+    randGen := rand.New(rand.NewSource(8))
+    phi := 0.8
+    ar := make([][]float64, 4)
+    for c := range ar {
+        ar[c] = make([]float64, 5000)
+        for i:=1; i<len(ar[c]); i++ { ar[c][i] = phi * ar[c][i-1] + randGen.NormFloat64() }
+    }
+    expected := 20000.0 * (1.0 - phi) / (1.0 + phi)
+    if ess := EffectiveSampleSize(ar); math.Abs(ess - expected) > 0.25 * expected {
+        t.Errorf("ESS %g of AR(1) chains, expected about %g", ess, expected)
+    }
+}
@@ -0,0 +1,340 @@
+package mcmc
+
+import (
+    "math"
+    "golang.org/x/exp/rand"
+    "gonum.org/v1/gonum/mat"
+)
+
+
+/*
+This type configures the Hamiltonian Monte Carlo and the No-U-Turn samplers.
+    NumSamples int: the number of draws kept
+    Warmup int: the number of draws discarded at the beginning, the step size adapts during these
+    StepSize float64: the initial leapfrog step size, if 0 a reasonable one is searched for
+    NumLeapfrog int: the number of leapfrog steps per iteration (HMC only)
+    MaxTreeDepth int: the maximal depth of the trajectory tree (NUTS only, 10 if 0)
+    Adapt bool: whether to adapt the step size with dual averaging during warm-up
+    TargetAcceptance float64: the mean acceptance probability the adaptation aims at (0.8 if 0)
+    Seed int: seed of the random number generator
+*/
+type HMCOptions struct {
+    NumSamples int
+    Warmup int
+    StepSize float64
+    NumLeapfrog int
+    MaxTreeDepth int
+    Adapt bool
+    TargetAcceptance float64
+    Seed int
+}
+
+
+// a trajectory whose energy error exceeds this is called divergent
+const maxEnergyError = 1000.0
+
+
+/*
+SUMMARY
+    One leapfrog step of the Hamiltonian dynamics with identity mass matrix.
+PARAMETERS
+    T Target: the target distribution
+    X []float64: the position
+    R []float64: the momentum
+    Grad []float64: the gradient of the log-density at X
+    StepSize float64: the step size, negative for integrating backwards in time
+RETURN
+    []float64: the new position
+    []float64: the new momentum
+    []float64: the gradient at the new position
+    float64: the log-density at the new position
+*/
+func leapfrog(T Target, X, R, Grad []float64, StepSize float64) ([]float64, []float64, []float64, float64) {
+    NewX := make([]float64, len(X))
+    NewR := make([]float64, len(R))
+    for i := range X {
+        NewR[i] = R[i] + 0.5 * StepSize * Grad[i]
+        NewX[i] = X[i] + StepSize * NewR[i]
+    }
+    NewGrad := T.Gradient(NewX)
+    for i := range R {
+        NewR[i] += 0.5 * StepSize * NewGrad[i]
+    }
+    return NewX, NewR, NewGrad, safeLogDensity(T, NewX)
+}
+
+
+/*
+SUMMARY
+    Heuristic for the initial step size (Hoffman & Gelman, Algorithm 4): the step size is halved or
+    doubled until the acceptance probability of one leapfrog step crosses 0.5.
+PARAMETERS
+    T Target: the target distribution
+    X []float64: the initial position
+    RandGen *rand.Rand: random number generator
+RETURN
+    float64: the step size
+*/
+func findReasonableStepSize(T Target, X []float64, RandGen *rand.Rand) float64 {
+    stepSize := 1.0
+    grad := T.Gradient(X)
+    logP := safeLogDensity(T, X)
+    R := make([]float64, len(X))
+    for i := range R { R[i] = RandGen.NormFloat64() }
+    logAccept := func() float64 {
+        _, NewR, _, NewLogP := leapfrog(T, X, R, grad, stepSize)
+        value := NewLogP - 0.5 * dot(NewR, NewR) - logP + 0.5 * dot(R, R)
+        if math.IsNaN(value) { return math.Inf(-1) }
+        return value
+    }
+    direction := -1.0
+    if logAccept() > math.Log(0.5) { direction = 1.0 }
+    for i:=0; i<100 && direction * logAccept() > -direction * math.Log(2.0); i++ {
+        stepSize *= math.Pow(2.0, direction)
+    }
+    return stepSize
+}
+
+
+/*
+SUMMARY
+    Checks the options shared by HMC and NUTS and sets up the initial step size.
+PARAMETERS
+    T Target: the target distribution
+    Start []float64: the initial position
+    Options *HMCOptions: the settings, the defaults are filled in
+    RandGen *rand.Rand: random number generator
+RETURN
+    float64: the initial step size
+*/
+func prepareHamiltonian(T Target, Start []float64, Options *HMCOptions, RandGen *rand.Rand) float64 {
+    if T.Gradient == nil { panic("Hamiltonian samplers need the gradient of the log-density") }
+    if Options.NumSamples <= 0 { panic("Negative/0 number of samples encountered") }
+    if Options.StepSize < 0 { panic("Negative step size encountered") }
+    if Options.TargetAcceptance == 0 { Options.TargetAcceptance = 0.8 }
+    if Options.MaxTreeDepth == 0 { Options.MaxTreeDepth = 10 }
+    if Options.StepSize == 0 {
+        return findReasonableStepSize(T, Start, RandGen)
+    }
+    return Options.StepSize
+}
+
+
+/*
+SUMMARY
+    Hamiltonian Monte Carlo with a fixed number of leapfrog steps and identity mass matrix.
+    The step size can be adapted during warm-up with dual averaging.
+PARAMETERS
+    T Target: the distribution, both LogDensity and Gradient are used
+    Start []float64: the initial position
+    Options HMCOptions: the settings of the sampler, NumLeapfrog must be positive
+RETURN
+    Chain: the draws after warm-up
+*/
+func HMC(T Target, Start []float64, Options HMCOptions) Chain {
+    if Options.NumLeapfrog <= 0 { panic("Negative/0 number of leapfrog steps encountered") }
+    randGen := rand.New(rand.NewSource(uint64(Options.Seed)))
+    stepSize := prepareHamiltonian(T, Start, &Options, randGen)
+    adaptation := newDualAveraging(stepSize, Options.TargetAcceptance)
+    D := len(Start)
+
+    X := make([]float64, D)
+    copy(X, Start)
+    grad := T.Gradient(X)
+    logP := safeLogDensity(T, X)
+    chain := Chain{Samples: mat.NewDense(Options.NumSamples, D, nil), LogDensities: make([]float64, Options.NumSamples)}
+    R := make([]float64, D)
+    for iter:=0; iter<Options.Warmup+Options.NumSamples; iter++ {
+        for i := range R { R[i] = randGen.NormFloat64() }
+        H0 := -logP + 0.5 * dot(R, R)
+        NewX, NewR, NewGrad, NewLogP := X, R, grad, logP
+        divergent := false
+        for l:=0; l<Options.NumLeapfrog; l++ {
+            NewX, NewR, NewGrad, NewLogP = leapfrog(T, NewX, NewR, NewGrad, stepSize)
+            if math.IsInf(NewLogP, -1) || -NewLogP + 0.5 * dot(NewR, NewR) - H0 > maxEnergyError {
+                divergent = true
+                break
+            }
+        }
+        acceptProb := 0.0
+        if !divergent {
+            acceptProb = math.Min(1.0, math.Exp(H0 - (-NewLogP + 0.5 * dot(NewR, NewR))))
+        }
+        if randGen.Float64() < acceptProb {
+            X, grad, logP = NewX, NewGrad, NewLogP
+        }
+
+        if iter < Options.Warmup {
+            if Options.Adapt {
+                stepSize = adaptation.update(acceptProb)
+                if iter == Options.Warmup - 1 { stepSize = adaptation.final() }
+            }
+        } else {
+            i := iter - Options.Warmup
+            chain.Samples.SetRow(i, X)
+            chain.LogDensities[i] = logP
+            chain.AcceptanceRate += acceptProb / float64(Options.NumSamples)
+            if divergent { chain.Divergences++ }
+        }
+    }
+    chain.StepSize = stepSize
+    return chain
+}
+
+
+// this type stores the result of building one subtree in NUTS
+type nutsTree struct {
+    XMinus, RMinus, GradMinus []float64
+    XPlus, RPlus, GradPlus []float64
+    XProposal, GradProposal []float64
+    LogPProposal float64
+    N float64
+    Continue bool
+    Divergent bool
+    AlphaSum float64
+    NumAlpha float64
+}
+
+
+/*
+SUMMARY
+    Checks the no-U-turn criterion between the two ends of a trajectory.
+PARAMETERS
+    XMinus []float64: the backward end position
+    XPlus []float64: the forward end position
+    RMinus []float64: the backward end momentum
+    RPlus []float64: the forward end momentum
+RETURN
+    bool: true if the trajectory has not started to turn back on itself
+*/
+func noUTurn(XMinus, XPlus, RMinus, RPlus []float64) bool {
+    diff := make([]float64, len(XPlus))
+    for i := range diff { diff[i] = XPlus[i] - XMinus[i] }
+    return dot(diff, RMinus) >= 0 && dot(diff, RPlus) >= 0
+}
+
+
+/*
+SUMMARY
+    Recursively builds a balanced binary tree of 2^Depth leapfrog steps in the direction Direction
+    (Hoffman & Gelman, Algorithm 6 BuildTree).
+PARAMETERS
+    T Target: the target distribution
+    X []float64: the position at the end of the trajectory we extend
+    R []float64: the momentum at the end of the trajectory we extend
+    Grad []float64: the gradient at X
+    LogU float64: log of the slice variable
+    Direction float64: +1 forward, -1 backward in time
+    Depth int: the depth of the tree
+    StepSize float64: the leapfrog step size
+    Joint0 float64: the joint log-density at the start of the iteration
+    RandGen *rand.Rand: random number generator
+RETURN
+    nutsTree: the subtree
+*/
+func buildTree(T Target, X, R, Grad []float64, LogU, Direction float64, Depth int, StepSize, Joint0 float64, RandGen *rand.Rand) nutsTree {
+    if Depth == 0 {
+        NewX, NewR, NewGrad, NewLogP := leapfrog(T, X, R, Grad, Direction * StepSize)
+        joint := NewLogP - 0.5 * dot(NewR, NewR)
+        if math.IsNaN(joint) { joint = math.Inf(-1) }
+        tree := nutsTree{XMinus: NewX, RMinus: NewR, GradMinus: NewGrad,
+                         XPlus: NewX, RPlus: NewR, GradPlus: NewGrad,
+                         XProposal: NewX, GradProposal: NewGrad, LogPProposal: NewLogP,
+                         NumAlpha: 1.0}
+        if LogU <= joint { tree.N = 1.0 }
+        tree.Continue = LogU < maxEnergyError + joint
+        tree.Divergent = !tree.Continue
+        tree.AlphaSum = math.Min(1.0, math.Exp(joint - Joint0))
+        return tree
+    }
+    tree := buildTree(T, X, R, Grad, LogU, Direction, Depth-1, StepSize, Joint0, RandGen)
+    if !tree.Continue {
+        return tree
+    }
+    var other nutsTree
+    if Direction < 0 {
+        other = buildTree(T, tree.XMinus, tree.RMinus, tree.GradMinus, LogU, Direction, Depth-1, StepSize, Joint0, RandGen)
+        tree.XMinus, tree.RMinus, tree.GradMinus = other.XMinus, other.RMinus, other.GradMinus
+    } else {
+        other = buildTree(T, tree.XPlus, tree.RPlus, tree.GradPlus, LogU, Direction, Depth-1, StepSize, Joint0, RandGen)
+        tree.XPlus, tree.RPlus, tree.GradPlus = other.XPlus, other.RPlus, other.GradPlus
+    }
+    if tree.N + other.N > 0 && RandGen.Float64() < other.N / (tree.N + other.N) {
+        tree.XProposal, tree.GradProposal, tree.LogPProposal = other.XProposal, other.GradProposal, other.LogPProposal
+    }
+    tree.AlphaSum += other.AlphaSum
+    tree.NumAlpha += other.NumAlpha
+    tree.Divergent = tree.Divergent || other.Divergent
+    tree.Continue = other.Continue && noUTurn(tree.XMinus, tree.XPlus, tree.RMinus, tree.RPlus)
+    tree.N += other.N
+    return tree
+}
+
+
+/*
+SUMMARY
+    The No-U-Turn Sampler with dual averaging, following Hoffman & Gelman,
+    https://arxiv.org/pdf/1111.4246.pdf (Algorithm 6). The trajectory is doubled in a random
+    direction until it starts to turn back on itself, hence the number of leapfrog steps need not be tuned.
+PARAMETERS
+    T Target: the distribution, both LogDensity and Gradient are used
+    Start []float64: the initial position
+    Options HMCOptions: the settings of the sampler (NumLeapfrog is ignored)
+RETURN
+    Chain: the draws after warm-up
+*/
+func NUTS(T Target, Start []float64, Options HMCOptions) Chain {
+    randGen := rand.New(rand.NewSource(uint64(Options.Seed)))
+    stepSize := prepareHamiltonian(T, Start, &Options, randGen)
+    adaptation := newDualAveraging(stepSize, Options.TargetAcceptance)
+    D := len(Start)
+
+    X := make([]float64, D)
+    copy(X, Start)
+    grad := T.Gradient(X)
+    logP := safeLogDensity(T, X)
+    chain := Chain{Samples: mat.NewDense(Options.NumSamples, D, nil), LogDensities: make([]float64, Options.NumSamples)}
+    for iter:=0; iter<Options.Warmup+Options.NumSamples; iter++ {
+        R0 := make([]float64, D)
+        for i := range R0 { R0[i] = randGen.NormFloat64() }
+        joint0 := logP - 0.5 * dot(R0, R0)
+        logU := joint0 + math.Log(randGen.Float64())
+
+        tree := nutsTree{XMinus: X, RMinus: R0, GradMinus: grad, XPlus: X, RPlus: R0, GradPlus: grad, N: 1.0, Continue: true}
+        acceptStat := 0.0
+        for depth:=0; tree.Continue && depth<Options.MaxTreeDepth; depth++ {
+            direction := 1.0
+            if randGen.Float64() < 0.5 { direction = -1.0 }
+            var other nutsTree
+            if direction < 0 {
+                other = buildTree(T, tree.XMinus, tree.RMinus, tree.GradMinus, logU, direction, depth, stepSize, joint0, randGen)
+                tree.XMinus, tree.RMinus, tree.GradMinus = other.XMinus, other.RMinus, other.GradMinus
+            } else {
+                other = buildTree(T, tree.XPlus, tree.RPlus, tree.GradPlus, logU, direction, depth, stepSize, joint0, randGen)
+                tree.XPlus, tree.RPlus, tree.GradPlus = other.XPlus, other.RPlus, other.GradPlus
+            }
+            if other.Continue && randGen.Float64() < other.N / tree.N {
+                X, grad, logP = other.XProposal, other.GradProposal, other.LogPProposal
+            }
+            tree.N += other.N
+            tree.Divergent = tree.Divergent || other.Divergent
+            tree.Continue = other.Continue && noUTurn(tree.XMinus, tree.XPlus, tree.RMinus, tree.RPlus)
+            acceptStat = other.AlphaSum / other.NumAlpha
+        }
+
+        if iter < Options.Warmup {
+            if Options.Adapt {
+                stepSize = adaptation.update(acceptStat)
+                if iter == Options.Warmup - 1 { stepSize = adaptation.final() }
+            }
+        } else {
+            i := iter - Options.Warmup
+            chain.Samples.SetRow(i, X)
+            chain.LogDensities[i] = logP
+            chain.AcceptanceRate += acceptStat / float64(Options.NumSamples)
+            if tree.Divergent { chain.Divergences++ }
+        }
+    }
+    chain.StepSize = stepSize
+    return chain
+}
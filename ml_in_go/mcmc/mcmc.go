@@ -0,0 +1,180 @@
+/*
+This library contains general Markov chain Monte Carlo samplers for arbitrary log-densities:
+random-walk Metropolis-Hastings with adaptive proposals, Hamiltonian Monte Carlo and the
+No-U-Turn Sampler. Every sampler returns a Chain; several chains can be run in parallel and
+checked for convergence with the diagnostics (R-hat, effective sample size, Monte Carlo standard error).
+*/
+package mcmc
+
+import (
+    "math"
+    "gonum.org/v1/gonum/mat"
+)
+
+
+/*
+This type describes the distribution we sample from.
+    LogDensity func([]float64) float64: the log-density up to an additive constant
+    Gradient func([]float64) []float64: the gradient of LogDensity, only HMC and NUTS need it
+*/
+type Target struct {
+    LogDensity func([]float64) float64
+    Gradient func([]float64) []float64
+}
+
+
+/*
+This type stores the draws of one sampler run (the warm-up draws are discarded).
+    Samples *mat.Dense: each row is one draw
+    LogDensities []float64: the log-density of each draw
+    AcceptanceRate float64: the mean acceptance probability after warm-up
+    StepSize float64: the final step size (HMC, NUTS) or proposal scale (Metropolis-Hastings)
+    Divergences int: the number of divergent trajectories after warm-up (HMC, NUTS)
+*/
+type Chain struct {
+    Samples *mat.Dense
+    LogDensities []float64
+    AcceptanceRate float64
+    StepSize float64
+    Divergences int
+}
+
+
+/*
+SUMMARY
+    Computes the mean of each parameter over the draws.
+PARAMETERS
+    N/A
+RETURN
+    []float64: the posterior mean estimate
+*/
+func (c Chain) Mean() []float64 {
+    N, D := c.Samples.Dims()
+    mean := make([]float64, D)
+    for i:=0; i<N; i++ {
+        for d:=0; d<D; d++ {
+            mean[d] += c.Samples.At(i, d) / float64(N)
+        }
+    }
+    return mean
+}
+
+
+/*
+SUMMARY
+    Computes the sample covariance of the draws.
+PARAMETERS
+    N/A
+RETURN
+    *mat.SymDense: the posterior covariance estimate
+*/
+func (c Chain) Covariance() *mat.SymDense {
+    N, D := c.Samples.Dims()
+    mean := c.Mean()
+    cov := mat.NewSymDense(D, nil)
+    for a:=0; a<D; a++ {
+        for b:=a; b<D; b++ {
+            sum := 0.0
+            for i:=0; i<N; i++ {
+                sum += (c.Samples.At(i, a) - mean[a]) * (c.Samples.At(i, b) - mean[b])
+            }
+            cov.SetSym(a, b, sum / float64(N-1))
+        }
+    }
+    return cov
+}
+
+
+/*
+SUMMARY
+    Dot product of two slices.
+PARAMETERS
+    a []float64: first slice
+    b []float64: second slice
+RETURN
+    float64: sum a_i b_i
+*/
+func dot(a, b []float64) float64 {
+    sum := 0.0
+    for i := range a { sum += a[i] * b[i] }
+    return sum
+}
+
+
+/*
+SUMMARY
+    Evaluates the log-density and turns NaN into -Inf so that such states are always rejected.
+PARAMETERS
+    T Target: the target distribution
+    X []float64: the position
+RETURN
+    float64: the log-density
+*/
+func safeLogDensity(T Target, X []float64) float64 {
+    value := T.LogDensity(X)
+    if math.IsNaN(value) {
+        return math.Inf(-1)
+    }
+    return value
+}
+
+
+/*
+This type implements the dual averaging step size adaptation of Nesterov as used in
+Hoffman & Gelman, The No-U-Turn Sampler, https://arxiv.org/pdf/1111.4246.pdf (Section 3.2).
+*/
+type dualAveraging struct {
+    mu float64
+    logStepSizeBar float64
+    hBar float64
+    t int
+    delta float64
+}
+
+
+/*
+SUMMARY
+    Creates the adaptation state.
+PARAMETERS
+    StepSize float64: the initial step size
+    Delta float64: the target mean acceptance probability
+RETURN
+    *dualAveraging: the adaptation state
+*/
+func newDualAveraging(StepSize, Delta float64) *dualAveraging {
+    return &dualAveraging{mu: math.Log(10.0 * StepSize), delta: Delta}
+}
+
+
+/*
+SUMMARY
+    One adaptation step with the usual constants gamma=0.05, t0=10 and kappa=0.75.
+PARAMETERS
+    AcceptStat float64: the mean acceptance probability of the last iteration
+RETURN
+    float64: the step size to use in the next iteration
+*/
+func (d *dualAveraging) update(AcceptStat float64) float64 {
+    const gamma, t0, kappa = 0.05, 10.0, 0.75
+    d.t++
+    t := float64(d.t)
+    eta := 1.0 / (t + t0)
+    d.hBar = (1 - eta) * d.hBar + eta * (d.delta - AcceptStat)
+    logStepSize := d.mu - math.Sqrt(t) / gamma * d.hBar
+    weight := math.Pow(t, -kappa)
+    d.logStepSizeBar = weight * logStepSize + (1 - weight) * d.logStepSizeBar
+    return math.Exp(logStepSize)
+}
+
+
+/*
+SUMMARY
+    The step size to use once the warm-up has finished.
+PARAMETERS
+    N/A
+RETURN
+    float64: the averaged step size
+*/
+func (d *dualAveraging) final() float64 {
+    return math.Exp(d.logStepSizeBar)
+}
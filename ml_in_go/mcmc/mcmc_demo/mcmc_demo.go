@@ -0,0 +1,219 @@
+package main
+
+import (
+    "fmt"
+    "math"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/stat/distuv"
+    "gonum.org/v1/plot"
+    "gonum.org/v1/plot/vg"
+
+    "ml_playground/kernels"
+    "ml_playground/mcmc"
+    "ml_playground/plt"
+)
+
+// random number seed and source
+var randSeed = 10
+var randSrc = rand.NewSource(uint64(randSeed))
+
+
+/*
+SUMMARY
+    The log-posterior of Bayesian linear regression with prior w ~ N(0,I) and noise precision Beta,
+    together with its gradient, and the exact Gaussian posterior for comparison.
+PARAMETERS
+    X *mat.Dense: N by 2 matrix, each row is (x, 1)
+    y *mat.Dense: N by 1 matrix of targets
+    Beta float64: the precision of the noise
+RETURN
+    mcmc.Target: the log-posterior and its gradient
+    *mat.VecDense: the exact posterior mean
+    *mat.Dense: the exact posterior covariance
+*/
+func LinearRegressionPosterior(X, y *mat.Dense, Beta float64) (mcmc.Target, *mat.VecDense, *mat.Dense) {
+    N, M := X.Dims()
+    residual := func (w []float64) *mat.VecDense {
+        r := mat.NewVecDense(N, nil)
+        r.MulVec(X, mat.NewVecDense(M, w))
+        r.SubVec(y.ColView(0), r)
+        return r
+    }
+    target := mcmc.Target{
+        LogDensity: func (w []float64) float64 {
+            r := residual(w)
+            wVec := mat.NewVecDense(M, w)
+            return -0.5 * mat.Dot(wVec, wVec) - 0.5 * Beta * mat.Dot(r, r)
+        },
+        Gradient: func (w []float64) []float64 {
+            r := residual(w)
+            grad := mat.NewVecDense(M, nil)
+            grad.MulVec(X.T(), r)
+            grad.ScaleVec(Beta, grad)
+            grad.SubVec(grad, mat.NewVecDense(M, w))
+            return grad.RawVector().Data
+        },
+    }
+    cov := mat.NewDense(M, M, nil)
+    cov.Mul(X.T(), X)
+    cov.Scale(Beta, cov)
+    for d:=0; d<M; d++ { cov.Set(d, d, cov.At(d, d) + 1.0) }
+    err := cov.Inverse(cov)
+    if err != nil { panic(err) }
+    mean := mat.NewVecDense(M, nil)
+    mean.MulVec(X.T(), y.ColView(0))
+    mean.MulVec(cov, mean)
+    mean.ScaleVec(Beta, mean)
+    return target, mean, cov
+}
+
+
+/*
+SUMMARY
+    The log-posterior of the RBF kernel hyperparameters (log varSigma, log lengthScale, log noise variance)
+    of a Gaussian process with standard normal priors on the logarithms.
+PARAMETERS
+    X *mat.Dense: column vector of inputs
+    Y *mat.Dense: column vector of observations
+RETURN
+    mcmc.Target: the log-posterior (no gradient, use it with Metropolis-Hastings)
+*/
+func GPHyperparameterPosterior(X, Y *mat.Dense) mcmc.Target {
+    N, _ := X.Dims()
+    return mcmc.Target{
+        LogDensity: func (theta []float64) float64 {
+            params := kernels.Parameters{Type: kernels.RBF, VarSigma: math.Exp(theta[0]), LengthScale: math.Exp(theta[1])}
+            K := kernels.Kernel(X, X, params)
+            for i:=0; i<N; i++ { K.Set(i, i, K.At(i, i) + math.Exp(theta[2])) }
+            var chol mat.Cholesky
+            if !chol.Factorize(mat.NewSymDense(N, K.RawMatrix().Data)) {
+                return math.Inf(-1)
+            }
+            alpha := mat.NewVecDense(N, nil)
+            err := chol.SolveVecTo(alpha, Y.ColView(0))
+            if err != nil { return math.Inf(-1) }
+            logLikelihood := -0.5 * mat.Dot(Y.ColView(0), alpha) - 0.5 * chol.LogDet()
+            logPrior := -0.5 * (theta[0]*theta[0] + theta[1]*theta[1] + theta[2]*theta[2])
+            return logLikelihood + logPrior
+        },
+    }
+}
+
+
+/*
+SUMMARY
+    Prints the convergence diagnostics of every parameter.
+PARAMETERS
+    Names []string: the names of the parameters
+    Chains []mcmc.Chain: the chains
+RETURN
+    N/A
+*/
+func PrintDiagnostics(Names []string, Chains []mcmc.Chain) {
+    fmt.Printf("    %-24s %9s %9s %9s %7s %9s %9s\n", "parameter", "mean", "sd", "mcse", "rhat", "bulk ess", "tail ess")
+    for d, s := range mcmc.Summarise(Chains) {
+        fmt.Printf("    %-24s %9.4f %9.4f %9.5f %7.4f %9.1f %9.1f\n", Names[d], s.Mean, s.SD, s.MCSE, s.RHat, s.BulkESS, s.TailESS)
+    }
+}
+
+
+/*
+SUMMARY
+    Saves the trace plot, the rank plot and the marginal histogram of one parameter.
+PARAMETERS
+    Draws [][]float64: chains by draws of the parameter
+    Name string: the file names start with it
+RETURN
+    N/A
+*/
+func SaveDiagnosticPlots(Draws [][]float64, Name string) {
+    pal := plt.DesignedPalette{Type: plt.KINDLMANN_PALETTE, Num: len(Draws) + 1}
+    p := plot.New()
+    p.Title.Text = "Trace"
+    for _, line := range plt.MakeTraceLines(Draws, 0.5, pal) { p.Add(line) }
+    p.X.Label.Text = "iteration"
+    if err := p.Save(6*vg.Inch, 3*vg.Inch, Name + "_trace.svg"); err != nil { panic(err) }
+
+    p = plot.New()
+    p.Title.Text = "Rank histogram per chain"
+    for _, line := range plt.MakeRankLines(Draws, 20, 1.5, pal) { p.Add(line) }
+    p.X.Label.Text = "rank bin"
+    if err := p.Save(4*vg.Inch, 3*vg.Inch, Name + "_rank.svg"); err != nil { panic(err) }
+
+    p = plot.New()
+    p.Title.Text = "Marginal posterior"
+    p.Add(plt.MakeMarginalHistogram(Draws, 40, 0x4477aaff))
+    if err := p.Save(4*vg.Inch, 3*vg.Inch, Name + "_marginal.svg"); err != nil { panic(err) }
+}
+
+
+/*
+We sample the posterior of the Bayesian linear regression model with Metropolis-Hastings, HMC and NUTS
+and compare the estimates with the exact posterior. Four NUTS chains run in parallel to check convergence
+with R-hat, effective sample sizes and autocorrelations. Then we sample the hyperparameters of a Gaussian
+process instead of computing point estimates.
+*/
+func main() {
+    N := 50
+    beta := 1 / 0.3
+    X := mat.NewDense(N, 2, nil)
+    for pt:=0; pt<N; pt++ {
+        X.Set(pt, 0, -1.0 + float64(pt)/float64(N) * 2.0)
+        X.Set(pt, 1, 1.0)
+    }
+    y := mat.NewDense(N, 1, nil)
+    y.Mul(X, mat.NewDense(2, 1, []float64{-1.3, 0.5}))
+    normal := distuv.Normal{Mu: 0.0, Sigma: 1 / math.Sqrt(beta), Src: randSrc}
+    y.Apply(func (j, i int, v float64) float64 { return v + normal.Rand() }, y)
+
+    target, mean, cov := LinearRegressionPosterior(X, y, beta)
+    fmt.Println("Exact posterior mean", mean.RawVector().Data)
+    fmt.Printf("Exact posterior covariance\n%.5f\n", mat.Formatted(cov))
+
+    start := []float64{0.0, 0.0}
+    chains := map[string]mcmc.Chain{
+        "Metropolis-Hastings": mcmc.MetropolisHastings(target, start, mcmc.MetropolisOptions{NumSamples: 20000, Warmup: 2000, ProposalScale: 0.1, Adapt: true, Seed: randSeed}),
+        "HMC": mcmc.HMC(target, start, mcmc.HMCOptions{NumSamples: 5000, Warmup: 500, NumLeapfrog: 10, Adapt: true, Seed: randSeed}),
+        "NUTS": mcmc.NUTS(target, start, mcmc.HMCOptions{NumSamples: 5000, Warmup: 500, Adapt: true, Seed: randSeed}),
+    }
+    for _, name := range []string{"Metropolis-Hastings", "HMC", "NUTS"} {
+        chain := chains[name]
+        fmt.Println(name, "acceptance", fmt.Sprintf("%.3f", chain.AcceptanceRate), "step size", fmt.Sprintf("%.4f", chain.StepSize))
+        fmt.Println("    mean", chain.Mean())
+        fmt.Printf("    covariance\n    %.5f\n", mat.Formatted(chain.Covariance(), mat.Prefix("    ")))
+    }
+
+    chainsNUTS := mcmc.RunChains(4, func (c int) mcmc.Chain {
+        // overdispersed starting points make R-hat meaningful
+        start := []float64{-3.0 + 2.0*float64(c), 3.0 - 2.0*float64(c)}
+        return mcmc.NUTS(target, start, mcmc.HMCOptions{NumSamples: 2000, Warmup: 500, Adapt: true, Seed: randSeed + c})
+    })
+    fmt.Println("Four parallel NUTS chains")
+    PrintDiagnostics([]string{"slope", "intercept"}, chainsNUTS)
+    fmt.Printf("    autocorrelation of the slope (lags 0-5) %.3f\n", mcmc.Autocorrelation(mcmc.Draws(chainsNUTS, 0)[0], 5))
+    chainsMH := mcmc.RunChains(4, func (c int) mcmc.Chain {
+        return mcmc.MetropolisHastings(target, []float64{-3.0 + 2.0*float64(c), 0.0},
+                                       mcmc.MetropolisOptions{NumSamples: 2000, Warmup: 500, ProposalScale: 0.1, Adapt: true, Seed: randSeed + c})
+    })
+    fmt.Println("Four parallel Metropolis-Hastings chains")
+    PrintDiagnostics([]string{"slope", "intercept"}, chainsMH)
+    fmt.Printf("    autocorrelation of the slope (lags 0-5) %.3f\n", mcmc.Autocorrelation(mcmc.Draws(chainsMH, 0)[0], 5))
+    SaveDiagnosticPlots(mcmc.Draws(chainsNUTS, 0), "nuts_slope")
+    SaveDiagnosticPlots(mcmc.Draws(chainsMH, 0), "mh_slope")
+
+    XGP := mat.NewDense(20, 1, nil)
+    YGP := mat.NewDense(20, 1, nil)
+    for i:=0; i<20; i++ {
+        x := -3.0 + 6.0 * float64(i) / 20.0
+        XGP.Set(i, 0, x)
+        YGP.Set(i, 0, math.Sin(2.0 * x) + 0.1 * normal.Rand())
+    }
+    chainsGP := mcmc.RunChains(4, func (c int) mcmc.Chain {
+        return mcmc.MetropolisHastings(GPHyperparameterPosterior(XGP, YGP), []float64{0.0, 0.0, -1.0},
+                                       mcmc.MetropolisOptions{NumSamples: 10000, Warmup: 2000, ProposalScale: 0.2, Adapt: true, Seed: randSeed + c})
+    })
+    fmt.Println("GP hyperparameters, acceptance", fmt.Sprintf("%.3f", chainsGP[0].AcceptanceRate))
+    PrintDiagnostics([]string{"log(varSigma)", "log(lengthScale)", "log(noise variance)"}, chainsGP)
+}
@@ -0,0 +1,66 @@
+package mcmc
+
+import (
+    "math"
+    "testing"
+)
+
+
+/*
+SUMMARY
+    A correlated two dimensional Gaussian target with mean (1, -2), variances 1 and 4 and correlation 0.5.
+PARAMETERS
+    N/A
+RETURN
+    Target: the target
+    []float64: the mean
+    [][]float64: the covariance
+*/
+func gaussianTarget() (Target, []float64, [][]float64) {
+    mean := []float64{1.0, -2.0}
+    covariance := [][]float64{{1.0, 1.0}, {1.0, 4.0}}
+    // the inverse of the covariance
+    det := covariance[0][0] * covariance[1][1] - covariance[0][1] * covariance[1][0]
+    precision := [][]float64{{covariance[1][1] / det, -covariance[0][1] / det}, {-covariance[1][0] / det, covariance[0][0] / det}}
+    gradient := func (X []float64) []float64 {
+        grad := make([]float64, 2)
+        for a:=0; a<2; a++ {
+            for b:=0; b<2; b++ { grad[a] -= precision[a][b] * (X[b] - mean[b]) }
+        }
+        return grad
+    }
+    logDensity := func (X []float64) float64 {
+        grad := gradient(X)
+        return 0.5 * ((X[0] - mean[0]) * grad[0] + (X[1] - mean[1]) * grad[1])
+    }
+    return Target{LogDensity: logDensity, Gradient: gradient}, mean, covariance
+}
+
+
+func TestSamplersMatchGaussian(t *testing.T) {
+    target, mean, covariance := gaussianTarget()
+    start := []float64{0.0, 0.0}
+    chains := []struct {
+        name string
+        chain Chain
+    }{
+        {name: "Metropolis-Hastings", chain: MetropolisHastings(target, start, MetropolisOptions{NumSamples: 40000, Warmup: 2000, ProposalScale: 0.5, Adapt: true, Seed: 1})},
+        {name: "HMC", chain: HMC(target, start, HMCOptions{NumSamples: 5000, Warmup: 500, NumLeapfrog: 10, Adapt: true, Seed: 2})},
+        {name: "NUTS", chain: NUTS(target, start, HMCOptions{NumSamples: 5000, Warmup: 500, Adapt: true, Seed: 3})},
+    }
+    for _, c := range chains {
+        chainMean, chainCovariance := c.chain.Mean(), c.chain.Covariance()
+        for a:=0; a<2; a++ {
+            sd := math.Sqrt(covariance[a][a])
+            if math.Abs(chainMean[a] - mean[a]) > 0.1 * sd {
+                t.Errorf("%s: mean %d is %g instead of %g", c.name, a, chainMean[a], mean[a])
+            }
+            for b:=0; b<2; b++ {
+                if math.Abs(chainCovariance.At(a, b) - covariance[a][b]) > 0.1 * sd * math.Sqrt(covariance[b][b]) {
+                    t.Errorf("%s: covariance (%d,%d) is %g instead of %g", c.name, a, b, chainCovariance.At(a, b), covariance[a][b])
+                }
+            }
+        }
+        if c.chain.Divergences > 0 { t.Errorf("%s: %d divergent trajectories", c.name, c.chain.Divergences) }
+    }
+}
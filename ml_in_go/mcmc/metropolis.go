@@ -0,0 +1,108 @@
+package mcmc
+
+import (
+    "math"
+    "golang.org/x/exp/rand"
+    "gonum.org/v1/gonum/mat"
+)
+
+
+/*
+This type configures the Metropolis-Hastings sampler.
+    NumSamples int: the number of draws kept
+    Warmup int: the number of draws discarded at the beginning, the proposal adapts during these
+    ProposalScale float64: the initial standard deviation of the Gaussian proposal
+    Adapt bool: whether to adapt the proposal covariance and scale during warm-up
+    TargetAcceptance float64: the acceptance rate the adaptation aims at (0.234 if 0)
+    Seed int: seed of the random number generator
+*/
+type MetropolisOptions struct {
+    NumSamples int
+    Warmup int
+    ProposalScale float64
+    Adapt bool
+    TargetAcceptance float64
+    Seed int
+}
+
+
+/*
+SUMMARY
+    Random-walk Metropolis-Hastings with a Gaussian proposal x' = x + lambda*L*z, z ~ N(0,I). With adaptation
+    switched on, the proposal follows Andrieu & Thoms, A tutorial on adaptive MCMC (Algorithm 4): during warm-up
+    LL^T tracks the covariance of the chain and log(lambda) is pushed towards the target acceptance rate.
+    After warm-up the proposal is frozen so the kept draws come from a proper Markov chain.
+PARAMETERS
+    T Target: the distribution, only LogDensity is used
+    Start []float64: the initial position
+    Options MetropolisOptions: the settings of the sampler
+RETURN
+    Chain: the draws after warm-up
+*/
+func MetropolisHastings(T Target, Start []float64, Options MetropolisOptions) Chain {
+    if Options.NumSamples <= 0 { panic("Negative/0 number of samples encountered") }
+    if Options.ProposalScale <= 0 { panic("Negative/0 proposal scale encountered") }
+    target := Options.TargetAcceptance
+    if target == 0 { target = 0.234 }
+    D := len(Start)
+    randGen := rand.New(rand.NewSource(uint64(Options.Seed)))
+
+    X := make([]float64, D)
+    copy(X, Start)
+    logP := safeLogDensity(T, X)
+    lambda := Options.ProposalScale
+    mean := make([]float64, D)
+    copy(mean, X)
+    cov := mat.NewSymDense(D, nil)
+    for d:=0; d<D; d++ { cov.SetSym(d, d, 1.0) }
+    var chol mat.Cholesky
+    chol.Factorize(cov)
+    L := mat.NewTriDense(D, mat.Lower, nil)
+    chol.LTo(L)
+
+    chain := Chain{Samples: mat.NewDense(Options.NumSamples, D, nil), LogDensities: make([]float64, Options.NumSamples)}
+    z := mat.NewVecDense(D, nil)
+    step := mat.NewVecDense(D, nil)
+    Proposal := make([]float64, D)
+    for iter:=0; iter<Options.Warmup+Options.NumSamples; iter++ {
+        for d:=0; d<D; d++ { z.SetVec(d, randGen.NormFloat64()) }
+        step.MulVec(L, z)
+        for d:=0; d<D; d++ { Proposal[d] = X[d] + lambda * step.AtVec(d) }
+        logPProposal := safeLogDensity(T, Proposal)
+        acceptProb := 0.0
+        if !math.IsInf(logPProposal, -1) {
+            acceptProb = math.Min(1.0, math.Exp(logPProposal - logP))
+        }
+        if randGen.Float64() < acceptProb {
+            copy(X, Proposal)
+            logP = logPProposal
+        }
+
+        if iter < Options.Warmup && Options.Adapt {
+            gamma := math.Pow(float64(iter + 2), -0.6)
+            lambda *= math.Exp(gamma * (acceptProb - target))
+            for d:=0; d<D; d++ { mean[d] += gamma * (X[d] - mean[d]) }
+            for a:=0; a<D; a++ {
+                for b:=a; b<D; b++ {
+                    cov.SetSym(a, b, cov.At(a, b) + gamma * ((X[a]-mean[a])*(X[b]-mean[b]) - cov.At(a, b)))
+                }
+            }
+            // a little jitter keeps the covariance positive definite
+            jittered := mat.NewSymDense(D, nil)
+            jittered.CopySym(cov)
+            for d:=0; d<D; d++ { jittered.SetSym(d, d, jittered.At(d, d) + 1e-10) }
+            if chol.Factorize(jittered) {
+                chol.LTo(L)
+            }
+        }
+
+        if iter >= Options.Warmup {
+            i := iter - Options.Warmup
+            chain.Samples.SetRow(i, X)
+            chain.LogDensities[i] = logP
+            chain.AcceptanceRate += acceptProb / float64(Options.NumSamples)
+        }
+    }
+    chain.StepSize = lambda
+    return chain
+}
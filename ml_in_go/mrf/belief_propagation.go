@@ -0,0 +1,363 @@
+package mrf
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/floats"
+    "gonum.org/v1/gonum/mat"
+
+    "ml_playground/utils"
+)
+
+
+/*
+This type is a pairwise Markov random field with K labels on a lattice, written with log-potentials:
+    log p(X) = \sum_i Unary_{i,X_i} + \sum_{i~j} Pairwise_{X_i,X_j} - log Z
+Every edge shares the same pairwise log-potential, which must be symmetric.
+    Lattice Lattice: the grid of pixels
+    NumLabels int: the number of labels K
+    Unary *mat.Dense: N by K matrix of unary log-potentials
+    Pairwise *mat.Dense: K by K matrix of pairwise log-potentials
+*/
+type PairwiseMRF struct {
+    Lattice Lattice
+    NumLabels int
+    Unary *mat.Dense
+    Pairwise *mat.Dense
+}
+
+
+/*
+This type configures loopy belief propagation.
+    MaxIterations int: the maximal number of parallel message updates
+    Damping float64: in [0,1), each message becomes Damping*old + (1-Damping)*new (in the log domain)
+    Tolerance float64: the messages converged when no log-message changed more than this
+    MaxProduct bool: max-product (MAP) instead of sum-product (marginals)
+*/
+type BPOptions struct {
+    MaxIterations int
+    Damping float64
+    Tolerance float64
+    MaxProduct bool
+}
+
+
+/*
+This type stores the outcome of a message passing algorithm.
+    Beliefs *mat.Dense: N by K matrix, row i is the (max-)marginal of pixel i, normalised to sum to 1
+    Labels []int: the most probable label of each pixel
+    Iterations int: the number of iterations run
+    Converged bool: whether the messages converged before MaxIterations
+*/
+type BPResult struct {
+    Beliefs *mat.Dense
+    Labels []int
+    Iterations int
+    Converged bool
+}
+
+
+/*
+SUMMARY
+    Writes the Ising model as a pairwise MRF, label 0 is -1 and label 1 is +1.
+PARAMETERS
+    N/A
+RETURN
+    PairwiseMRF: the same distribution
+*/
+func (m IsingModel) PairwiseMRF() PairwiseMRF {
+    N := m.Lattice.Size()
+    unary := mat.NewDense(N, 2, nil)
+    for i:=0; i<N; i++ {
+        unary.Set(i, 0, m.LogLikelihood(i, -1.0))
+        unary.Set(i, 1, m.LogLikelihood(i, 1.0))
+    }
+    pairwise := mat.NewDense(2, 2, []float64{m.Coupling, -m.Coupling, -m.Coupling, m.Coupling})
+    return PairwiseMRF{Lattice: m.Lattice, NumLabels: 2, Unary: unary, Pairwise: pairwise}
+}
+
+
+/*
+SUMMARY
+    Writes the Potts model as a pairwise MRF.
+PARAMETERS
+    N/A
+RETURN
+    PairwiseMRF: the same distribution
+*/
+func (m PottsModel) PairwiseMRF() PairwiseMRF {
+    N := m.Lattice.Size()
+    unary := mat.NewDense(N, m.NumLabels, nil)
+    for i:=0; i<N; i++ {
+        for k:=0; k<m.NumLabels; k++ {
+            unary.Set(i, k, m.LogLikelihood(i, k))
+        }
+    }
+    pairwise := mat.NewDense(m.NumLabels, m.NumLabels, nil)
+    for k:=0; k<m.NumLabels; k++ {
+        pairwise.Set(k, k, m.Coupling)
+    }
+    return PairwiseMRF{Lattice: m.Lattice, NumLabels: m.NumLabels, Unary: unary, Pairwise: pairwise}
+}
+
+
+/*
+SUMMARY
+    Evaluates the energy -log p(X) - log Z of a labelling.
+PARAMETERS
+    Labels []int: the label of each pixel
+RETURN
+    float64: the energy
+*/
+func (m PairwiseMRF) Energy(Labels []int) float64 {
+    energy := 0.0
+    for i, label := range Labels {
+        energy -= m.Unary.At(i, label)
+        c := m.Lattice.Coord(i)
+        for _, neighbour := range m.Lattice.Neighbours(c.Y, c.X) {
+            if j := m.Lattice.Index(neighbour); j > i {
+                energy -= m.Pairwise.At(label, Labels[j])
+            }
+        }
+    }
+    return energy
+}
+
+
+/*
+This type indexes the directed edges of the lattice, message e goes from Source[e] to Target[e]
+and Reverse[e] is the message in the opposite direction.
+*/
+type edgeIndex struct {
+    Source []int
+    Target []int
+    Reverse []int
+    Incoming [][]int
+}
+
+
+/*
+SUMMARY
+    Enumerates the directed edges of the lattice.
+PARAMETERS
+    L Lattice: the lattice
+RETURN
+    edgeIndex: the edges
+*/
+func newEdgeIndex(L Lattice) edgeIndex {
+    N := L.Size()
+    index := edgeIndex{Incoming: make([][]int, N)}
+    ids := make(map[[2]int]int)
+    for i:=0; i<N; i++ {
+        c := L.Coord(i)
+        for _, neighbour := range L.Neighbours(c.Y, c.X) {
+            j := L.Index(neighbour)
+            ids[[2]int{i, j}] = len(index.Source)
+            index.Source = append(index.Source, i)
+            index.Target = append(index.Target, j)
+        }
+    }
+    index.Reverse = make([]int, len(index.Source))
+    for e := range index.Source {
+        index.Reverse[e] = ids[[2]int{index.Target[e], index.Source[e]}]
+        index.Incoming[index.Target[e]] = append(index.Incoming[index.Target[e]], e)
+    }
+    return index
+}
+
+
+/*
+SUMMARY
+    Loopy belief propagation with parallel (flooding) message updates in the log domain.
+    Sum-product gives approximate marginals, max-product gives approximate max-marginals whose
+    argmax is an approximate MAP labelling. On loopy graphs the messages may oscillate,
+    damping usually fixes it.
+PARAMETERS
+    Options BPOptions: the settings
+RETURN
+    BPResult: the beliefs and labels
+*/
+func (m PairwiseMRF) BeliefPropagation(Options BPOptions) BPResult {
+    if Options.Damping < 0 || Options.Damping >= 1 { panic("Damping outside [0,1) encountered") }
+    K := m.NumLabels
+    edges := newEdgeIndex(m.Lattice)
+    messages := mat.NewDense(len(edges.Source), K, nil)
+    newMessages := mat.NewDense(len(edges.Source), K, nil)
+    beliefs := mat.NewDense(m.Lattice.Size(), K, nil)
+    result := BPResult{}
+    expPairwise := mat.NewDense(K, K, nil)
+    expPairwise.Apply(func (a, b int, v float64) float64 { return math.Exp(v) }, m.Pairwise)
+
+    collect := func (i int) {
+        // log-belief of pixel i: the unary potential plus every incoming message
+        row := beliefs.RawRowView(i)
+        copy(row, m.Unary.RawRowView(i))
+        for _, e := range edges.Incoming[i] {
+            floats.Add(row, messages.RawRowView(e))
+        }
+    }
+    terms := make([]float64, K)
+    cavity := make([]float64, K)
+    for result.Iterations < Options.MaxIterations {
+        result.Iterations++
+        for i := range edges.Incoming { collect(i) }
+        change := 0.0
+        for e := range edges.Source {
+            // the belief of the source without the message coming back from the target
+            floats.SubTo(cavity, beliefs.RawRowView(edges.Source[e]), messages.RawRowView(edges.Reverse[e]))
+            message := newMessages.RawRowView(e)
+            if Options.MaxProduct {
+                for b:=0; b<K; b++ {
+                    for a:=0; a<K; a++ { terms[a] = cavity[a] + m.Pairwise.At(a, b) }
+                    message[b] = floats.Max(terms)
+                }
+                floats.AddConst(-floats.Max(message), message)
+            } else {
+                // the sum runs in the probability domain, shifted to avoid overflow
+                shift := floats.Max(cavity)
+                for a:=0; a<K; a++ { terms[a] = math.Exp(cavity[a] - shift) }
+                total := 0.0
+                for b:=0; b<K; b++ {
+                    message[b] = 0.0
+                    for a:=0; a<K; a++ { message[b] += terms[a] * expPairwise.At(a, b) }
+                    total += message[b]
+                }
+                for b:=0; b<K; b++ { message[b] = math.Log(message[b] / total) }
+            }
+            old := messages.RawRowView(e)
+            for b := range message {
+                message[b] = Options.Damping * old[b] + (1 - Options.Damping) * message[b]
+                change = math.Max(change, math.Abs(message[b] - old[b]))
+            }
+        }
+        messages, newMessages = newMessages, messages
+        if change < Options.Tolerance {
+            result.Converged = true
+            break
+        }
+    }
+    for i := range edges.Incoming { collect(i) }
+    result.Beliefs, result.Labels = normaliseBeliefs(beliefs)
+    return result
+}
+
+
+/*
+SUMMARY
+    Turns log-beliefs into normalised beliefs in place and finds the most probable labels.
+PARAMETERS
+    LogBeliefs *mat.Dense: N by K matrix of unnormalised log-beliefs
+RETURN
+    *mat.Dense: the normalised beliefs (the same matrix)
+    []int: the argmax of each row
+*/
+func normaliseBeliefs(LogBeliefs *mat.Dense) (*mat.Dense, []int) {
+    N, _ := LogBeliefs.Dims()
+    labels := make([]int, N)
+    for i:=0; i<N; i++ {
+        row := LogBeliefs.RawRowView(i)
+        labels[i] = utils.Argmax(row)
+        floats.AddConst(-floats.LogSumExp(row), row)
+        for k := range row { row[k] = math.Exp(row[k]) }
+    }
+    return LogBeliefs, labels
+}
+
+
+/*
+SUMMARY
+    Sequential tree-reweighted message passing (TRW-S) of Kolmogorov, Convergent Tree-Reweighted
+    Message Passing for Energy Minimization, https://doi.org/10.1109/TPAMI.2006.200
+    The pixels are visited in raster order, forwards then backwards; the lattice is covered by monotonic
+    chains, so pixel s gets the weight 1/n_s where n_s is the larger of its numbers of earlier and later
+    neighbours. Unlike max-product belief propagation the lower bound on the energy never decreases.
+PARAMETERS
+    MaxIterations int: the maximal number of forward-backward passes
+    Tolerance float64: the messages converged when no message changed more than this
+RETURN
+    BPResult: the labels and the normalised min-marginals as beliefs
+*/
+func (m PairwiseMRF) TRWS(MaxIterations int, Tolerance float64) BPResult {
+    K := m.NumLabels
+    N := m.Lattice.Size()
+    edges := newEdgeIndex(m.Lattice)
+    // messages in the energy domain, M[e] is the message from Source[e] to Target[e]
+    messages := mat.NewDense(len(edges.Source), K, nil)
+    outgoing := make([][]int, N)
+    gamma := make([]float64, N)
+    for i := range outgoing {
+        earlier, later := 0, 0
+        for _, e := range edges.Incoming[i] {
+            outgoing[i] = append(outgoing[i], edges.Reverse[e])
+            if edges.Source[e] < i { earlier++ } else { later++ }
+        }
+        gamma[i] = 1.0 / math.Max(1.0, math.Max(float64(earlier), float64(later)))
+    }
+
+    theta := make([]float64, K)
+    terms := make([]float64, K)
+    message := make([]float64, K)
+    collect := func (i int) {
+        for k := range theta { theta[k] = -m.Unary.At(i, k) }
+        for _, e := range edges.Incoming[i] {
+            floats.Add(theta, messages.RawRowView(e))
+        }
+    }
+    pass := func (i int, Forward bool) float64 {
+        change := 0.0
+        collect(i)
+        for _, e := range outgoing[i] {
+            j := edges.Target[e]
+            if (j > i) != Forward { continue }
+            reverse := messages.RawRowView(edges.Reverse[e])
+            for b:=0; b<K; b++ {
+                for a:=0; a<K; a++ { terms[a] = gamma[i] * theta[a] - reverse[a] - m.Pairwise.At(a, b) }
+                message[b] = floats.Min(terms)
+            }
+            floats.AddConst(-floats.Min(message), message)
+            old := messages.RawRowView(e)
+            for b := range message {
+                change = math.Max(change, math.Abs(message[b] - old[b]))
+            }
+            copy(old, message)
+        }
+        return change
+    }
+
+    result := BPResult{}
+    for result.Iterations < MaxIterations {
+        result.Iterations++
+        change := 0.0
+        for i:=0; i<N; i++ { change = math.Max(change, pass(i, true)) }
+        for i:=N-1; i>=0; i-- { change = math.Max(change, pass(i, false)) }
+        if change < Tolerance {
+            result.Converged = true
+            break
+        }
+    }
+
+    // labels are fixed in raster order: the earlier neighbours enter through their labels,
+    // the later ones through their messages
+    beliefs := mat.NewDense(N, K, nil)
+    labels := make([]int, N)
+    for i:=0; i<N; i++ {
+        collect(i)
+        row := beliefs.RawRowView(i)
+        for k := range row { row[k] = -theta[k] }
+        copy(terms, m.Unary.RawRowView(i))
+        for _, e := range edges.Incoming[i] {
+            source := edges.Source[e]
+            for k := range terms {
+                if source < i {
+                    terms[k] += m.Pairwise.At(labels[source], k)
+                } else {
+                    terms[k] -= messages.At(e, k)
+                }
+            }
+        }
+        labels[i] = utils.Argmax(terms)
+    }
+    result.Beliefs, _ = normaliseBeliefs(beliefs)
+    result.Labels = labels
+    return result
+}
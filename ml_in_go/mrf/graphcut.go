@@ -0,0 +1,480 @@
+package mrf
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/mat"
+)
+
+
+// special values of the parent arc of a node in the search trees
+const noParent = -1
+const terminalParent = -2
+const orphanParent = -3
+
+
+/*
+This type is a directed graph with a source and a sink terminal for computing the minimum s-t cut
+with the max-flow algorithm of Boykov & Kolmogorov, An Experimental Comparison of Min-Cut/Max-Flow
+Algorithms for Energy Minimization in Vision, https://doi.org/10.1109/TPAMI.2004.60
+Arcs are stored in pairs, arc a^1 is the reverse of arc a.
+*/
+type Graph struct {
+    // per node
+    first []int
+    terminalCap []float64
+    parent []int
+    inSinkTree []bool
+    active []bool
+    timestamp []int
+    dist []int
+    // per arc
+    head []int
+    next []int
+    residual []float64
+    // the flow through the graph
+    flow float64
+}
+
+
+/*
+SUMMARY
+    Creates a graph without edges.
+PARAMETERS
+    NumNodes int: the number of non-terminal nodes
+RETURN
+    *Graph: the graph
+*/
+func NewGraph(NumNodes int) *Graph {
+    g := &Graph{
+        first: make([]int, NumNodes),
+        terminalCap: make([]float64, NumNodes),
+        parent: make([]int, NumNodes),
+        inSinkTree: make([]bool, NumNodes),
+        active: make([]bool, NumNodes),
+        timestamp: make([]int, NumNodes),
+        dist: make([]int, NumNodes),
+    }
+    for i := range g.first { g.first[i] = -1 }
+    return g
+}
+
+
+/*
+SUMMARY
+    Adds the edges source->I and I->sink. Only the difference of the capacities matters for the cut,
+    the smaller one is pushed through straight away.
+PARAMETERS
+    I int: the node
+    SourceCap float64: capacity of the edge from the source
+    SinkCap float64: capacity of the edge to the sink
+RETURN
+    N/A
+*/
+func (g *Graph) AddTerminalWeights(I int, SourceCap, SinkCap float64) {
+    g.flow += math.Min(SourceCap, SinkCap)
+    g.terminalCap[I] += SourceCap - SinkCap
+}
+
+
+/*
+SUMMARY
+    Adds the edges I->J and J->I.
+PARAMETERS
+    I int: the first node
+    J int: the second node
+    Cap float64: capacity of I->J
+    RevCap float64: capacity of J->I
+RETURN
+    N/A
+*/
+func (g *Graph) AddEdge(I, J int, Cap, RevCap float64) {
+    if Cap < 0 || RevCap < 0 { panic("Negative capacity encountered") }
+    a := len(g.head)
+    g.head = append(g.head, J, I)
+    g.residual = append(g.residual, Cap, RevCap)
+    g.next = append(g.next, g.first[I], g.first[J])
+    g.first[I], g.first[J] = a, a + 1
+}
+
+
+/*
+SUMMARY
+    Adds a unary energy term of a binary variable, label 0 means the source segment, label 1 the sink segment.
+PARAMETERS
+    I int: the node
+    E0 float64: the energy when the node takes label 0
+    E1 float64: the energy when the node takes label 1
+RETURN
+    N/A
+*/
+func (g *Graph) AddUnaryTerm(I int, E0, E1 float64) {
+    if E1 > E0 {
+        g.AddTerminalWeights(I, E1 - E0, 0)
+    } else {
+        g.AddTerminalWeights(I, 0, E0 - E1)
+    }
+}
+
+
+/*
+SUMMARY
+    Adds a pairwise energy term of two binary variables with the construction of Kolmogorov & Zabih,
+    What Energy Functions can be Minimized via Graph Cuts?, https://doi.org/10.1109/TPAMI.2004.1262177
+    The term must be submodular: E00 + E11 <= E01 + E10.
+PARAMETERS
+    I int: the first node
+    J int: the second node
+    E00, E01, E10, E11 float64: the energies of the label pairs (label of I, label of J)
+RETURN
+    N/A
+*/
+func (g *Graph) AddPairwiseTerm(I, J int, E00, E01, E10, E11 float64) {
+    c := E01 + E10 - E00 - E11
+    if c < -1e-12 { panic("Non-submodular pairwise term encountered") }
+    g.AddUnaryTerm(I, 0, E10 - E00)
+    g.AddUnaryTerm(J, 0, E11 - E10)
+    g.AddEdge(I, J, math.Max(c, 0), 0)
+}
+
+
+/*
+SUMMARY
+    Computes the maximum flow from the source to the sink, which equals the minimal cut.
+    Call it once, after every edge is added.
+PARAMETERS
+    N/A
+RETURN
+    float64: the value of the flow (the minimal energy up to the constants dropped by AddUnaryTerm)
+*/
+func (g *Graph) MaxFlow() float64 {
+    var queue []int
+    setActive := func (i int) {
+        if !g.active[i] {
+            g.active[i] = true
+            queue = append(queue, i)
+        }
+    }
+    for i := range g.first {
+        g.parent[i] = noParent
+        if g.terminalCap[i] != 0 {
+            g.inSinkTree[i] = g.terminalCap[i] < 0
+            g.parent[i] = terminalParent
+            g.dist[i] = 1
+            setActive(i)
+        }
+    }
+
+    time := 0
+    current := -1
+    var orphans []int
+    for {
+        i := current
+        if i < 0 || g.parent[i] == noParent {
+            i = -1
+            for len(queue) > 0 {
+                candidate := queue[0]
+                queue = queue[1:]
+                g.active[candidate] = false
+                if g.parent[candidate] != noParent {
+                    i = candidate
+                    break
+                }
+            }
+            if i < 0 { break }
+        }
+
+        // growth stage
+        path := -1
+        for a:=g.first[i]; a>=0; a=g.next[a] {
+            j := g.head[a]
+            if !g.inSinkTree[i] && g.residual[a] > 0 {
+                if g.parent[j] == noParent {
+                    g.inSinkTree[j], g.parent[j] = false, a ^ 1
+                    g.timestamp[j], g.dist[j] = g.timestamp[i], g.dist[i] + 1
+                    setActive(j)
+                } else if g.inSinkTree[j] {
+                    path = a
+                    break
+                }
+            } else if g.inSinkTree[i] && g.residual[a ^ 1] > 0 {
+                if g.parent[j] == noParent {
+                    g.inSinkTree[j], g.parent[j] = true, a ^ 1
+                    g.timestamp[j], g.dist[j] = g.timestamp[i], g.dist[i] + 1
+                    setActive(j)
+                } else if !g.inSinkTree[j] {
+                    path = a ^ 1
+                    break
+                }
+            }
+        }
+
+        time++
+        if path < 0 {
+            current = -1
+            continue
+        }
+        // the node may have more paths, we continue from it
+        current = i
+        orphans = g.augment(path, orphans[:0])
+
+        // adoption stage
+        for len(orphans) > 0 {
+            orphan := orphans[0]
+            orphans = orphans[1:]
+            orphans = g.adopt(orphan, time, orphans, setActive)
+        }
+    }
+    return g.flow
+}
+
+
+/*
+SUMMARY
+    Pushes the bottleneck flow through the path source -> ... -> tail(Middle) -> head(Middle) -> ... -> sink.
+PARAMETERS
+    Middle int: the arc connecting the source tree to the sink tree
+    Orphans []int: the slice to append the new orphans to
+RETURN
+    []int: the nodes that lost their parent
+*/
+func (g *Graph) augment(Middle int, Orphans []int) []int {
+    bottleneck := g.residual[Middle]
+    for i:=g.head[Middle ^ 1]; ; {
+        a := g.parent[i]
+        if a == terminalParent {
+            bottleneck = math.Min(bottleneck, g.terminalCap[i])
+            break
+        }
+        bottleneck = math.Min(bottleneck, g.residual[a ^ 1])
+        i = g.head[a]
+    }
+    for i:=g.head[Middle]; ; {
+        a := g.parent[i]
+        if a == terminalParent {
+            bottleneck = math.Min(bottleneck, -g.terminalCap[i])
+            break
+        }
+        bottleneck = math.Min(bottleneck, g.residual[a])
+        i = g.head[a]
+    }
+
+    g.residual[Middle] -= bottleneck
+    g.residual[Middle ^ 1] += bottleneck
+    for i:=g.head[Middle ^ 1]; ; {
+        a := g.parent[i]
+        if a == terminalParent {
+            g.terminalCap[i] -= bottleneck
+            if g.terminalCap[i] == 0 {
+                g.parent[i] = orphanParent
+                Orphans = append(Orphans, i)
+            }
+            break
+        }
+        g.residual[a] += bottleneck
+        g.residual[a ^ 1] -= bottleneck
+        if g.residual[a ^ 1] == 0 {
+            g.parent[i] = orphanParent
+            Orphans = append(Orphans, i)
+        }
+        i = g.head[a]
+    }
+    for i:=g.head[Middle]; ; {
+        a := g.parent[i]
+        if a == terminalParent {
+            g.terminalCap[i] += bottleneck
+            if g.terminalCap[i] == 0 {
+                g.parent[i] = orphanParent
+                Orphans = append(Orphans, i)
+            }
+            break
+        }
+        g.residual[a ^ 1] += bottleneck
+        g.residual[a] -= bottleneck
+        if g.residual[a] == 0 {
+            g.parent[i] = orphanParent
+            Orphans = append(Orphans, i)
+        }
+        i = g.head[a]
+    }
+    g.flow += bottleneck
+    return Orphans
+}
+
+
+/*
+SUMMARY
+    Looks for a new parent of an orphan in its own tree, among the neighbours that are connected to
+    a terminal. The closest such neighbour is chosen. Without one the orphan becomes free and its
+    children become orphans.
+PARAMETERS
+    I int: the orphan
+    Time int: the current time stamp, it marks the nodes known to be connected to a terminal
+    Orphans []int: the queue of orphans
+    SetActive func(int): marks a node active
+RETURN
+    []int: the queue of orphans
+*/
+func (g *Graph) adopt(I int, Time int, Orphans []int, SetActive func(int)) []int {
+    const infiniteDist = math.MaxInt32
+    // residual capacity of the arc from the neighbour towards I in the tree of I
+    towards := func (a int) float64 {
+        if g.inSinkTree[I] { return g.residual[a] }
+        return g.residual[a ^ 1]
+    }
+    bestArc, bestDist := -1, infiniteDist
+    for a:=g.first[I]; a>=0; a=g.next[a] {
+        j := g.head[a]
+        if towards(a) <= 0 || g.inSinkTree[j] != g.inSinkTree[I] || g.parent[j] == noParent {
+            continue
+        }
+        // checking the origin of j
+        d := 0
+        for k:=j; ; {
+            if g.timestamp[k] == Time {
+                d += g.dist[k]
+                break
+            }
+            parent := g.parent[k]
+            d++
+            if parent == terminalParent {
+                g.timestamp[k], g.dist[k] = Time, 1
+                break
+            }
+            if parent == orphanParent {
+                d = infiniteDist
+                break
+            }
+            k = g.head[parent]
+        }
+        if d == infiniteDist { continue }
+        if d < bestDist {
+            bestArc, bestDist = a, d
+        }
+        // marks along the path
+        for k:=j; g.timestamp[k] != Time; k=g.head[g.parent[k]] {
+            g.timestamp[k], g.dist[k] = Time, d
+            d--
+        }
+    }
+
+    if bestArc >= 0 {
+        g.parent[I] = bestArc
+        g.timestamp[I], g.dist[I] = Time, bestDist + 1
+        return Orphans
+    }
+    for a:=g.first[I]; a>=0; a=g.next[a] {
+        j := g.head[a]
+        if g.inSinkTree[j] != g.inSinkTree[I] || g.parent[j] == noParent {
+            continue
+        }
+        if towards(a) > 0 { SetActive(j) }
+        parent := g.parent[j]
+        if parent != terminalParent && parent != orphanParent && g.head[parent] == I {
+            g.parent[j] = orphanParent
+            Orphans = append(Orphans, j)
+        }
+    }
+    g.parent[I] = noParent
+    return Orphans
+}
+
+
+/*
+SUMMARY
+    Tells on which side of the minimal cut a node is, call it after MaxFlow.
+PARAMETERS
+    I int: the node
+RETURN
+    bool: true for the source segment (label 0), false for the sink segment (label 1)
+*/
+func (g *Graph) InSourceSegment(I int) bool {
+    return g.parent[I] == noParent || !g.inSinkTree[I]
+}
+
+
+/*
+SUMMARY
+    Computes the exact MAP configuration (the global minimum of the energy) of the Ising model with a
+    single minimal cut. The energy is submodular because the coupling is not negative.
+PARAMETERS
+    N/A
+RETURN
+    *mat.Dense: the restored image of -1s and +1s
+*/
+func (m IsingModel) GraphCut() *mat.Dense {
+    if m.Coupling < 0 { panic("Negative coupling encountered, the energy is not submodular") }
+    N := m.Lattice.Size()
+    g := NewGraph(N)
+    // label 0 is -1, label 1 is +1
+    for i:=0; i<N; i++ {
+        g.AddUnaryTerm(i, -m.LogLikelihood(i, -1.0), -m.LogLikelihood(i, 1.0))
+        c := m.Lattice.Coord(i)
+        for _, neighbour := range m.Lattice.Neighbours(c.Y, c.X) {
+            if j := m.Lattice.Index(neighbour); j > i {
+                g.AddPairwiseTerm(i, j, -m.Coupling, m.Coupling, m.Coupling, -m.Coupling)
+            }
+        }
+    }
+    g.MaxFlow()
+    x := make([]float64, N)
+    for i := range x {
+        if g.InSourceSegment(i) {
+            x[i] = -1.0
+        } else {
+            x[i] = 1.0
+        }
+    }
+    return mat.NewDense(m.Lattice.Height, m.Lattice.Width, x)
+}
+
+
+/*
+SUMMARY
+    Computes the alpha-expansion of Boykov, Veksler & Zabih, Fast Approximate Energy Minimization via
+    Graph Cuts, https://doi.org/10.1109/34.969114 Each move lets any set of pixels switch to the label alpha
+    and the best such move is found with a minimal cut. The result is within a factor 2 of the global minimum.
+PARAMETERS
+    Sweeps int: the maximal number of sweeps over the labels, it stops earlier when no move helps
+RETURN
+    []int: the labels of the pixels
+*/
+func (m PottsModel) AlphaExpansion(Sweeps int) []int {
+    if m.Coupling < 0 { panic("Negative coupling encountered, the energy is not a metric") }
+    N := m.Lattice.Size()
+    x := make([]int, N)
+    copy(x, m.InitialLabels)
+    energy := m.Energy(x)
+    pairwise := func (a, b int) float64 {
+        if a == b { return -m.Coupling }
+        return 0.0
+    }
+    for sweep:=0; sweep<Sweeps; sweep++ {
+        improved := false
+        for alpha:=0; alpha<m.NumLabels; alpha++ {
+            g := NewGraph(N)
+            // label 0 keeps the current label, label 1 switches to alpha
+            for i:=0; i<N; i++ {
+                g.AddUnaryTerm(i, -m.LogLikelihood(i, x[i]), -m.LogLikelihood(i, alpha))
+                c := m.Lattice.Coord(i)
+                for _, neighbour := range m.Lattice.Neighbours(c.Y, c.X) {
+                    if j := m.Lattice.Index(neighbour); j > i {
+                        g.AddPairwiseTerm(i, j, pairwise(x[i], x[j]), pairwise(x[i], alpha),
+                                          pairwise(alpha, x[j]), pairwise(alpha, alpha))
+                    }
+                }
+            }
+            g.MaxFlow()
+            proposal := make([]int, N)
+            for i := range proposal {
+                proposal[i] = x[i]
+                if !g.InSourceSegment(i) { proposal[i] = alpha }
+            }
+            if proposalEnergy := m.Energy(proposal); proposalEnergy < energy - 1e-9 {
+                x, energy, improved = proposal, proposalEnergy, true
+            }
+        }
+        if !improved { break }
+    }
+    return x
+}
@@ -0,0 +1,95 @@
+package mrf
+
+import (
+    "math"
+    "testing"
+    "golang.org/x/exp/rand"
+)
+
+
+/*
+SUMMARY
+    The global minimum of an energy over every labelling of a tiny lattice.
+PARAMETERS
+    N int: the number of pixels
+    NumLabels int: the number of labels
+    Energy func([]int) float64: the energy of a labelling
+RETURN
+    float64: the minimal energy
+    []int: a minimiser
+*/
+func bruteForceMinimum(N, NumLabels int, Energy func([]int) float64) (float64, []int) {
+    best, minimiser := math.Inf(1), make([]int, N)
+    x := make([]int, N)
+    for {
+        if e := Energy(x); e < best {
+            best = e
+            copy(minimiser, x)
+        }
+        // the next labelling in base NumLabels
+        i := 0
+        for ; i < N && x[i] == NumLabels - 1; i++ { x[i] = 0 }
+        if i == N { return best, minimiser }
+        x[i]++
+    }
+}
+
+
+func TestGraphCutIsExact(t *testing.T) {
+    randGen := rand.New(rand.NewSource(1))
+    for _, neighbourhood := range []int{FOUR_NEIGHBOURHOOD, EIGHT_NEIGHBOURHOOD} {
+        for _, coupling := range []float64{0.0, 0.3, 1.0, 2.5} {
+            m := IsingModel{Lattice: Lattice{Height: 3, Width: 4, Neighbourhood: neighbourhood}, Coupling: coupling, LikelihoodWeight: 1.0}
+            m.Y = make([]float64, m.Lattice.Size())
+            for i := range m.Y { m.Y[i] = randGen.Float64() }
+            spins := func (X []int) []float64 {
+                values := make([]float64, len(X))
+                for i, label := range X { values[i] = 2.0 * float64(label) - 1.0 }
+                return values
+            }
+            minimum, _ := bruteForceMinimum(m.Lattice.Size(), 2, func (X []int) float64 { return m.Energy(spins(X)) })
+            cut := m.Energy(m.GraphCut().RawMatrix().Data)
+            if math.Abs(cut - minimum) > 1e-9 {
+                t.Errorf("neighbourhood %d, coupling %g: graph cut energy %g, global minimum %g", neighbourhood, coupling, cut, minimum)
+            }
+        }
+    }
+}
+
+
+func TestAlphaExpansionReachesMinimum(t *testing.T) {
+    randGen := rand.New(rand.NewSource(2))
+    for _, numLabels := range []int{2, 3} {
+        for _, coupling := range []float64{0.2, 1.0, 3.0} {
+            m := PottsModel{
+                Lattice: Lattice{Height: 3, Width: 3, Neighbourhood: EIGHT_NEIGHBOURHOOD},
+                NumLabels: numLabels,
+                Coupling: coupling,
+                Means: [][]float64{{0.2, 0.2, 0.2}, {0.8, 0.8, 0.8}, {0.2, 0.8, 0.5}}[:numLabels],
+                Variances: []float64{0.05, 0.05, 0.05}[:numLabels],
+            }
+            m.Y = make([][]float64, m.Lattice.Size())
+            m.InitialLabels = make([]int, m.Lattice.Size())
+            for i := range m.Y {
+                m.Y[i] = []float64{randGen.Float64(), randGen.Float64(), randGen.Float64()}
+                m.InitialLabels[i] = randGen.Intn(numLabels)
+            }
+            minimum, minimiser := bruteForceMinimum(m.Lattice.Size(), numLabels, m.Energy)
+            expansion := m.Energy(m.AlphaExpansion(10))
+            if math.Abs(expansion - minimum) > 1e-9 {
+                t.Errorf("%d labels, coupling %g: alpha-expansion energy %g, global minimum %g", numLabels, coupling, expansion, minimum)
+            }
+            // the guarantee of Boykov, Veksler & Zabih: at most the minimum plus its smoothness cost once more
+            disagreements := 0.0
+            for i := range minimiser {
+                c := m.Lattice.Coord(i)
+                for _, neighbour := range m.Lattice.Neighbours(c.Y, c.X) {
+                    if minimiser[m.Lattice.Index(neighbour)] != minimiser[i] { disagreements += 0.5 }
+                }
+            }
+            if expansion > minimum + coupling * disagreements + 1e-9 {
+                t.Errorf("%d labels, coupling %g: alpha-expansion energy %g exceeds the bound", numLabels, coupling, expansion)
+            }
+        }
+    }
+}
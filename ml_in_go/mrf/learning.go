@@ -0,0 +1,199 @@
+package mrf
+
+import (
+    "math"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+
+    "ml_playground/optimisers"
+    "ml_playground/utils"
+)
+
+
+/*
+This type is one training example for learning the parameters of the Ising model.
+    Clean *mat.Dense: the clean binary image, values in [0,1] (thresholded at 0.5)
+    Noisy *mat.Dense: the corrupted image, values in [0,1]
+*/
+type TrainingPair struct {
+    Clean *mat.Dense
+    Noisy *mat.Dense
+}
+
+
+/*
+This type configures the parameter learning.
+    Neighbourhood int: FOUR_NEIGHBOURHOOD or EIGHT_NEIGHBOURHOOD
+    Iterations int: the maximal number of optimiser steps
+    StepSize float64: the step size of Adam
+    CDSteps int: the number of Gibbs sweeps of contrastive divergence (1 if 0)
+    Seed int: seed of the random number generator of contrastive divergence
+*/
+type LearningOptions struct {
+    Neighbourhood int
+    Iterations int
+    StepSize float64
+    CDSteps int
+    Seed int
+}
+
+
+/*
+SUMMARY
+    Turns a clean image into -1s and +1s.
+PARAMETERS
+    Clean *mat.Dense: the clean image with values in [0,1]
+RETURN
+    []float64: the latent configuration
+*/
+func cleanSpins(Clean *mat.Dense) []float64 {
+    x := utils.Flatten(Clean, true)
+    for i := range x {
+        if x[i] >= 0.5 { x[i] = 1.0 } else { x[i] = -1.0 }
+    }
+    return x
+}
+
+
+/*
+SUMMARY
+    The sufficient statistics of the Ising model, log p(X|Y) = Coupling*s_0 + LikelihoodWeight*s_1 - log Z.
+PARAMETERS
+    M IsingModel: the model, only the lattice and the observations are used
+    X []float64: the latent space of -1s and +1s
+RETURN
+    []float64: s_0 = \sum_{i~j} X_i X_j and s_1 = -\sum_i (2Y_i-1 - X_i)^2
+*/
+func isingStatistics(M IsingModel, X []float64) []float64 {
+    statistics := make([]float64, 2)
+    for i := range X {
+        statistics[0] += 0.5 * M.Lattice.NeighbourSum(i, X[i], X)
+        statistics[1] -= math.Pow(2*M.Y[i]-1 - X[i], 2.0)
+    }
+    return statistics
+}
+
+
+/*
+SUMMARY
+    The log pseudo-likelihood \sum_i log p(X_i|X_{N(i)},Y_i) of the clean images given the noisy ones,
+    averaged over the pixels, and its gradient with respect to (Coupling, LikelihoodWeight).
+    It is concave in the parameters.
+PARAMETERS
+    Pairs []TrainingPair: the training images
+    Coupling float64: the coupling
+    LikelihoodWeight float64: the likelihood weight
+    Neighbourhood int: FOUR_NEIGHBOURHOOD or EIGHT_NEIGHBOURHOOD
+RETURN
+    float64: the average log pseudo-likelihood
+    []float64: its gradient
+*/
+func PseudoLikelihood(Pairs []TrainingPair, Coupling, LikelihoodWeight float64, Neighbourhood int) (float64, []float64) {
+    value := 0.0
+    gradient := make([]float64, 2)
+    count := 0.0
+    for _, pair := range Pairs {
+        model := NewIsingModel(pair.Noisy, Neighbourhood, Coupling, LikelihoodWeight)
+        x := cleanSpins(pair.Clean)
+        for i := range x {
+            probPlus := model.GibbsPosterior(i, x)
+            prob := probPlus
+            if x[i] < 0 { prob = 1 - probPlus }
+            value += math.Log(math.Max(prob, 1e-300))
+            // observed statistic minus its conditional expectation
+            neighbourSum := model.Lattice.NeighbourSum(i, 1.0, x)
+            gradient[0] += x[i] * neighbourSum - (2*probPlus - 1) * neighbourSum
+            dPlus, dMinus := math.Pow(2*model.Y[i]-1 - 1.0, 2.0), math.Pow(2*model.Y[i]-1 + 1.0, 2.0)
+            observed := dPlus
+            if x[i] < 0 { observed = dMinus }
+            gradient[1] += -observed + probPlus * dPlus + (1 - probPlus) * dMinus
+        }
+        count += float64(len(x))
+    }
+    gradient[0] /= count
+    gradient[1] /= count
+    return value / count, gradient
+}
+
+
+/*
+SUMMARY
+    Learns the coupling and the likelihood weight by maximising the pseudo-likelihood with Adam.
+PARAMETERS
+    Pairs []TrainingPair: the training images
+    Options LearningOptions: the settings, CDSteps and Seed are not used
+RETURN
+    float64: the learned coupling
+    float64: the learned likelihood weight
+    error: the error of the optimiser
+*/
+func LearnPseudoLikelihood(Pairs []TrainingPair, Options LearningOptions) (float64, float64, error) {
+    step, err := optimisers.Adam(Options.StepSize, 0.9, 0.999, 1e-8, 1e-6)
+    if err != nil { return 0, 0, err }
+    negativeGradient := func (theta []float64) []float64 {
+        _, gradient := PseudoLikelihood(Pairs, theta[0], theta[1], Options.Neighbourhood)
+        return []float64{-gradient[0], -gradient[1]}
+    }
+    theta := []float64{0.0, 1.0}
+    for iter:=0; iter<Options.Iterations; iter++ {
+        var converged bool
+        theta, converged, _, err = step(negativeGradient, theta)
+        if err != nil { return theta[0], theta[1], err }
+        if converged { break }
+    }
+    return theta[0], theta[1], nil
+}
+
+
+/*
+SUMMARY
+    Learns the coupling and the likelihood weight with contrastive divergence (CD-k) and Adam: the gradient
+    of the log-likelihood is approximated by the statistics of the clean images minus the statistics after
+    k Gibbs sweeps started from the clean images.
+PARAMETERS
+    Pairs []TrainingPair: the training images
+    Options LearningOptions: the settings
+RETURN
+    float64: the learned coupling
+    float64: the learned likelihood weight
+    error: the error of the optimiser
+*/
+func LearnContrastiveDivergence(Pairs []TrainingPair, Options LearningOptions) (float64, float64, error) {
+    step, err := optimisers.Adam(Options.StepSize, 0.9, 0.999, 1e-8, 1e-6)
+    if err != nil { return 0, 0, err }
+    cdSteps := Options.CDSteps
+    if cdSteps <= 0 { cdSteps = 1 }
+    randGen := rand.New(rand.NewSource(uint64(Options.Seed)))
+    negativeGradient := func (theta []float64) []float64 {
+        gradient := make([]float64, 2)
+        count := 0.0
+        for _, pair := range Pairs {
+            model := NewIsingModel(pair.Noisy, Options.Neighbourhood, theta[0], theta[1])
+            x := cleanSpins(pair.Clean)
+            data := isingStatistics(model, x)
+            for sweep:=0; sweep<cdSteps; sweep++ {
+                for i := range x {
+                    if model.GibbsPosterior(i, x) > randGen.Float64() {
+                        x[i] = 1.0
+                    } else {
+                        x[i] = -1.0
+                    }
+                }
+            }
+            reconstruction := isingStatistics(model, x)
+            for d := range gradient { gradient[d] -= data[d] - reconstruction[d] }
+            count += float64(len(x))
+        }
+        for d := range gradient { gradient[d] /= count }
+        return gradient
+    }
+    theta := []float64{0.0, 1.0}
+    for iter:=0; iter<Options.Iterations; iter++ {
+        var converged bool
+        theta, converged, _, err = step(negativeGradient, theta)
+        if err != nil { return theta[0], theta[1], err }
+        if converged { break }
+    }
+    return theta[0], theta[1], nil
+}
@@ -0,0 +1,82 @@
+package mrf
+
+import (
+    "math"
+    "testing"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+)
+
+
+/*
+SUMMARY
+    Random training pairs on a tiny lattice.
+PARAMETERS
+    NumPairs int: the number of pairs
+    Height int: the height of the images
+    Width int: the width of the images
+    Seed int: seed of the random numbers
+RETURN
+    []TrainingPair: the pairs
+*/
+func randomPairs(NumPairs, Height, Width, Seed int) []TrainingPair {
+    randGen := rand.New(rand.NewSource(uint64(Seed)))
+    pairs := make([]TrainingPair, NumPairs)
+    for p := range pairs {
+        clean, noisy := mat.NewDense(Height, Width, nil), mat.NewDense(Height, Width, nil)
+        clean.Apply(func (i, j int, v float64) float64 { return math.Round(randGen.Float64()) }, clean)
+        noisy.Apply(func (i, j int, v float64) float64 { return randGen.Float64() }, noisy)
+        pairs[p] = TrainingPair{Clean: clean, Noisy: noisy}
+    }
+    return pairs
+}
+
+
+func TestPseudoLikelihoodGradient(t *testing.T) {
+    pairs := randomPairs(2, 3, 4, 5)
+    for _, neighbourhood := range []int{FOUR_NEIGHBOURHOOD, EIGHT_NEIGHBOURHOOD} {
+        for _, theta := range [][]float64{{0.0, 1.0}, {0.7, 0.4}, {-0.3, 2.0}} {
+            _, gradient := PseudoLikelihood(pairs, theta[0], theta[1], neighbourhood)
+            for k := range theta {
+                h := 1e-6
+                plus, minus := append([]float64{}, theta...), append([]float64{}, theta...)
+                plus[k] += h
+                minus[k] -= h
+                valuePlus, _ := PseudoLikelihood(pairs, plus[0], plus[1], neighbourhood)
+                valueMinus, _ := PseudoLikelihood(pairs, minus[0], minus[1], neighbourhood)
+                numeric := (valuePlus - valueMinus) / (2.0 * h)
+                if math.Abs(gradient[k] - numeric) > 1e-6 * (1.0 + math.Abs(numeric)) {
+                    t.Errorf("neighbourhood %d, parameters %v: derivative %d is %g, finite difference %g", neighbourhood, theta, k, gradient[k], numeric)
+                }
+            }
+        }
+    }
+}
+
+
+func TestPseudoLikelihoodValue(t *testing.T) {
+    pairs := randomPairs(1, 3, 3, 6)
+    coupling, weight := 0.6, 1.3
+    for _, neighbourhood := range []int{FOUR_NEIGHBOURHOOD, EIGHT_NEIGHBOURHOOD} {
+        value, _ := PseudoLikelihood(pairs, coupling, weight, neighbourhood)
+        // the conditionals from the unnormalised log-density Coupling*s_0 + LikelihoodWeight*s_1
+        model := NewIsingModel(pairs[0].Noisy, neighbourhood, coupling, weight)
+        x := cleanSpins(pairs[0].Clean)
+        logDensity := func (X []float64) float64 {
+            statistics := isingStatistics(model, X)
+            return coupling * statistics[0] + weight * statistics[1]
+        }
+        expected := 0.0
+        for i := range x {
+            flipped := append([]float64{}, x...)
+            flipped[i] = -x[i]
+            own, other := logDensity(x), logDensity(flipped)
+            expected += own - math.Log(math.Exp(own) + math.Exp(other))
+        }
+        expected /= float64(len(x))
+        if math.Abs(value - expected) > 1e-10 {
+            t.Errorf("neighbourhood %d: log pseudo-likelihood %g instead of %g", neighbourhood, value, expected)
+        }
+    }
+}
@@ -0,0 +1,341 @@
+/*
+This library contains Markov random fields on image lattices. The Ising model restores binary
+images: the latent pixels are -1s and +1s, neighbouring pixels prefer to agree (coupling) and each
+latent pixel prefers to agree with the observed pixel (likelihood). The posterior can be explored with
+Iterated Conditional Modes, Gibbs sampling, mean-field variational Bayes, loopy belief propagation
+or tree-reweighted message passing. The Potts model extends
+the same ideas to K labels with Gaussian likelihoods to denoise and segment colour images. Graph cuts
+find the exact MAP of the Ising model and strong local minima (alpha-expansion) of the Potts model.
+The coupling and the likelihood weight of the Ising model can be learned from clean/noisy image pairs.
+*/
+package mrf
+
+import (
+    "math"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+
+    "ml_playground/utils"
+)
+
+
+// types of neighbourhoods on the lattice
+const FOUR_NEIGHBOURHOOD = 4
+const EIGHT_NEIGHBOURHOOD = 8
+
+
+// this type stores a pair of coordinate
+type Coord struct {
+    Y int
+    X int
+}
+
+
+/*
+This type describes a rectangular grid of pixels, the pixels are indexed row after row.
+    Height int: the number of rows
+    Width int: the number of columns
+    Neighbourhood int: FOUR_NEIGHBOURHOOD or EIGHT_NEIGHBOURHOOD
+*/
+type Lattice struct {
+    Height int
+    Width int
+    Neighbourhood int
+}
+
+
+/*
+SUMMARY
+    The number of pixels in the lattice.
+PARAMETERS
+    N/A
+RETURN
+    int: Height*Width
+*/
+func (l Lattice) Size() int {
+    return l.Height * l.Width
+}
+
+
+/*
+SUMMARY
+    Converts a pixel index into coordinates.
+PARAMETERS
+    I int: the index of the pixel
+RETURN
+    Coord: the row and column of the pixel
+*/
+func (l Lattice) Coord(I int) Coord {
+    return Coord{Y: I / l.Width, X: I % l.Width}
+}
+
+
+/*
+SUMMARY
+    Converts coordinates into a pixel index.
+PARAMETERS
+    C Coord: the row and column of the pixel
+RETURN
+    int: the index of the pixel
+*/
+func (l Lattice) Index(C Coord) int {
+    return C.Y * l.Width + C.X
+}
+
+
+/*
+SUMMARY
+    Returns the set of neighbours of a pixel, either in the 4 axis directions or in all 8 directions.
+    Pixels on the border have fewer neighbours.
+PARAMETERS
+    j int: row of the pixel
+    i int: col of the pixel
+RETURN
+    []Coord: set of coordinates (pair of indices) of all neighbours
+*/
+func (l Lattice) Neighbours(j, i int) []Coord {
+    var offsets []Coord
+    switch l.Neighbourhood {
+        case FOUR_NEIGHBOURHOOD:
+            offsets = []Coord{{Y: 0, X: 1}, {Y: 0, X: -1}, {Y: -1, X: 0}, {Y: 1, X: 0}}
+        case EIGHT_NEIGHBOURHOOD:
+            offsets = []Coord{{Y: 0, X: 1}, {Y: 0, X: -1}, {Y: -1, X: 0}, {Y: -1, X: -1},
+                              {Y: -1, X: 1}, {Y: 1, X: 0}, {Y: 1, X: -1}, {Y: 1, X: 1}}
+        default:
+            panic("Unknown neighbourhood encountered")
+    }
+    neighbours := make([]Coord, 0, len(offsets))
+    for _, offset := range offsets {
+        y, x := j + offset.Y, i + offset.X
+        if y >= 0 && y < l.Height && x >= 0 && x < l.Width {
+            neighbours = append(neighbours, Coord{Y: y, X: x})
+        }
+    }
+    return neighbours
+}
+
+
+/*
+SUMMARY
+    Computes the sum: `\sum_{j\in\mathcal{N}(I)} Value\cdot X_j` where `\mathcal{N}(I)` is the set
+    of neighbours of I in X.
+PARAMETERS
+    I int: the index of the pixel
+    Value float64: the value multiplying each neighbour
+    X []float64: the values on the lattice
+RETURN
+    float64: the sum
+*/
+func (l Lattice) NeighbourSum(I int, Value float64, X []float64) float64 {
+    miniSum := 0.0
+    c := l.Coord(I)
+    for _, neighbour := range l.Neighbours(c.Y, c.X) {
+        miniSum += X[l.Index(neighbour)] * Value
+    }
+    return miniSum
+}
+
+
+/*
+This type is the Ising model of a noisy binary image with the energy
+    E(X) = -Coupling \sum_{i~j} X_i X_j + LikelihoodWeight \sum_i (2Y_i-1 - X_i)^2
+where the first sum runs over the neighbouring pairs, and p(X|Y) is proportional to exp(-E(X)).
+    Lattice Lattice: the grid of pixels
+    Coupling float64: how strongly the neighbouring pixels prefer to agree
+    LikelihoodWeight float64: how strongly a latent pixel prefers to agree with the observed pixel
+    Y []float64: the observed image flattened row after row, values in [0,1]
+*/
+type IsingModel struct {
+    Lattice Lattice
+    Coupling float64
+    LikelihoodWeight float64
+    Y []float64
+}
+
+
+/*
+SUMMARY
+    Creates the Ising model of an observed image.
+PARAMETERS
+    Img *mat.Dense: one channel from an image with values in [0,1] (in BW images all channels are the same)
+    Neighbourhood int: FOUR_NEIGHBOURHOOD or EIGHT_NEIGHBOURHOOD
+    Coupling float64: how strongly the neighbouring pixels prefer to agree
+    LikelihoodWeight float64: how strongly a latent pixel prefers to agree with the observed pixel
+RETURN
+    IsingModel: the model
+*/
+func NewIsingModel(Img *mat.Dense, Neighbourhood int, Coupling, LikelihoodWeight float64) IsingModel {
+    Height, Width := Img.Dims()
+    return IsingModel{
+        Lattice: Lattice{Height: Height, Width: Width, Neighbourhood: Neighbourhood},
+        Coupling: Coupling,
+        LikelihoodWeight: LikelihoodWeight,
+        Y: utils.Flatten(Img, true),
+    }
+}
+
+
+/*
+SUMMARY
+    The log-likelihood log p(Y_I|X_I) up to an additive constant.
+PARAMETERS
+    I int: the index of the pixel
+    Value float64: the value of the latent pixel, +1 or -1
+RETURN
+    float64: -LikelihoodWeight*(2Y_I-1 - Value)^2
+*/
+func (m IsingModel) LogLikelihood(I int, Value float64) float64 {
+    return -m.LikelihoodWeight * math.Pow(2*m.Y[I]-1 - Value, 2.0)
+}
+
+
+/*
+SUMMARY
+    The terms of -E(X) that depend on the Ith pixel when it takes Value and the rest stays as X.
+PARAMETERS
+    I int: the index of the pixel
+    Value float64: the value we test at the Ith index, +1 or -1
+    X []float64: the latent space of -1s and +1s
+RETURN
+    float64: the local log-potential
+*/
+func (m IsingModel) LocalLogPotential(I int, Value float64, X []float64) float64 {
+    return m.Coupling * m.Lattice.NeighbourSum(I, Value, X) + m.LogLikelihood(I, Value)
+}
+
+
+/*
+SUMMARY
+    Evaluates the energy of a configuration, lower is more probable.
+PARAMETERS
+    X []float64: the latent space of -1s and +1s
+RETURN
+    float64: E(X)
+*/
+func (m IsingModel) Energy(X []float64) float64 {
+    energy := 0.0
+    for i := range X {
+        // each pair is visited from both ends
+        energy -= 0.5 * m.Coupling * m.Lattice.NeighbourSum(i, X[i], X)
+        energy -= m.LogLikelihood(i, X[i])
+    }
+    return energy
+}
+
+
+/*
+SUMMARY
+    Computes the Iterative Conditional Modes: every pixel in turn takes the value of lower energy.
+PARAMETERS
+    Periods int: the number of sweeps over the image
+RETURN
+    *mat.Dense: the restored image of -1s and +1s
+*/
+func (m IsingModel) ICM(Periods int) *mat.Dense {
+    x := utils.Linspace(1.0, 1.0, m.Lattice.Size())
+    for tau:=0; tau<Periods; tau++ {
+        for i := range x {
+            if m.LocalLogPotential(i, 1.0, x) > m.LocalLogPotential(i, -1.0, x) {
+                x[i] = 1.0
+            } else {
+                x[i] = -1.0
+            }
+        }
+    }
+    return mat.NewDense(m.Lattice.Height, m.Lattice.Width, x)
+}
+
+
+/*
+SUMMARY
+    Computes the Gibbs Sampling conditional p(X_I=+1|X_{-I},Y).
+PARAMETERS
+    I int: the index of the pixel
+    X []float64: the latent space of -1s and +1s
+RETURN
+    float64: the probability
+*/
+func (m IsingModel) GibbsPosterior(I int, X []float64) float64 {
+    // exp(a)/(exp(a)+exp(b)) written in a form that cannot overflow
+    return 1.0 / (1.0 + math.Exp(m.LocalLogPotential(I, -1.0, X) - m.LocalLogPotential(I, 1.0, X)))
+}
+
+
+/*
+SUMMARY
+    Computes the Gibbs Sampling for the Ising model, returning the last sample.
+PARAMETERS
+    Periods int: the number of sweeps over the image
+    Src rand.Source: the source of randomness
+RETURN
+    *mat.Dense: the last sample of -1s and +1s
+*/
+func (m IsingModel) GibbsSampling(Periods int, Src rand.Source) *mat.Dense {
+    randGen := rand.New(Src)
+    x := utils.Linspace(1.0, 1.0, m.Lattice.Size())
+    for tau:=0; tau<Periods; tau++ {
+        for i := range x {
+            if m.GibbsPosterior(i, x) > randGen.Float64() {
+                x[i] = 1.0
+            } else {
+                x[i] = -1.0
+            }
+        }
+    }
+    return mat.NewDense(m.Lattice.Height, m.Lattice.Width, x)
+}
+
+
+/*
+SUMMARY
+    Estimates the posterior marginals p(X_I=+1|Y) by averaging the samples of one Gibbs chain.
+PARAMETERS
+    BurnIn int: the number of sweeps discarded at the beginning
+    NumSamples int: the number of sweeps averaged
+    Src rand.Source: the source of randomness
+RETURN
+    *mat.Dense: the marginal probability of +1 for each pixel
+*/
+func (m IsingModel) GibbsMarginals(BurnIn, NumSamples int, Src rand.Source) *mat.Dense {
+    randGen := rand.New(Src)
+    x := utils.Linspace(1.0, 1.0, m.Lattice.Size())
+    marginals := make([]float64, len(x))
+    for tau:=0; tau<BurnIn+NumSamples; tau++ {
+        for i := range x {
+            if m.GibbsPosterior(i, x) > randGen.Float64() {
+                x[i] = 1.0
+            } else {
+                x[i] = -1.0
+            }
+        }
+        if tau >= BurnIn {
+            for i := range x {
+                if x[i] > 0 { marginals[i] += 1.0 / float64(NumSamples) }
+            }
+        }
+    }
+    return mat.NewDense(m.Lattice.Height, m.Lattice.Width, marginals)
+}
+
+
+/*
+SUMMARY
+    Computes the mean-field Variational Bayes for the Ising model. The approximate posterior
+    q(X)=\prod_i q_i(X_i) is updated pixel by pixel with
+    mu_i = tanh(Coupling \sum_{j\in\mathcal{N}(i)} mu_j + (log p(Y_i|+1) - log p(Y_i|-1))/2)
+PARAMETERS
+    Periods int: the number of sweeps over the image
+RETURN
+    *mat.Dense: the posterior means E_q[X_i] in [-1,1]
+*/
+func (m IsingModel) VariationalBayes(Periods int) *mat.Dense {
+    mu := utils.Linspace(0.0, 0.0, m.Lattice.Size())
+    for tau:=0; tau<Periods; tau++ {
+        for i := range mu {
+            field := m.Coupling * m.Lattice.NeighbourSum(i, 1.0, mu)
+            mu[i] = math.Tanh(field + 0.5 * (m.LogLikelihood(i, 1.0) - m.LogLikelihood(i, -1.0)))
+        }
+    }
+    return mat.NewDense(m.Lattice.Height, m.Lattice.Width, mu)
+}
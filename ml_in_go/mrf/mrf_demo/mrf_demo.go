@@ -0,0 +1,116 @@
+package main
+
+import (
+    "fmt"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+
+    "ml_playground/mrf"
+    "ml_playground/pic"
+)
+
+
+// random number seed and source
+var randSeed = 10
+var randSrc = rand.NewSource(uint64(randSeed))
+
+
+/*
+SUMMARY
+    Loads the scottie drawing, thresholds it, and corrupts it like the pic demo does.
+PARAMETERS
+    Sigma float64: the standard deviation of the noise
+    Proportion float64: the proportion of the corrupted pixels
+RETURN
+    mrf.TrainingPair: the clean and the noisy image with values in [0,1]
+*/
+func NoisyScottie(Sigma, Proportion float64) mrf.TrainingPair {
+    var img pic.RGBImg = make([]mat.Dense, 3)
+    err := img.LoadPixels("dog.jpg")
+    if err != nil { panic(err) }
+    img.BinaryThreshold(70.0)
+    clean := mat.NewDense(img[0].RawMatrix().Rows, img[0].RawMatrix().Cols, nil)
+    clean.Scale(1.0/255.0, &img[0])
+    img.AddNoise(Sigma, Proportion)
+    img.GrayScale()
+    noisy := mat.NewDense(img[0].RawMatrix().Rows, img[0].RawMatrix().Cols, nil)
+    noisy.Scale(1.0/255.0, &img[0])
+    return mrf.TrainingPair{Clean: clean, Noisy: noisy}
+}
+
+
+/*
+SUMMARY
+    The proportion of pixels where the restored image differs from the clean one.
+PARAMETERS
+    Restored *mat.Dense: the restored image of -1s and +1s
+    Clean *mat.Dense: the clean image with values in [0,1]
+RETURN
+    float64: the error rate
+*/
+func ErrorRate(Restored, Clean *mat.Dense) float64 {
+    H, W := Clean.Dims()
+    errors := 0.0
+    for j:=0; j<H; j++ {
+        for i:=0; i<W; i++ {
+            if (Restored.At(j, i) > 0) != (Clean.At(j, i) >= 0.5) { errors++ }
+        }
+    }
+    return errors / float64(H*W)
+}
+
+
+/*
+We corrupt a colour image with noise, then denoise and segment it with a Potts model
+using ICM, Gibbs sampling, mean-field Variational Bayes and alpha-expansion.
+Then we learn the parameters of the Ising model from clean/noisy pairs of the scottie drawing at two noise
+levels, and compare the graph cut restorations with the learned and the hand-tuned parameters.
+*/
+func main() {
+    var img pic.RGBImg = make([]mat.Dense, 3)
+    err := img.LoadPixels("image.jpg")
+    if err != nil { panic(err) }
+    img.AddNoise(40.0, 0.2)
+    img.SaveImage("noisy_image.jpg")
+
+    model := mrf.NewPottsModel(img, 5, mrf.EIGHT_NEIGHBOURHOOD, 3.0, randSeed)
+    fmt.Println("energy of the KMeans labels", model.Energy(model.InitialLabels))
+    model.Segmentation(model.InitialLabels).SaveImage("potts_kmeans.jpg")
+
+    labels := model.ICM(5)
+    fmt.Println("energy after ICM", model.Energy(labels))
+    model.Segmentation(labels).SaveImage("potts_icm.jpg")
+
+    labels = model.GibbsSampling(5, randSrc)
+    fmt.Println("energy of the last Gibbs sample", model.Energy(labels))
+    model.Segmentation(labels).SaveImage("potts_gibbs.jpg")
+
+    labels = mrf.MostProbableLabels(model.VariationalBayes(5))
+    fmt.Println("energy of the most probable labels under Variational Bayes", model.Energy(labels))
+    model.Segmentation(labels).SaveImage("potts_vb.jpg")
+
+    labels = model.AlphaExpansion(5)
+    fmt.Println("energy after alpha-expansion", model.Energy(labels))
+    model.Segmentation(labels).SaveImage("potts_alpha_expansion.jpg")
+
+    options := mrf.LearningOptions{Neighbourhood: mrf.EIGHT_NEIGHBOURHOOD, Iterations: 150, StepSize: 0.05, CDSteps: 1, Seed: randSeed}
+    for _, noise := range []struct{ sigma, proportion float64 }{{sigma: 150.0, proportion: 0.6}, {sigma: 100.0, proportion: 0.2}} {
+        fmt.Println("noise sigma", noise.sigma, "proportion", noise.proportion)
+        train := []mrf.TrainingPair{NoisyScottie(noise.sigma, noise.proportion)}
+        test := NoisyScottie(noise.sigma, noise.proportion)
+        couplingPL, weightPL, err := mrf.LearnPseudoLikelihood(train, options)
+        if err != nil { panic(err) }
+        couplingCD, weightCD, err := mrf.LearnContrastiveDivergence(train, options)
+        if err != nil { panic(err) }
+        for _, params := range []struct{ name string; coupling, weight float64 }{
+            {name: "hand-tuned", coupling: 1.0, weight: 1.5},
+            {name: "pseudo-likelihood", coupling: couplingPL, weight: weightPL},
+            {name: "contrastive divergence", coupling: couplingCD, weight: weightCD},
+        } {
+            restored := mrf.NewIsingModel(test.Noisy, mrf.EIGHT_NEIGHBOURHOOD, params.coupling, params.weight).GraphCut()
+            fmt.Printf("    %-24s coupling %.3f likelihood weight %.3f error rate %.4f\n",
+                       params.name, params.coupling, params.weight, ErrorRate(restored, test.Clean))
+        }
+    }
+}
@@ -0,0 +1,138 @@
+package mrf
+
+import (
+    "sync"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+
+    "ml_playground/utils"
+)
+
+
+/*
+This type configures the parallel Gibbs sampler.
+    BurnIn int: the number of sweeps discarded at the beginning
+    NumSamples int: the number of samples averaged into the marginals
+    Thinning int: the number of sweeps between two kept samples (1 if 0)
+    NumWorkers int: the number of goroutines (1 if 0)
+    Seed int: seed of the random number generators
+*/
+type GibbsOptions struct {
+    BurnIn int
+    NumSamples int
+    Thinning int
+    NumWorkers int
+    Seed int
+}
+
+
+/*
+This type stores the outcome of the parallel Gibbs sampler.
+    Marginals *mat.Dense: the estimated posterior probability p(X_i=+1|Y) of each pixel
+    Sample *mat.Dense: the last sample of -1s and +1s
+*/
+type GibbsResult struct {
+    Marginals *mat.Dense
+    Sample *mat.Dense
+}
+
+
+/*
+SUMMARY
+    The colour of a pixel in a colouring of the lattice where no two neighbours share a colour:
+    the red-black checkerboard for 4-neighbourhoods and a 2 by 2 tiling of 4 colours for 8-neighbourhoods.
+PARAMETERS
+    C Coord: the pixel
+RETURN
+    int: the colour
+*/
+func (l Lattice) colour(C Coord) int {
+    if l.Neighbourhood == FOUR_NEIGHBOURHOOD {
+        return (C.Y + C.X) % 2
+    }
+    return 2 * (C.Y % 2) + C.X % 2
+}
+
+
+/*
+SUMMARY
+    The number of colours used by colour.
+PARAMETERS
+    N/A
+RETURN
+    int: 2 or 4
+*/
+func (l Lattice) numColours() int {
+    if l.Neighbourhood == FOUR_NEIGHBOURHOOD {
+        return 2
+    }
+    return 4
+}
+
+
+/*
+SUMMARY
+    Gibbs sampling with a checkerboard schedule. The pixels of one colour are conditionally independent
+    given the rest, so they are updated at the same time by goroutines working on strips of rows.
+    Every row owns a random number generator seeded from Seed, hence the result depends neither on
+    NumWorkers nor on GOMAXPROCS.
+PARAMETERS
+    Options GibbsOptions: the settings of the sampler
+RETURN
+    GibbsResult: the marginals and the last sample
+*/
+func (m IsingModel) ParallelGibbs(Options GibbsOptions) GibbsResult {
+    if Options.NumSamples <= 0 { panic("Negative/0 number of samples encountered") }
+    thinning := Options.Thinning
+    if thinning <= 0 { thinning = 1 }
+    workers := Options.NumWorkers
+    if workers <= 0 { workers = 1 }
+    if workers > m.Lattice.Height { workers = m.Lattice.Height }
+
+    seeds := rand.New(rand.NewSource(uint64(Options.Seed)))
+    rowGens := make([]*rand.Rand, m.Lattice.Height)
+    for row := range rowGens {
+        rowGens[row] = rand.New(rand.NewSource(seeds.Uint64()))
+    }
+    x := utils.Linspace(1.0, 1.0, m.Lattice.Size())
+    marginals := make([]float64, len(x))
+
+    sweep := func (colour, first, last int) {
+        for row:=first; row<last; row++ {
+            for col:=0; col<m.Lattice.Width; col++ {
+                c := Coord{Y: row, X: col}
+                if m.Lattice.colour(c) != colour { continue }
+                i := m.Lattice.Index(c)
+                if m.GibbsPosterior(i, x) > rowGens[row].Float64() {
+                    x[i] = 1.0
+                } else {
+                    x[i] = -1.0
+                }
+            }
+        }
+    }
+    var wg sync.WaitGroup
+    total := Options.BurnIn + Options.NumSamples * thinning
+    for tau:=0; tau<total; tau++ {
+        for colour:=0; colour<m.Lattice.numColours(); colour++ {
+            for w:=0; w<workers; w++ {
+                wg.Add(1)
+                go func(first, last int) {
+                    defer wg.Done()
+                    sweep(colour, first, last)
+                }(w * m.Lattice.Height / workers, (w + 1) * m.Lattice.Height / workers)
+            }
+            wg.Wait()
+        }
+        if tau >= Options.BurnIn && (tau - Options.BurnIn + 1) % thinning == 0 {
+            for i := range x {
+                if x[i] > 0 { marginals[i] += 1.0 / float64(Options.NumSamples) }
+            }
+        }
+    }
+    return GibbsResult{
+        Marginals: mat.NewDense(m.Lattice.Height, m.Lattice.Width, marginals),
+        Sample: mat.NewDense(m.Lattice.Height, m.Lattice.Width, x),
+    }
+}
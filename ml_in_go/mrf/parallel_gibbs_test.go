@@ -0,0 +1,32 @@
+package mrf
+
+import (
+    "testing"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+)
+
+
+func TestParallelGibbsIndependentOfWorkers(t *testing.T) {
+    randGen := rand.New(rand.NewSource(3))
+    for _, neighbourhood := range []int{FOUR_NEIGHBOURHOOD, EIGHT_NEIGHBOURHOOD} {
+        m := IsingModel{Lattice: Lattice{Height: 13, Width: 9, Neighbourhood: neighbourhood}, Coupling: 0.8, LikelihoodWeight: 1.5}
+        m.Y = make([]float64, m.Lattice.Size())
+        for i := range m.Y { m.Y[i] = randGen.Float64() }
+        options := GibbsOptions{BurnIn: 5, NumSamples: 20, Thinning: 2, Seed: 4}
+        var reference GibbsResult
+        // more workers than rows are capped by the number of rows
+        for _, workers := range []int{1, 2, 3, 4, 20} {
+            options.NumWorkers = workers
+            result := m.ParallelGibbs(options)
+            if workers == 1 {
+                reference = result
+                continue
+            }
+            if !mat.Equal(reference.Marginals, result.Marginals) || !mat.Equal(reference.Sample, result.Sample) {
+                t.Errorf("neighbourhood %d: %d workers give a different result than 1 worker", neighbourhood, workers)
+            }
+        }
+    }
+}
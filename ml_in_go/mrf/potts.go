@@ -0,0 +1,323 @@
+package mrf
+
+import (
+    "math"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/floats"
+    "gonum.org/v1/gonum/mat"
+
+    "ml_playground/kmeans"
+    "ml_playground/pic"
+    "ml_playground/utils"
+)
+
+
+/*
+This type is the Potts model of a noisy colour image. Each pixel carries one of K labels and each label
+has a Gaussian likelihood with its own mean colour and isotropic variance. The energy is
+    E(X) = -Coupling \sum_{i~j} [X_i = X_j] - \sum_i log N(Y_i | Means_{X_i}, Variances_{X_i} I)
+where the first sum runs over the neighbouring pairs, and p(X|Y) is proportional to exp(-E(X)).
+    Lattice Lattice: the grid of pixels
+    NumLabels int: the number of labels K
+    Coupling float64: how strongly the neighbouring pixels prefer to share a label
+    Means [][]float64: K slices, the mean colour of each label
+    Variances []float64: the variance of each label (the same in every channel)
+    Y [][]float64: the observed colours, one slice of 3 values per pixel, row after row
+    InitialLabels []int: the KMeans labels, ICM and Gibbs sampling start from them
+*/
+type PottsModel struct {
+    Lattice Lattice
+    NumLabels int
+    Coupling float64
+    Means [][]float64
+    Variances []float64
+    Y [][]float64
+    InitialLabels []int
+}
+
+
+/*
+SUMMARY
+    Creates the Potts model of an image. The pixel colours are clustered with KMeans,
+    the clusters give the initial labels and the Gaussian likelihoods.
+PARAMETERS
+    Img pic.RGBImg: the observed image with values in [0,255]
+    NumLabels int: the number of labels
+    Neighbourhood int: FOUR_NEIGHBOURHOOD or EIGHT_NEIGHBOURHOOD
+    Coupling float64: how strongly the neighbouring pixels prefer to share a label
+    Seed int: seed of the KMeans seeding
+RETURN
+    PottsModel: the model
+*/
+func NewPottsModel(Img pic.RGBImg, NumLabels, Neighbourhood int, Coupling float64, Seed int) PottsModel {
+    if NumLabels < 2 { panic("Less than 2 labels encountered") }
+    Height, Width := Img[0].Dims()
+    points := mat.NewDense(3, Height*Width, nil)
+    for c:=0; c<3; c++ {
+        points.SetRow(c, utils.Flatten(&Img[c], true))
+    }
+    Y := make([][]float64, Height*Width)
+    for i := range Y {
+        Y[i] = mat.Col(nil, i, points)
+    }
+    labels, _ := kmeans.KMeansClassify(points, NumLabels, Seed)
+    model := PottsModel{
+        Lattice: Lattice{Height: Height, Width: Width, Neighbourhood: Neighbourhood},
+        NumLabels: NumLabels,
+        Coupling: Coupling,
+        Y: Y,
+        InitialLabels: labels,
+    }
+    model.FitLikelihoods(labels)
+    return model
+}
+
+
+/*
+SUMMARY
+    Re-estimates the mean and the variance of every label from a labelling. A label without
+    pixels keeps its previous likelihood (or gets a flat one).
+PARAMETERS
+    Labels []int: the label of each pixel
+RETURN
+    N/A
+*/
+func (m *PottsModel) FitLikelihoods(Labels []int) {
+    means := make([][]float64, m.NumLabels)
+    variances := make([]float64, m.NumLabels)
+    counts := make([]float64, m.NumLabels)
+    for k := range means { means[k] = make([]float64, 3) }
+    for i, label := range Labels {
+        floats.Add(means[label], m.Y[i])
+        counts[label]++
+    }
+    for k := range means {
+        if counts[k] > 0 { floats.Scale(1.0 / counts[k], means[k]) }
+    }
+    for i, label := range Labels {
+        d := floats.Distance(m.Y[i], means[label], 2)
+        variances[label] += d * d / 3.0
+    }
+    for k := range variances {
+        if counts[k] == 0 {
+            if m.Means != nil {
+                means[k], variances[k] = m.Means[k], m.Variances[k]
+            } else {
+                means[k], variances[k] = []float64{127.5, 127.5, 127.5}, 127.5 * 127.5
+            }
+            continue
+        }
+        // a floor on the variance keeps flat regions from dominating
+        variances[k] = math.Max(variances[k] / counts[k], 1.0)
+    }
+    m.Means, m.Variances = means, variances
+}
+
+
+/*
+SUMMARY
+    The log-likelihood log N(Y_I | Means_Label, Variances_Label I).
+PARAMETERS
+    I int: the index of the pixel
+    Label int: the label of the pixel
+RETURN
+    float64: the log-likelihood
+*/
+func (m PottsModel) LogLikelihood(I int, Label int) float64 {
+    d := floats.Distance(m.Y[I], m.Means[Label], 2)
+    return -1.5 * math.Log(2 * math.Pi * m.Variances[Label]) - 0.5 * d * d / m.Variances[Label]
+}
+
+
+/*
+SUMMARY
+    The terms of -E(X) that depend on the Ith pixel when it takes Label and the rest stays as X.
+PARAMETERS
+    I int: the index of the pixel
+    Label int: the label we test at the Ith index
+    X []int: the labels of the pixels
+RETURN
+    float64: the local log-potential
+*/
+func (m PottsModel) LocalLogPotential(I int, Label int, X []int) float64 {
+    agree := 0.0
+    c := m.Lattice.Coord(I)
+    for _, neighbour := range m.Lattice.Neighbours(c.Y, c.X) {
+        if X[m.Lattice.Index(neighbour)] == Label { agree++ }
+    }
+    return m.Coupling * agree + m.LogLikelihood(I, Label)
+}
+
+
+/*
+SUMMARY
+    Evaluates the energy of a labelling, lower is more probable.
+PARAMETERS
+    X []int: the labels of the pixels
+RETURN
+    float64: E(X)
+*/
+func (m PottsModel) Energy(X []int) float64 {
+    energy := 0.0
+    for i := range X {
+        c := m.Lattice.Coord(i)
+        for _, neighbour := range m.Lattice.Neighbours(c.Y, c.X) {
+            // each pair is visited from both ends
+            if X[m.Lattice.Index(neighbour)] == X[i] { energy -= 0.5 * m.Coupling }
+        }
+        energy -= m.LogLikelihood(i, X[i])
+    }
+    return energy
+}
+
+
+/*
+SUMMARY
+    Computes the Iterative Conditional Modes: every pixel in turn takes the label of lowest energy.
+PARAMETERS
+    Periods int: the number of sweeps over the image
+RETURN
+    []int: the labels of the pixels
+*/
+func (m PottsModel) ICM(Periods int) []int {
+    x := make([]int, len(m.InitialLabels))
+    copy(x, m.InitialLabels)
+    potentials := make([]float64, m.NumLabels)
+    for tau:=0; tau<Periods; tau++ {
+        for i := range x {
+            for k := range potentials { potentials[k] = m.LocalLogPotential(i, k, x) }
+            x[i] = utils.Argmax(potentials)
+        }
+    }
+    return x
+}
+
+
+/*
+SUMMARY
+    Computes the Gibbs Sampling for the Potts model, returning the last sample.
+PARAMETERS
+    Periods int: the number of sweeps over the image
+    Src rand.Source: the source of randomness
+RETURN
+    []int: the labels of the pixels in the last sample
+*/
+func (m PottsModel) GibbsSampling(Periods int, Src rand.Source) []int {
+    randGen := rand.New(Src)
+    x := make([]int, len(m.InitialLabels))
+    copy(x, m.InitialLabels)
+    potentials := make([]float64, m.NumLabels)
+    for tau:=0; tau<Periods; tau++ {
+        for i := range x {
+            for k := range potentials { potentials[k] = m.LocalLogPotential(i, k, x) }
+            logNormaliser := floats.LogSumExp(potentials)
+            t := randGen.Float64()
+            x[i] = m.NumLabels - 1
+            for k := range potentials {
+                t -= math.Exp(potentials[k] - logNormaliser)
+                if t < 0 {
+                    x[i] = k
+                    break
+                }
+            }
+        }
+    }
+    return x
+}
+
+
+/*
+SUMMARY
+    Computes the mean-field Variational Bayes for the Potts model. The approximate posterior
+    q(X)=\prod_i q_i(X_i) is updated pixel by pixel with
+    q_i(k) proportional to exp(Coupling \sum_{j\in\mathcal{N}(i)} q_j(k) + log N(Y_i | Means_k, Variances_k I))
+PARAMETERS
+    Periods int: the number of sweeps over the image
+RETURN
+    *mat.Dense: N by K matrix, row i holds q_i
+*/
+func (m PottsModel) VariationalBayes(Periods int) *mat.Dense {
+    N := m.Lattice.Size()
+    q := mat.NewDense(N, m.NumLabels, nil)
+    potentials := make([]float64, m.NumLabels)
+    // the first sweep sees the likelihoods only
+    for i:=0; i<N; i++ {
+        for k := range potentials { potentials[k] = m.LogLikelihood(i, k) }
+        softmaxRow(q, i, potentials)
+    }
+    for tau:=0; tau<Periods; tau++ {
+        for i:=0; i<N; i++ {
+            c := m.Lattice.Coord(i)
+            neighbours := m.Lattice.Neighbours(c.Y, c.X)
+            for k := range potentials {
+                field := 0.0
+                for _, neighbour := range neighbours {
+                    field += q.At(m.Lattice.Index(neighbour), k)
+                }
+                potentials[k] = m.Coupling * field + m.LogLikelihood(i, k)
+            }
+            softmaxRow(q, i, potentials)
+        }
+    }
+    return q
+}
+
+
+/*
+SUMMARY
+    Writes softmax(Potentials) into a row of a matrix.
+PARAMETERS
+    Q *mat.Dense: the matrix
+    I int: the row
+    Potentials []float64: the unnormalised log-probabilities
+RETURN
+    N/A
+*/
+func softmaxRow(Q *mat.Dense, I int, Potentials []float64) {
+    logNormaliser := floats.LogSumExp(Potentials)
+    for k, p := range Potentials {
+        Q.Set(I, k, math.Exp(p - logNormaliser))
+    }
+}
+
+
+/*
+SUMMARY
+    The most probable label of each pixel under the mean-field posterior.
+PARAMETERS
+    Q *mat.Dense: the output of VariationalBayes
+RETURN
+    []int: the labels of the pixels
+*/
+func MostProbableLabels(Q *mat.Dense) []int {
+    N, _ := Q.Dims()
+    labels := make([]int, N)
+    for i := range labels {
+        labels[i] = utils.Argmax(Q.RawRowView(i))
+    }
+    return labels
+}
+
+
+/*
+SUMMARY
+    Paints every pixel with the mean colour of its label.
+PARAMETERS
+    Labels []int: the labels of the pixels
+RETURN
+    pic.RGBImg: the segmented image
+*/
+func (m PottsModel) Segmentation(Labels []int) pic.RGBImg {
+    var img pic.RGBImg = make([]mat.Dense, 3)
+    for c := range img {
+        img[c] = *mat.NewDense(m.Lattice.Height, m.Lattice.Width, nil)
+    }
+    for i, label := range Labels {
+        coord := m.Lattice.Coord(i)
+        for c := range img {
+            img[c].Set(coord.Y, coord.X, m.Means[label][c])
+        }
+    }
+    return img
+}
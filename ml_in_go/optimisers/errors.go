@@ -0,0 +1,208 @@
+package optimisers
+
+import (
+    "fmt"
+    "math"
+)
+
+// enumerate for the kind of divergence
+const NAN_DIVERGENCE = 0
+const INF_DIVERGENCE = 1
+const EXPLODING_DIVERGENCE = 2
+
+
+/*
+This type is returned by the constructors when a hyperparameter is invalid.
+    Optimiser string: the name of the optimiser
+    Parameter string: the name of the offending hyperparameter
+    Value float64: the value we received
+    Expected string: the description of the valid values
+*/
+type ParameterError struct {
+    Optimiser string
+    Parameter string
+    Value float64
+    Expected string
+}
+
+func (e *ParameterError) Error() string {
+    return fmt.Sprintf("%s: %s must be %s, got %g", e.Optimiser, e.Parameter, e.Expected, e.Value)
+}
+
+
+/*
+This type is returned by an optimisation step when the optimisation has diverged.
+    Kind int: NAN_DIVERGENCE, INF_DIVERGENCE or EXPLODING_DIVERGENCE
+    Quantity string: what diverged, "gradient", "position" or "objective"
+    Step int: the step in which the divergence was detected
+    Value float64: the offending value
+*/
+type DivergenceError struct {
+    Kind int
+    Quantity string
+    Step int
+    Value float64
+}
+
+func (e *DivergenceError) Error() string {
+    switch e.Kind {
+        case NAN_DIVERGENCE:
+            return fmt.Sprintf("NaN %s encountered at step %d", e.Quantity, e.Step)
+        case INF_DIVERGENCE:
+            return fmt.Sprintf("infinite %s encountered at step %d", e.Quantity, e.Step)
+    }
+    return fmt.Sprintf("exploding %s (%g) encountered at step %d", e.Quantity, e.Value, e.Step)
+}
+
+
+/*
+SUMMARY
+    Checks that the hyperparameter is strictly positive (NaN is rejected as well).
+PARAMETERS
+    Optimiser string: the name of the optimiser
+    Parameter string: the name of the hyperparameter
+    Value float64: the value of the hyperparameter
+RETURN
+    error: nil or *ParameterError
+*/
+func positive(Optimiser, Parameter string, Value float64) error {
+    if !(Value > 0) || math.IsInf(Value, 1) {
+        return &ParameterError{Optimiser: Optimiser, Parameter: Parameter, Value: Value, Expected: "positive and finite"}
+    }
+    return nil
+}
+
+
+/*
+SUMMARY
+    Checks that the hyperparameter is non-negative (NaN is rejected as well).
+PARAMETERS
+    Optimiser string: the name of the optimiser
+    Parameter string: the name of the hyperparameter
+    Value float64: the value of the hyperparameter
+RETURN
+    error: nil or *ParameterError
+*/
+func nonNegative(Optimiser, Parameter string, Value float64) error {
+    if !(Value >= 0) || math.IsInf(Value, 1) {
+        return &ParameterError{Optimiser: Optimiser, Parameter: Parameter, Value: Value, Expected: "non-negative and finite"}
+    }
+    return nil
+}
+
+
+/*
+SUMMARY
+    Checks that the hyperparameter is in the range [0,1).
+PARAMETERS
+    Optimiser string: the name of the optimiser
+    Parameter string: the name of the hyperparameter
+    Value float64: the value of the hyperparameter
+RETURN
+    error: nil or *ParameterError
+*/
+func unitInterval(Optimiser, Parameter string, Value float64) error {
+    if !(Value >= 0 && Value < 1) {
+        return &ParameterError{Optimiser: Optimiser, Parameter: Parameter, Value: Value, Expected: "in range [0,1)"}
+    }
+    return nil
+}
+
+
+/*
+SUMMARY
+    Checks that the hyperparameter is in the range (0,1).
+PARAMETERS
+    Optimiser string: the name of the optimiser
+    Parameter string: the name of the hyperparameter
+    Value float64: the value of the hyperparameter
+RETURN
+    error: nil or *ParameterError
+*/
+func openUnitInterval(Optimiser, Parameter string, Value float64) error {
+    if !(Value > 0 && Value < 1) {
+        return &ParameterError{Optimiser: Optimiser, Parameter: Parameter, Value: Value, Expected: "in range (0,1)"}
+    }
+    return nil
+}
+
+
+/*
+SUMMARY
+    Returns the first non-nil error.
+PARAMETERS
+    Errors ...error: the errors of the validations
+RETURN
+    error: the first error or nil
+*/
+func firstError(Errors ...error) error {
+    for _, err := range Errors {
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+
+/*
+SUMMARY
+    Checks a vector (gradient or position) for NaN and Inf values.
+PARAMETERS
+    Values []float64: the vector to check
+    Quantity string: the name of the vector reported in the error
+    Step int: the current step
+RETURN
+    error: nil or *DivergenceError
+*/
+func checkFinite(Values []float64, Quantity string, Step int) error {
+    for _, v := range Values {
+        if math.IsNaN(v) {
+            return &DivergenceError{Kind: NAN_DIVERGENCE, Quantity: Quantity, Step: Step, Value: v}
+        }
+        if math.IsInf(v, 0) {
+            return &DivergenceError{Kind: INF_DIVERGENCE, Quantity: Quantity, Step: Step, Value: v}
+        }
+    }
+    return nil
+}
+
+
+/*
+SUMMARY
+    Wraps an optimiser so that the objective is evaluated after every step. It reports a divergence when
+    the objective becomes NaN/Inf or grows above GrowthFactor times the magnitude of the first objective value
+    (at least GrowthFactor).
+PARAMETERS
+    Step Optimiser: the optimiser to monitor
+    F func ([]float64) float64: the objective function
+    GrowthFactor float64: how many times the objective may grow before we call it exploding, must be > 1
+RETURN
+    Optimiser: the monitored optimiser
+    error: *ParameterError if GrowthFactor is invalid
+*/
+func MonitorObjective(Step Optimiser, F func ([]float64) float64, GrowthFactor float64) (Optimiser, error) {
+    if !(GrowthFactor > 1) {
+        return nil, &ParameterError{Optimiser: "MonitorObjective", Parameter: "GrowthFactor", Value: GrowthFactor, Expected: "greater than 1"}
+    }
+    Limit := 0.0
+    Started := false
+    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int, error) {
+        if !Started {
+            Limit = GrowthFactor * math.Max(math.Abs(F(At)), 1.0)
+            Started = true
+        }
+        NewAt, Terminate, Steps, err := Step(Derivative, At)
+        if err != nil {
+            return NewAt, Terminate, Steps, err
+        }
+        Value := F(NewAt)
+        if err := checkFinite([]float64{Value}, "objective", Steps); err != nil {
+            return At, Terminate, Steps, err
+        }
+        if Value > Limit {
+            return At, Terminate, Steps, &DivergenceError{Kind: EXPLODING_DIVERGENCE, Quantity: "objective", Step: Steps, Value: Value}
+        }
+        return NewAt, Terminate, Steps, nil
+    }, nil
+}
@@ -0,0 +1,91 @@
+package optimisers
+
+import (
+    "golang.org/x/exp/rand"
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+)
+
+
+/*
+This type configures the minibatch training loop.
+    BatchSize int: the number of examples averaged in one gradient, the last batch of an epoch may be smaller
+    Epochs int: the maximal number of passes over the dataset
+    Seed int: seed of the random number generator shuffling the dataset before each epoch
+    Tolerance float64: the loop stops once the position moves less than Tolerance over a whole epoch,
+        0 means that every epoch is run
+    Callback func(int, []float64): if not nil it is called after every epoch with the epoch index
+        and the current position (useful for printing the loss)
+*/
+type MinibatchOptions struct {
+    BatchSize int
+    Epochs int
+    Seed int
+    Tolerance float64
+    Callback func(int, []float64)
+}
+
+
+/*
+SUMMARY
+    Minibatch training loop, this is what makes the optimisers of this package truly stochastic.
+    Before each epoch the examples are shuffled, then for every minibatch the per-example gradients
+    are averaged and the average is fed to the optimiser as if it was the full-batch derivative.
+    The convergence flag of the optimiser is ignored, a single noisy minibatch may barely move the
+    position, instead the loop stops early once the position moves less than Options.Tolerance over an epoch.
+PARAMETERS
+    Step Optimiser: any optimiser of this package e.g. SGD(0.01, 1e-6)
+    ExampleGradient func([]float64, []float64) []float64: the gradient of the loss of one example,
+        the first argument is the position, the second one is the example
+    Dataset *mat.Dense: each row is one example
+    At []float64: the starting position
+    Options MinibatchOptions: batch size, number of epochs, seed and tolerance
+RETURN
+    []float64: the final position (the last valid one when the optimisation diverged)
+    bool: whether the convergence has happened
+    int: the number of steps taken by the optimiser
+    error: *ParameterError for invalid options or the *DivergenceError of the optimiser
+*/
+func MinibatchOptimise(Step Optimiser, ExampleGradient func([]float64, []float64) []float64, Dataset *mat.Dense, At []float64, Options MinibatchOptions) ([]float64, bool, int, error) {
+    N, _ := Dataset.Dims()
+    err := firstError(positive("MinibatchOptimise", "BatchSize", float64(Options.BatchSize)),
+                      positive("MinibatchOptimise", "Epochs", float64(Options.Epochs)),
+                      nonNegative("MinibatchOptimise", "Tolerance", Options.Tolerance))
+    if err != nil { return At, false, 0, err }
+
+    randGen := rand.New(rand.NewSource(uint64(Options.Seed)))
+    Steps := 0
+    Converged := false
+    var Batch []int
+    // the optimisers only see a function of the position, the current minibatch is captured here
+    Derivative := func (X []float64) []float64 {
+        Mean := make([]float64, len(X))
+        for _, index := range Batch {
+            Gradient := ExampleGradient(X, Dataset.RawRowView(index))
+            for i := range Mean {
+                Mean[i] += Gradient[i]
+            }
+        }
+        for i := range Mean {
+            Mean[i] /= float64(len(Batch))
+        }
+        return Mean
+    }
+
+    for epoch:=0; epoch<Options.Epochs && !Converged; epoch++ {
+        Order := randGen.Perm(N)
+        Start := append([]float64{}, At...)
+        for start:=0; start<N; start+=Options.BatchSize {
+            end := start + Options.BatchSize
+            if end > N { end = N }
+            Batch = Order[start:end]
+            At, _, Steps, err = Step(Derivative, At)
+            if err != nil { return At, false, Steps, err }
+        }
+        Converged = floats.Distance(Start, At, 2) < Options.Tolerance
+        if Options.Callback != nil {
+            Options.Callback(epoch, At)
+        }
+    }
+    return At, Converged, Steps, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+    "fmt"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/stat/distuv"
+
+    "ml_playground/optimisers"
+)
+
+// random number seed and source
+var randSeed = 10
+var randSrc = rand.NewSource(uint64(randSeed))
+
+
+/*
+SUMMARY
+    The gradient of the squared error of one example in the linear model y = w0*x + w1.
+PARAMETERS
+    W []float64: the parameters (w0, w1)
+    Example []float64: one row of the dataset (x, y)
+RETURN
+    []float64: the gradient of (w0*x + w1 - y)^2 with respect to W
+*/
+func SquaredErrorGradient(W, Example []float64) []float64 {
+    x, y := Example[0], Example[1]
+    residual := W[0]*x + W[1] - y
+    return []float64{2.0 * residual * x, 2.0 * residual}
+}
+
+
+/*
+SUMMARY
+    The mean squared error of the linear model on the whole dataset.
+PARAMETERS
+    W []float64: the parameters (w0, w1)
+    Dataset *mat.Dense: each row is an example (x, y)
+RETURN
+    float64: the loss
+*/
+func MeanSquaredError(W []float64, Dataset *mat.Dense) float64 {
+    N, _ := Dataset.Dims()
+    loss := 0.0
+    for i:=0; i<N; i++ {
+        residual := W[0]*Dataset.At(i, 0) + W[1] - Dataset.At(i, 1)
+        loss += residual * residual
+    }
+    return loss / float64(N)
+}
+
+
+/*
+We generate the same kind of data as in the Bayesian linear regression program (y = -1.3x + 0.5 + noise)
+but with far more points, then fit the line with minibatches using SGD and Adam.
+*/
+func main() {
+    N := 20000
+    uniform := distuv.Uniform{Min: -1.0, Max: 1.0, Src: randSrc}
+    normal := distuv.Normal{Mu: 0.0, Sigma: 0.3, Src: randSrc}
+    Dataset := mat.NewDense(N, 2, nil)
+    for i:=0; i<N; i++ {
+        x := uniform.Rand()
+        Dataset.Set(i, 0, x)
+        Dataset.Set(i, 1, -1.3*x + 0.5 + normal.Rand())
+    }
+
+    Options := optimisers.MinibatchOptions{
+        BatchSize: 64,
+        Epochs: 5,
+        Seed: randSeed,
+        Tolerance: 1e-3,
+        Callback: func (Epoch int, At []float64) {
+            fmt.Println("Epoch", Epoch, "w", At, "loss", MeanSquaredError(At, Dataset))
+        },
+    }
+
+    fmt.Println("SGD")
+    sgd, err := optimisers.SGD(0.01, 1e-6)
+    if err != nil { panic(err) }
+    At, Converged, Steps, err := optimisers.MinibatchOptimise(sgd, SquaredErrorGradient, Dataset, []float64{0.0, 0.0}, Options)
+    if err != nil { panic(err) }
+    fmt.Println("w", At, "converged", Converged, "steps", Steps)
+
+    fmt.Println("Adam")
+    adam, err := optimisers.Adam(0.01, 0.9, 0.999, 1e-8, 1e-6)
+    if err != nil { panic(err) }
+    At, Converged, Steps, err = optimisers.MinibatchOptimise(adam, SquaredErrorGradient, Dataset, []float64{0.0, 0.0}, Options)
+    if err != nil { panic(err) }
+    fmt.Println("w", At, "converged", Converged, "steps", Steps)
+}
@@ -27,40 +27,51 @@ direction we take a step and arrive at a new location. We repeat this until conv
 */
 
 
+// the signature shared by every gradient-only optimiser in this package
+type Optimiser func(func ([]float64) []float64, []float64) ([]float64, bool, int, error)
+
+// the signature of the optimisers which need the objective function as well
+type LineSearchOptimiser func(func ([]float64) float64, func ([]float64) []float64, []float64) ([]float64, bool, int, error)
+
 
 
 
 /*
 SUMMARY
     Gradient descent with constant step size; or stochastic gradient descent (the name we know it from
-    Computer Science).
+    Computer Science). On its own it consumes the full-batch derivative, it becomes stochastic when
+    driven by MinibatchOptimise.
 PARAMETERS
     StepSize float64: step size or learning rate
     ConvergeEpsilon: epsilon in the convergence criterion
 RETURN
-    func(func ([]float64) []float64, []float64) ([]float64, bool, int): a function that takes
-        a gradient function and a position and return the new position, boolean whether the convergence
-        has happened and the number of steps already taken place
+    Optimiser: a function that takes a gradient function and a position and return the new position,
+        boolean whether the convergence has happened, the number of steps already taken place and
+        *DivergenceError if the gradient or the new position is NaN/Inf
+    error: *ParameterError if a hyperparameter is invalid
 */
-func SGD(StepSize, ConvergeEpsilon float64) func(func ([]float64) []float64, []float64) ([]float64, bool, int) {
+func SGD(StepSize, ConvergeEpsilon float64) (Optimiser, error) {
     Steps := 0
     Terminate := false
 
-    if StepSize <= 0 { panic("Negative/0 step size/learning rate encountered") }
-    if ConvergeEpsilon <= 0 { panic("Negative/0 epsilon encountered") }
+    err := firstError(positive("SGD", "StepSize", StepSize),
+                      positive("SGD", "ConvergeEpsilon", ConvergeEpsilon))
+    if err != nil { return nil, err }
 
-    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int) {
+    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int, error) {
+        Steps++
         Gradient := Derivative(At)
+        if err := checkFinite(Gradient, "gradient", Steps); err != nil { return At, Terminate, Steps, err }
         NewAt := make([]float64, len(Gradient))
         for i := range NewAt {
             NewAt[i] = At[i] - StepSize * Gradient[i]
         }
-        Steps++
+        if err := checkFinite(NewAt, "position", Steps); err != nil { return At, Terminate, Steps, err }
         if floats.Distance(At, NewAt, 2) < ConvergeEpsilon {
             Terminate = true
         }
-        return NewAt, Terminate, Steps
-    }
+        return NewAt, Terminate, Steps, nil
+    }, nil
 }
 
 
@@ -72,35 +83,39 @@ PARAMETERS
     Friction float64: determines how much momentum is taken into account
     ConvergeEpsilon: epsilon in the convergence criterion
 RETURN
-    func(func ([]float64) []float64, []float64) ([]float64, bool, int): a function that takes
-        a gradient function and a position and return the new position, boolean whether the convergence
-        has happened and the number of steps already taken place
+    Optimiser: a function that takes a gradient function and a position and return the new position,
+        boolean whether the convergence has happened, the number of steps already taken place and
+        *DivergenceError if the gradient or the new position is NaN/Inf
+    error: *ParameterError if a hyperparameter is invalid
 */
-func SGDMomentum(StepSize, Friction, ConvergeEpsilon float64) func(func ([]float64) []float64, []float64) ([]float64, bool, int) {
+func SGDMomentum(StepSize, Friction, ConvergeEpsilon float64) (Optimiser, error) {
     Steps := 0
     var Velocity []float64
     Terminate := false
 
-    if StepSize <= 0 { panic("Negative/0 step size/learning rate encountered") }
-    if Friction <= 0 { panic("Negative value encountered as initial value for the gradient accumulator") }
-    if ConvergeEpsilon <= 0 { panic("Negative/0 epsilon encountered") }
+    err := firstError(positive("SGDMomentum", "StepSize", StepSize),
+                      unitInterval("SGDMomentum", "Friction", Friction),
+                      positive("SGDMomentum", "ConvergeEpsilon", ConvergeEpsilon))
+    if err != nil { return nil, err }
 
-    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int) {
+    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int, error) {
+        Steps++
         if len(Velocity) == 0 {
             Velocity = make([]float64, len(At))
         }
         Gradient := Derivative(At)
+        if err := checkFinite(Gradient, "gradient", Steps); err != nil { return At, Terminate, Steps, err }
         NewAt := make([]float64, len(Gradient))
         for i := range NewAt {
             Velocity[i] = Friction*Velocity[i] + StepSize*Gradient[i]
             NewAt[i] = At[i] - Velocity[i]
         }
-        Steps++
+        if err := checkFinite(NewAt, "position", Steps); err != nil { return At, Terminate, Steps, err }
         if floats.Distance(At, NewAt, 2) < ConvergeEpsilon {
             Terminate = true
         }
-        return NewAt, Terminate, Steps
-    }
+        return NewAt, Terminate, Steps, nil
+    }, nil
 }
 
 
@@ -112,20 +127,23 @@ PARAMETERS
     Friction float64: determines how much momentum is taken into account
     ConvergeEpsilon: epsilon in the convergence criterion
 RETURN
-    func(func ([]float64) []float64, []float64) ([]float64, bool, int): a function that takes
-        a gradient function and a position and return the new position, boolean whether the convergence
-        has happened and the number of steps already taken place
+    Optimiser: a function that takes a gradient function and a position and return the new position,
+        boolean whether the convergence has happened, the number of steps already taken place and
+        *DivergenceError if the gradient or the new position is NaN/Inf
+    error: *ParameterError if a hyperparameter is invalid
 */
-func NesterovAcceleratedGradient(StepSize, Friction, ConvergeEpsilon float64) func(func ([]float64) []float64, []float64) ([]float64, bool, int) {
+func NesterovAcceleratedGradient(StepSize, Friction, ConvergeEpsilon float64) (Optimiser, error) {
     Steps := 0
     var Velocity []float64
     Terminate := false
 
-    if StepSize <= 0 { panic("Negative/0 step size/learning rate encountered") }
-    if Friction <= 0 { panic("Negative value encountered as initial value for the gradient accumulator") }
-    if ConvergeEpsilon <= 0 { panic("Negative/0 epsilon encountered") }
+    err := firstError(positive("NesterovAcceleratedGradient", "StepSize", StepSize),
+                      unitInterval("NesterovAcceleratedGradient", "Friction", Friction),
+                      positive("NesterovAcceleratedGradient", "ConvergeEpsilon", ConvergeEpsilon))
+    if err != nil { return nil, err }
 
-    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int) {
+    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int, error) {
+        Steps++
         if len(Velocity) == 0 {
             Velocity = make([]float64, len(At))
         }
@@ -134,18 +152,19 @@ func NesterovAcceleratedGradient(StepSize, Friction, ConvergeEpsilon float64) fu
             ModifiedAt[i] = At[i] - Friction * Velocity[i]
         }
         Gradient := Derivative(ModifiedAt)
+        if err := checkFinite(Gradient, "gradient", Steps); err != nil { return At, Terminate, Steps, err }
 
         NewAt := make([]float64, len(Gradient))
         for i := range NewAt {
             Velocity[i] = Friction*Velocity[i] + StepSize*Gradient[i]
             NewAt[i] = At[i] - Velocity[i]
         }
-        Steps++
+        if err := checkFinite(NewAt, "position", Steps); err != nil { return At, Terminate, Steps, err }
         if floats.Distance(At, NewAt, 2) < ConvergeEpsilon {
             Terminate = true
         }
-        return NewAt, Terminate, Steps
-    }
+        return NewAt, Terminate, Steps, nil
+    }, nil
 }
 
 
@@ -156,22 +175,27 @@ PARAMETERS
     Beta float64: parameter that sets what step sizes the algorithm will choose from
     ConvergeEpsilon: epsilon in the convergence criterion
 RETURN
-    func(func ([]float64) float64, func ([]float64) []float64, []float64) ([]float64, bool, int):
-        a function that takes the objective function a gradient function and a position and return
-        the new position, boolean whether the convergence has happened and the number of steps already
-        taken place
+    LineSearchOptimiser: a function that takes the objective function a gradient function and a position
+        and return the new position, boolean whether the convergence has happened, the number of steps
+        already taken place and *DivergenceError if the gradient, the objective or the new position is NaN/Inf
+    error: *ParameterError if a hyperparameter is invalid
 */
-func BacktrackingLineSearch(Beta, ConvergeEpsilon float64) func(func ([]float64) float64, func ([]float64) []float64, []float64) ([]float64, bool, int) {
+func BacktrackingLineSearch(Beta, ConvergeEpsilon float64) (LineSearchOptimiser, error) {
     Steps := 0
     Terminate := false
 
-    if Beta <= 0 || Beta >= 1 { panic("Negative/0 beta parameter encountered in BacktrackingLineSearch") }
-    if ConvergeEpsilon <= 0 { panic("Negative/0 epsilon encountered") }
+    err := firstError(openUnitInterval("BacktrackingLineSearch", "Beta", Beta),
+                      positive("BacktrackingLineSearch", "ConvergeEpsilon", ConvergeEpsilon))
+    if err != nil { return nil, err }
 
-    return func(F func ([]float64) float64, Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int) {
+    return func(F func ([]float64) float64, Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int, error) {
+        Steps++
         Gradient := Derivative(At)
+        if err := checkFinite(Gradient, "gradient", Steps); err != nil { return At, Terminate, Steps, err }
         SearchAt := make([]float64, len(At))
 
+        Objective := F(At)
+        if err := checkFinite([]float64{Objective}, "objective", Steps); err != nil { return At, Terminate, Steps, err }
         LeftHandSide := 1.0
         RightHandSide := 0.0
         NormSquare := math.Pow(floats.Norm(Gradient, 2), 2)
@@ -181,15 +205,16 @@ func BacktrackingLineSearch(Beta, ConvergeEpsilon float64) func(func ([]float64)
                 SearchAt[i] = At[i] - t * Gradient[i]
             }
             LeftHandSide = F(SearchAt)
-            RightHandSide = F(At) - t / 2.0 * NormSquare
+            RightHandSide = Objective - t / 2.0 * NormSquare
             t *= Beta
         }
-        Steps++
+        if err := checkFinite([]float64{LeftHandSide}, "objective", Steps); err != nil { return At, Terminate, Steps, err }
+        if err := checkFinite(SearchAt, "position", Steps); err != nil { return At, Terminate, Steps, err }
         if floats.Distance(At, SearchAt, 2) < ConvergeEpsilon {
             Terminate = true
         }
-        return SearchAt, Terminate, Steps
-    }
+        return SearchAt, Terminate, Steps, nil
+    }, nil
 }
 
 
@@ -203,37 +228,42 @@ PARAMETERS
     Epsilon float64: ensures the denominator is not zero
     ConvergeEpsilon: epsilon in the convergence criterion
 RETURN
-    func(func ([]float64) []float64, []float64) ([]float64, bool, int): a function that takes
-        a gradient function and a position and return the new position, boolean whether the convergence
-        has happened and the number of steps already taken place
+    Optimiser: a function that takes a gradient function and a position and return the new position,
+        boolean whether the convergence has happened, the number of steps already taken place and
+        *DivergenceError if the gradient or the new position is NaN/Inf
+    error: *ParameterError if a hyperparameter is invalid
 */
-func Adagrad(StepSize, InitialAccumulatorValue, Epsilon, ConvergeEpsilon float64) func(func ([]float64) []float64, []float64) ([]float64, bool, int) {
+func Adagrad(StepSize, InitialAccumulatorValue, Epsilon, ConvergeEpsilon float64) (Optimiser, error) {
     Steps := 0
     var G []float64
     Terminate := false
 
-    if StepSize <= 0 { panic("Negative/0 step size/learning rate encountered") }
-    if InitialAccumulatorValue < 0 { panic("Negative value encountered as initial value for the gradient accumulator") }
-    if Epsilon <= 0 || ConvergeEpsilon <= 0 { panic("Negative/0 epsilon encountered") }
+    err := firstError(positive("Adagrad", "StepSize", StepSize),
+                      nonNegative("Adagrad", "InitialAccumulatorValue", InitialAccumulatorValue),
+                      positive("Adagrad", "Epsilon", Epsilon),
+                      positive("Adagrad", "ConvergeEpsilon", ConvergeEpsilon))
+    if err != nil { return nil, err }
 
-    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int) {
+    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int, error) {
+        Steps++
         if len(G) == 0 {
             G = make([]float64, len(At))
             floats.AddConst(InitialAccumulatorValue, G)
         }
         Gradient := Derivative(At)
+        if err := checkFinite(Gradient, "gradient", Steps); err != nil { return At, Terminate, Steps, err }
 
         NewAt := make([]float64, len(Gradient))
         for i := range NewAt {
             G[i] = G[i] + Gradient[i] * Gradient[i]
             NewAt[i] = At[i] - StepSize / (math.Sqrt(G[i] + Epsilon)) * Gradient[i]
         }
-        Steps++
+        if err := checkFinite(NewAt, "position", Steps); err != nil { return At, Terminate, Steps, err }
         if floats.Distance(At, NewAt, 2) < ConvergeEpsilon {
             Terminate = true
         }
-        return NewAt, Terminate, Steps
-    }
+        return NewAt, Terminate, Steps, nil
+    }, nil
 }
 
 /*
@@ -245,25 +275,29 @@ PARAMETERS
     Epsilon float64: ensures the denominator is not zero
     ConvergeEpsilon: epsilon in the convergence criterion
 RETURN
-    func(func ([]float64) []float64, []float64) ([]float64, bool, int): a function that takes
-        a gradient function and a position and return the new position, boolean whether the convergence
-        has happened and the number of steps already taken place
+    Optimiser: a function that takes a gradient function and a position and return the new position,
+        boolean whether the convergence has happened, the number of steps already taken place and
+        *DivergenceError if the gradient or the new position is NaN/Inf
+    error: *ParameterError if a hyperparameter is invalid
 */
-func Adadelta(Decay, Epsilon, ConvergeEpsilon float64) func(func ([]float64) []float64, []float64) ([]float64, bool, int) {
+func Adadelta(Decay, Epsilon, ConvergeEpsilon float64) (Optimiser, error) {
     Steps := 0
     var G, Delta []float64
     Terminate := false
 
-    if Decay < 0 || Decay > 1 { panic("Adadelta's decay is outside of range [0,1)") }
-    if Epsilon <= 0 || ConvergeEpsilon <= 0 { panic("Negative/0 epsilon encountered") }
+    err := firstError(unitInterval("Adadelta", "Decay", Decay),
+                      positive("Adadelta", "Epsilon", Epsilon),
+                      positive("Adadelta", "ConvergeEpsilon", ConvergeEpsilon))
+    if err != nil { return nil, err }
 
-    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int) {
+    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int, error) {
         Steps++
         if len(G) == 0 {
             G = make([]float64, len(At))
             Delta = make([]float64, len(At))
         }
         Gradient := Derivative(At)
+        if err := checkFinite(Gradient, "gradient", Steps); err != nil { return At, Terminate, Steps, err }
 
         NewAt := make([]float64, len(Gradient))
         for i := range NewAt {
@@ -272,11 +306,12 @@ func Adadelta(Decay, Epsilon, ConvergeEpsilon float64) func(func ([]float64) []f
             Delta[i] = Decay * Delta[i] + (1-Decay) * Update * Update
             NewAt[i] = At[i] + Update
         }
+        if err := checkFinite(NewAt, "position", Steps); err != nil { return At, Terminate, Steps, err }
         if floats.Distance(At, NewAt, 2) < ConvergeEpsilon {
             Terminate = true
         }
-        return NewAt, Terminate, Steps
-    }
+        return NewAt, Terminate, Steps, nil
+    }, nil
 }
 
 
@@ -289,36 +324,41 @@ PARAMETERS
     Epsilon float64: ensures the denominator is not zero
     ConvergeEpsilon: epsilon in the convergence criterion
 RETURN
-    func(func ([]float64) []float64, []float64) ([]float64, bool, int): a function that takes
-        a gradient function and a position and return the new position, boolean whether the convergence
-        has happened and the number of steps already taken place
+    Optimiser: a function that takes a gradient function and a position and return the new position,
+        boolean whether the convergence has happened, the number of steps already taken place and
+        *DivergenceError if the gradient or the new position is NaN/Inf
+    error: *ParameterError if a hyperparameter is invalid
 */
-func RMSprop(StepSize, Decay, Epsilon, ConvergeEpsilon float64) func(func ([]float64) []float64, []float64) ([]float64, bool, int) {
+func RMSprop(StepSize, Decay, Epsilon, ConvergeEpsilon float64) (Optimiser, error) {
     Steps := 0
     var G []float64
     Terminate := false
 
-    if StepSize <= 0 { panic("Negative/0 step size/learning rate encountered") }
-    if Decay < 0 || Decay > 1 { panic("RMSprop's decay is outside of range [0,1)") }
-    if Epsilon <= 0 || ConvergeEpsilon <= 0 { panic("Negative/0 epsilon encountered") }
+    err := firstError(positive("RMSprop", "StepSize", StepSize),
+                      unitInterval("RMSprop", "Decay", Decay),
+                      positive("RMSprop", "Epsilon", Epsilon),
+                      positive("RMSprop", "ConvergeEpsilon", ConvergeEpsilon))
+    if err != nil { return nil, err }
 
-    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int) {
+    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int, error) {
+        Steps++
         if len(G) == 0 {
             G = make([]float64, len(At))
         }
         Gradient := Derivative(At)
+        if err := checkFinite(Gradient, "gradient", Steps); err != nil { return At, Terminate, Steps, err }
 
         NewAt := make([]float64, len(Gradient))
         for i := range NewAt {
             G[i] = Decay * G[i] + (1-Decay) * Gradient[i] * Gradient[i]
             NewAt[i] = At[i] - StepSize / (math.Sqrt(G[i] + Epsilon)) * Gradient[i]
         }
-        Steps++
+        if err := checkFinite(NewAt, "position", Steps); err != nil { return At, Terminate, Steps, err }
         if floats.Distance(At, NewAt, 2) < ConvergeEpsilon {
             Terminate = true
         }
-        return NewAt, Terminate, Steps
-    }
+        return NewAt, Terminate, Steps, nil
+    }, nil
 }
 
 /*
@@ -331,21 +371,24 @@ PARAMETERS
     Epsilon float64: ensures the denominator is not zero
     ConvergeEpsilon: epsilon in the convergence criterion
 RETURN
-    func(func ([]float64) []float64, []float64) ([]float64, bool, int): a function that takes
-        a gradient function and a position and return the new position, boolean whether the convergence
-        has happened and the number of steps already taken place
+    Optimiser: a function that takes a gradient function and a position and return the new position,
+        boolean whether the convergence has happened, the number of steps already taken place and
+        *DivergenceError if the gradient or the new position is NaN/Inf
+    error: *ParameterError if a hyperparameter is invalid
 */
-func Adam(StepSize, Beta1, Beta2, Epsilon, ConvergeEpsilon float64) func(func ([]float64) []float64, []float64) ([]float64, bool, int) {
+func Adam(StepSize, Beta1, Beta2, Epsilon, ConvergeEpsilon float64) (Optimiser, error) {
     Steps := 0
     var M, MHat, V, VHat []float64
     Terminate := false
 
-    if StepSize <= 0 { panic("Negative/0 step size/learning rate encountered") }
-    if Beta1 < 0 || Beta1 >= 1 { panic("Adam's beta1 is outside of range [0,1)") }
-    if Beta2 < 0 || Beta2 >= 1 { panic("Adam's beta2 is outside of range [0,1)") }
-    if Epsilon <= 0 || ConvergeEpsilon <= 0 { panic("Negative/0 epsilon encountered") }
+    err := firstError(positive("Adam", "StepSize", StepSize),
+                      unitInterval("Adam", "Beta1", Beta1),
+                      unitInterval("Adam", "Beta2", Beta2),
+                      positive("Adam", "Epsilon", Epsilon),
+                      positive("Adam", "ConvergeEpsilon", ConvergeEpsilon))
+    if err != nil { return nil, err }
 
-    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int) {
+    return func(Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int, error) {
         Steps++
         if len(M) == 0 {
             M = make([]float64, len(At))
@@ -354,6 +397,7 @@ func Adam(StepSize, Beta1, Beta2, Epsilon, ConvergeEpsilon float64) func(func ([
             VHat = make([]float64, len(At))
         }
         Gradient := Derivative(At)
+        if err := checkFinite(Gradient, "gradient", Steps); err != nil { return At, Terminate, Steps, err }
 
         NewAt := make([]float64, len(Gradient))
         for i := range NewAt {
@@ -363,9 +407,10 @@ func Adam(StepSize, Beta1, Beta2, Epsilon, ConvergeEpsilon float64) func(func ([
             VHat[i] = V[i] / (1 - math.Pow(Beta2, float64(Steps)))
             NewAt[i] = At[i] - StepSize * MHat[i] / (math.Sqrt(VHat[i]) + Epsilon)
         }
+        if err := checkFinite(NewAt, "position", Steps); err != nil { return At, Terminate, Steps, err }
         if floats.Distance(At, NewAt, 2) < ConvergeEpsilon {
             Terminate = true
         }
-        return NewAt, Terminate, Steps
-    }
+        return NewAt, Terminate, Steps, nil
+    }, nil
 }
@@ -157,14 +157,22 @@ func main() {
     steps := 0
 
     epsilon := 1e-4
-    optimiser1 := optimisers.SGD(0.01, epsilon)
-    optimiser2 := optimisers.SGDMomentum(0.01, 0.95, epsilon)
-    optimiser3 := optimisers.NesterovAcceleratedGradient(0.01, 0.95, epsilon)
-    optimiser4 := optimisers.BacktrackingLineSearch(0.9, epsilon)
-    optimiser5 := optimisers.Adagrad(0.8, 0.1, 1e-7, epsilon)
-    optimiser6 := optimisers.Adadelta(0.95, 1e-7, epsilon)
-    optimiser7 := optimisers.RMSprop(0.01, 0.9, 1e-8, epsilon)
-    optimiser8 := optimisers.Adam(0.5, 0.9, 0.999, 1e-8, epsilon)
+    optimiser1, err := optimisers.SGD(0.01, epsilon)
+    if err != nil { panic(err) }
+    optimiser2, err := optimisers.SGDMomentum(0.01, 0.95, epsilon)
+    if err != nil { panic(err) }
+    optimiser3, err := optimisers.NesterovAcceleratedGradient(0.01, 0.95, epsilon)
+    if err != nil { panic(err) }
+    optimiser4, err := optimisers.BacktrackingLineSearch(0.9, epsilon)
+    if err != nil { panic(err) }
+    optimiser5, err := optimisers.Adagrad(0.8, 0.1, 1e-7, epsilon)
+    if err != nil { panic(err) }
+    optimiser6, err := optimisers.Adadelta(0.95, 1e-7, epsilon)
+    if err != nil { panic(err) }
+    optimiser7, err := optimisers.RMSprop(0.01, 0.9, 1e-8, epsilon)
+    if err != nil { panic(err) }
+    optimiser8, err := optimisers.Adam(0.5, 0.9, 0.999, 1e-8, epsilon)
+    if err != nil { panic(err) }
 
     gm := pic.GifMaker{Width: 600, Height: 600, Delay:1}
 
@@ -184,14 +192,22 @@ func main() {
             gm.CollectFrames(p)
         }
 
-        At1, Finished1, steps = optimiser1(gradient, At1)
-        At2, Finished2, steps = optimiser2(gradient, At2)
-        At3, Finished3, steps = optimiser3(gradient, At3)
-        At4, Finished4, steps = optimiser4(F, gradient, At4)
-        At5, Finished5, steps = optimiser5(gradient, At5)
-        At6, Finished6, steps = optimiser6(gradient, At6)
-        At7, Finished7, steps = optimiser7(gradient, At7)
-        At8, Finished8, steps = optimiser8(gradient, At8)
+        At1, Finished1, steps, err = optimiser1(gradient, At1)
+        if err != nil { panic(err) }
+        At2, Finished2, steps, err = optimiser2(gradient, At2)
+        if err != nil { panic(err) }
+        At3, Finished3, steps, err = optimiser3(gradient, At3)
+        if err != nil { panic(err) }
+        At4, Finished4, steps, err = optimiser4(F, gradient, At4)
+        if err != nil { panic(err) }
+        At5, Finished5, steps, err = optimiser5(gradient, At5)
+        if err != nil { panic(err) }
+        At6, Finished6, steps, err = optimiser6(gradient, At6)
+        if err != nil { panic(err) }
+        At7, Finished7, steps, err = optimiser7(gradient, At7)
+        if err != nil { panic(err) }
+        At8, Finished8, steps, err = optimiser8(gradient, At8)
+        if err != nil { panic(err) }
 
         fmt.Println(Finished1, Finished2, Finished3, Finished4, Finished5, Finished6, Finished7, Finished8)
 
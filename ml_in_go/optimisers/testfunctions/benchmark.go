@@ -0,0 +1,170 @@
+package testfunctions
+
+import (
+    "fmt"
+    "math"
+    "strings"
+    "text/tabwriter"
+
+    "gonum.org/v1/gonum/floats"
+
+    "ml_playground/optimisers"
+)
+
+
+/*
+This type represents one optimiser in the benchmark. The optimisers carry state (velocity,
+accumulators, step counter), hence New must create a fresh one for every test function.
+    Name string: the name printed in the table
+    New func() (optimisers.LineSearchOptimiser, error): creates the optimiser, the gradient-only ones are wrapped by GradientOnly
+*/
+type Contestant struct {
+    Name string
+    New func() (optimisers.LineSearchOptimiser, error)
+}
+
+
+/*
+This type stores the outcome of one optimiser on one test function.
+    Function string: the name of the test function
+    Dims int: the dimension of the test function
+    Optimiser string: the name of the optimiser
+    Iterations int: the number of iterations until F(x)-MinimumValue dropped below the tolerance, -1 if never
+    Steps int: the number of iterations run in total
+    FinalError float64: F(x)-MinimumValue at the final position
+    FinalDistance float64: the distance of the final position from the closest global minimiser
+    Converged bool: whether the optimiser reported convergence
+    Diverged error: the *optimisers.DivergenceError that stopped the run, nil if it did not diverge
+*/
+type Result struct {
+    Function string
+    Dims int
+    Optimiser string
+    Iterations int
+    Steps int
+    FinalError float64
+    FinalDistance float64
+    Converged bool
+    Diverged error
+}
+
+
+/*
+SUMMARY
+    Turns a gradient-only optimiser into a line search optimiser by ignoring the objective function.
+PARAMETERS
+    New func() (optimisers.Optimiser, error): creates the optimiser
+RETURN
+    func() (optimisers.LineSearchOptimiser, error): creates the wrapped optimiser
+*/
+func GradientOnly(New func() (optimisers.Optimiser, error)) func() (optimisers.LineSearchOptimiser, error) {
+    return func() (optimisers.LineSearchOptimiser, error) {
+        step, err := New()
+        if err != nil { return nil, err }
+        return func(F func ([]float64) float64, Derivative func ([]float64) []float64, At []float64) ([]float64, bool, int, error) {
+            return step(Derivative, At)
+        }, nil
+    }
+}
+
+
+/*
+SUMMARY
+    Every optimiser of the optimisers package with the hyperparameters of the optimisers demo.
+PARAMETERS
+    ConvergeEpsilon float64: epsilon in the convergence criterion
+RETURN
+    []Contestant: the contestants
+*/
+func DefaultContestants(ConvergeEpsilon float64) []Contestant {
+    return []Contestant{
+        {Name: "SGD", New: GradientOnly(func() (optimisers.Optimiser, error) { return optimisers.SGD(0.01, ConvergeEpsilon) })},
+        {Name: "SGD with momentum", New: GradientOnly(func() (optimisers.Optimiser, error) { return optimisers.SGDMomentum(0.01, 0.9, ConvergeEpsilon) })},
+        {Name: "Nesterov", New: GradientOnly(func() (optimisers.Optimiser, error) { return optimisers.NesterovAcceleratedGradient(0.01, 0.9, ConvergeEpsilon) })},
+        {Name: "Backtracking line search", New: func() (optimisers.LineSearchOptimiser, error) { return optimisers.BacktrackingLineSearch(0.9, ConvergeEpsilon) }},
+        {Name: "Adagrad", New: GradientOnly(func() (optimisers.Optimiser, error) { return optimisers.Adagrad(0.8, 0.1, 1e-7, ConvergeEpsilon) })},
+        {Name: "Adadelta", New: GradientOnly(func() (optimisers.Optimiser, error) { return optimisers.Adadelta(0.95, 1e-7, ConvergeEpsilon) })},
+        {Name: "RMSprop", New: GradientOnly(func() (optimisers.Optimiser, error) { return optimisers.RMSprop(0.01, 0.9, 1e-8, ConvergeEpsilon) })},
+        {Name: "Adam", New: GradientOnly(func() (optimisers.Optimiser, error) { return optimisers.Adam(0.1, 0.9, 0.999, 1e-8, ConvergeEpsilon) })},
+    }
+}
+
+
+/*
+SUMMARY
+    Runs every contestant on every test function from the customary starting point.
+    A run stops when the optimiser reports convergence, when the optimiser reports divergence,
+    or after MaxIterations iterations.
+PARAMETERS
+    Functions []TestFunction: the test functions
+    Contestants []Contestant: the optimisers
+    MaxIterations int: the maximal number of iterations per run
+    Tolerance float64: a run reaches the tolerance when F(x)-MinimumValue < Tolerance
+RETURN
+    []Result: one result per (function, optimiser) pair, ordered by function then optimiser
+    error: the error of the first contestant that could not be created
+*/
+func Benchmark(Functions []TestFunction, Contestants []Contestant, MaxIterations int, Tolerance float64) ([]Result, error) {
+    var results []Result
+    for _, function := range Functions {
+        for _, contestant := range Contestants {
+            step, err := contestant.New()
+            if err != nil { return nil, err }
+            At := make([]float64, len(function.Start))
+            copy(At, function.Start)
+            result := Result{Function: function.Name, Dims: function.Dims, Optimiser: contestant.Name, Iterations: -1}
+            if function.F(At) - function.MinimumValue < Tolerance {
+                result.Iterations = 0
+            }
+            for i:=1; i<=MaxIterations; i++ {
+                At, result.Converged, _, result.Diverged = step(function.F, function.Gradient, At)
+                result.Steps = i
+                if result.Diverged != nil {
+                    break
+                }
+                if result.Iterations < 0 && function.F(At) - function.MinimumValue < Tolerance {
+                    result.Iterations = i
+                }
+                if result.Converged {
+                    break
+                }
+            }
+            result.FinalError = function.F(At) - function.MinimumValue
+            result.FinalDistance = math.Inf(1)
+            for _, minimum := range function.Minima {
+                result.FinalDistance = math.Min(result.FinalDistance, floats.Distance(At, minimum, 2))
+            }
+            results = append(results, result)
+        }
+    }
+    return results, nil
+}
+
+
+/*
+SUMMARY
+    Formats the benchmark results as an aligned text table.
+PARAMETERS
+    Results []Result: the output of Benchmark
+RETURN
+    string: the table
+*/
+func FormatTable(Results []Result) string {
+    var builder strings.Builder
+    writer := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(writer, "function\tdims\toptimiser\titers to tol\tsteps\tfinal error\tdistance\tconverged\tdiverged")
+    for _, r := range Results {
+        iterations := "-"
+        if r.Iterations >= 0 {
+            iterations = fmt.Sprint(r.Iterations)
+        }
+        diverged := "-"
+        if r.Diverged != nil {
+            diverged = r.Diverged.Error()
+        }
+        fmt.Fprintf(writer, "%s\t%d\t%s\t%s\t%d\t%.3e\t%.3e\t%t\t%s\n",
+                    r.Function, r.Dims, r.Optimiser, iterations, r.Steps, r.FinalError, r.FinalDistance, r.Converged, diverged)
+    }
+    writer.Flush()
+    return builder.String()
+}
@@ -0,0 +1,593 @@
+/*
+This library contains standard test functions for optimisation with their gradients, Hessians
+and known global minima. The N dimensional ones can be created in any dimension, the others
+are defined on the plane.
+*/
+package testfunctions
+
+import (
+    "math"
+    "gonum.org/v1/gonum/mat"
+)
+
+
+/*
+This type stores everything we know about a test function.
+    Name string: the name of the function (dimension included)
+    Dims int: the number of variables
+    F func ([]float64) float64: the objective function
+    Gradient func ([]float64) []float64: the gradient of F
+    Hessian func ([]float64) *mat.SymDense: the Hessian of F
+    Minima [][]float64: all the global minimisers
+    MinimumValue float64: the value of F at the global minima
+    Start []float64: the customary starting point of the optimisation
+*/
+type TestFunction struct {
+    Name string
+    Dims int
+    F func ([]float64) float64
+    Gradient func ([]float64) []float64
+    Hessian func ([]float64) *mat.SymDense
+    Minima [][]float64
+    MinimumValue float64
+    Start []float64
+}
+
+
+/*
+SUMMARY
+    Creates a slice where every element is the same.
+PARAMETERS
+    N int: the length of the slice
+    Value float64: the value of the elements
+RETURN
+    []float64: the slice
+*/
+func constant(N int, Value float64) []float64 {
+    slice := make([]float64, N)
+    for i := range slice { slice[i] = Value }
+    return slice
+}
+
+
+/*
+SUMMARY
+    The sphere function f(x) = sum x_i^2, the easiest convex test case.
+PARAMETERS
+    N int: the number of dimensions
+RETURN
+    TestFunction: the test function
+*/
+func Sphere(N int) TestFunction {
+    return TestFunction{
+        Name: "Sphere",
+        Dims: N,
+        F: func (x []float64) float64 {
+            sum := 0.0
+            for i := range x { sum += x[i] * x[i] }
+            return sum
+        },
+        Gradient: func (x []float64) []float64 {
+            grad := make([]float64, N)
+            for i := range x { grad[i] = 2.0 * x[i] }
+            return grad
+        },
+        Hessian: func (x []float64) *mat.SymDense {
+            hessian := mat.NewSymDense(N, nil)
+            for i:=0; i<N; i++ { hessian.SetSym(i, i, 2.0) }
+            return hessian
+        },
+        Minima: [][]float64{constant(N, 0.0)},
+        MinimumValue: 0.0,
+        Start: constant(N, 1.5),
+    }
+}
+
+
+/*
+SUMMARY
+    The Rosenbrock function f(x) = sum_{i<N-1} 100(x_{i+1}-x_i^2)^2 + (1-x_i)^2,
+    its minimum lies at the bottom of a long curved valley.
+PARAMETERS
+    N int: the number of dimensions, at least 2
+RETURN
+    TestFunction: the test function
+*/
+func Rosenbrock(N int) TestFunction {
+    if N < 2 { panic("Rosenbrock function needs at least 2 dimensions") }
+    start := make([]float64, N)
+    for i := range start {
+        if i % 2 == 0 { start[i] = -1.2 } else { start[i] = 1.0 }
+    }
+    return TestFunction{
+        Name: "Rosenbrock",
+        Dims: N,
+        F: func (x []float64) float64 {
+            sum := 0.0
+            for i:=0; i<N-1; i++ {
+                sum += 100.0 * math.Pow(x[i+1] - x[i]*x[i], 2) + math.Pow(1 - x[i], 2)
+            }
+            return sum
+        },
+        Gradient: func (x []float64) []float64 {
+            grad := make([]float64, N)
+            for i:=0; i<N; i++ {
+                if i < N-1 {
+                    grad[i] += -400.0 * x[i] * (x[i+1] - x[i]*x[i]) - 2.0 * (1 - x[i])
+                }
+                if i > 0 {
+                    grad[i] += 200.0 * (x[i] - x[i-1]*x[i-1])
+                }
+            }
+            return grad
+        },
+        Hessian: func (x []float64) *mat.SymDense {
+            hessian := mat.NewSymDense(N, nil)
+            for i:=0; i<N; i++ {
+                diagonal := 0.0
+                if i < N-1 {
+                    diagonal += 1200.0 * x[i]*x[i] - 400.0 * x[i+1] + 2.0
+                    hessian.SetSym(i, i+1, -400.0 * x[i])
+                }
+                if i > 0 {
+                    diagonal += 200.0
+                }
+                hessian.SetSym(i, i, diagonal)
+            }
+            return hessian
+        },
+        Minima: [][]float64{constant(N, 1.0)},
+        MinimumValue: 0.0,
+        Start: start,
+    }
+}
+
+
+/*
+SUMMARY
+    The Rastrigin function f(x) = 10N + sum x_i^2 - 10cos(2 pi x_i), highly multimodal
+    with a regular grid of local minima.
+PARAMETERS
+    N int: the number of dimensions
+RETURN
+    TestFunction: the test function
+*/
+func Rastrigin(N int) TestFunction {
+    return TestFunction{
+        Name: "Rastrigin",
+        Dims: N,
+        F: func (x []float64) float64 {
+            sum := 10.0 * float64(N)
+            for i := range x {
+                sum += x[i]*x[i] - 10.0 * math.Cos(2.0*math.Pi*x[i])
+            }
+            return sum
+        },
+        Gradient: func (x []float64) []float64 {
+            grad := make([]float64, N)
+            for i := range x {
+                grad[i] = 2.0 * x[i] + 20.0 * math.Pi * math.Sin(2.0*math.Pi*x[i])
+            }
+            return grad
+        },
+        Hessian: func (x []float64) *mat.SymDense {
+            hessian := mat.NewSymDense(N, nil)
+            for i := range x {
+                hessian.SetSym(i, i, 2.0 + 40.0 * math.Pi * math.Pi * math.Cos(2.0*math.Pi*x[i]))
+            }
+            return hessian
+        },
+        Minima: [][]float64{constant(N, 0.0)},
+        MinimumValue: 0.0,
+        Start: constant(N, 0.4),
+    }
+}
+
+
+/*
+SUMMARY
+    The Ackley function, nearly flat outer region with a deep hole at the origin.
+    f(x) = -20exp(-0.2 sqrt(sum x_i^2 / N)) - exp(sum cos(2 pi x_i) / N) + 20 + e
+PARAMETERS
+    N int: the number of dimensions
+RETURN
+    TestFunction: the test function
+*/
+func Ackley(N int) TestFunction {
+    n := float64(N)
+    // returns the radius r = sqrt(sum x_i^2 / N) and E = exp(sum cos(2 pi x_i) / N)
+    parts := func (x []float64) (float64, float64) {
+        sumSquares, sumCos := 0.0, 0.0
+        for i := range x {
+            sumSquares += x[i] * x[i]
+            sumCos += math.Cos(2.0*math.Pi*x[i])
+        }
+        return math.Sqrt(sumSquares / n), math.Exp(sumCos / n)
+    }
+    return TestFunction{
+        Name: "Ackley",
+        Dims: N,
+        F: func (x []float64) float64 {
+            r, E := parts(x)
+            return -20.0 * math.Exp(-0.2 * r) - E + 20.0 + math.E
+        },
+        Gradient: func (x []float64) []float64 {
+            r, E := parts(x)
+            grad := make([]float64, N)
+            for i := range x {
+                if r > 0 {
+                    grad[i] = 4.0 * math.Exp(-0.2 * r) * x[i] / (n * r)
+                }
+                grad[i] += 2.0 * math.Pi / n * math.Sin(2.0*math.Pi*x[i]) * E
+            }
+            return grad
+        },
+        Hessian: func (x []float64) *mat.SymDense {
+            r, E := parts(x)
+            hessian := mat.NewSymDense(N, nil)
+            A, APrime := 0.0, 0.0
+            if r > 0 {
+                A = 4.0 * math.Exp(-0.2 * r) / (n * r)
+                APrime = 4.0 / n * math.Exp(-0.2 * r) * (-0.2 / r - 1.0 / (r * r))
+            }
+            for i := range x {
+                for j:=i; j<N; j++ {
+                    v := - 4.0 * math.Pi * math.Pi / (n * n) * math.Sin(2.0*math.Pi*x[i]) * math.Sin(2.0*math.Pi*x[j]) * E
+                    if r > 0 {
+                        v += APrime * x[i] * x[j] / (n * r)
+                    }
+                    if i == j {
+                        v += A + 4.0 * math.Pi * math.Pi / n * math.Cos(2.0*math.Pi*x[i]) * E
+                    }
+                    hessian.SetSym(i, j, v)
+                }
+            }
+            return hessian
+        },
+        Minima: [][]float64{constant(N, 0.0)},
+        MinimumValue: 0.0,
+        Start: constant(N, 0.8),
+    }
+}
+
+
+/*
+SUMMARY
+    The Styblinski-Tang function f(x) = 1/2 sum x_i^4 - 16x_i^2 + 5x_i.
+PARAMETERS
+    N int: the number of dimensions
+RETURN
+    TestFunction: the test function
+*/
+func StyblinskiTang(N int) TestFunction {
+    // the minimiser in one coordinate is the root of 2x^3 - 16x + 2.5 near -2.9, we polish it with Newton's method
+    root := -2.9
+    for i:=0; i<50; i++ {
+        root -= (2.0*root*root*root - 16.0*root + 2.5) / (6.0*root*root - 16.0)
+    }
+    f := func (x []float64) float64 {
+        sum := 0.0
+        for i := range x {
+            sum += math.Pow(x[i], 4) - 16.0 * x[i]*x[i] + 5.0 * x[i]
+        }
+        return sum / 2.0
+    }
+    minimum := constant(N, root)
+    return TestFunction{
+        Name: "Styblinski-Tang",
+        Dims: N,
+        F: f,
+        Gradient: func (x []float64) []float64 {
+            grad := make([]float64, N)
+            for i := range x {
+                grad[i] = 2.0 * x[i]*x[i]*x[i] - 16.0 * x[i] + 2.5
+            }
+            return grad
+        },
+        Hessian: func (x []float64) *mat.SymDense {
+            hessian := mat.NewSymDense(N, nil)
+            for i := range x {
+                hessian.SetSym(i, i, 6.0 * x[i]*x[i] - 16.0)
+            }
+            return hessian
+        },
+        Minima: [][]float64{minimum},
+        MinimumValue: f(minimum),
+        Start: constant(N, -1.0),
+    }
+}
+
+
+/*
+SUMMARY
+    The Griewank function f(x) = 1 + sum x_i^2/4000 - prod cos(x_i/sqrt(i)) with i starting from 1.
+PARAMETERS
+    N int: the number of dimensions
+RETURN
+    TestFunction: the test function
+*/
+func Griewank(N int) TestFunction {
+    // product of the cosines skipping the indices a and b
+    cosProduct := func (x []float64, a, b int) float64 {
+        prod := 1.0
+        for i := range x {
+            if i != a && i != b {
+                prod *= math.Cos(x[i] / math.Sqrt(float64(i+1)))
+            }
+        }
+        return prod
+    }
+    return TestFunction{
+        Name: "Griewank",
+        Dims: N,
+        F: func (x []float64) float64 {
+            sum := 0.0
+            for i := range x { sum += x[i] * x[i] / 4000.0 }
+            return 1.0 + sum - cosProduct(x, -1, -1)
+        },
+        Gradient: func (x []float64) []float64 {
+            grad := make([]float64, N)
+            for i := range x {
+                s := math.Sqrt(float64(i+1))
+                grad[i] = x[i] / 2000.0 + math.Sin(x[i] / s) / s * cosProduct(x, i, -1)
+            }
+            return grad
+        },
+        Hessian: func (x []float64) *mat.SymDense {
+            hessian := mat.NewSymDense(N, nil)
+            for i := range x {
+                si := math.Sqrt(float64(i+1))
+                hessian.SetSym(i, i, 1.0 / 2000.0 + math.Cos(x[i] / si) / float64(i+1) * cosProduct(x, i, -1))
+                for j:=i+1; j<N; j++ {
+                    sj := math.Sqrt(float64(j+1))
+                    hessian.SetSym(i, j, - math.Sin(x[i] / si) / si * math.Sin(x[j] / sj) / sj * cosProduct(x, i, j))
+                }
+            }
+            return hessian
+        },
+        Minima: [][]float64{constant(N, 0.0)},
+        MinimumValue: 0.0,
+        Start: constant(N, 2.0),
+    }
+}
+
+
+/*
+SUMMARY
+    The Beale function on the plane, f(x,y) = (1.5-x+xy)^2 + (2.25-x+xy^2)^2 + (2.625-x+xy^3)^2.
+PARAMETERS
+    N/A
+RETURN
+    TestFunction: the test function
+*/
+func Beale() TestFunction {
+    // the three residuals, their gradients and Hessians are needed for every quantity
+    constants := []float64{1.5, 2.25, 2.625}
+    residual := func (x, y float64, k int) (float64, [2]float64, [3]float64) {
+        p := float64(k + 1)
+        a := constants[k] - x + x * math.Pow(y, p)
+        grad := [2]float64{math.Pow(y, p) - 1.0, p * x * math.Pow(y, p-1)}
+        // xx, xy, yy second derivatives
+        hess := [3]float64{0.0, p * math.Pow(y, p-1), p * (p-1) * x * math.Pow(y, p-2)}
+        if k == 0 { hess[2] = 0.0 }
+        return a, grad, hess
+    }
+    return TestFunction{
+        Name: "Beale",
+        Dims: 2,
+        F: func (x []float64) float64 {
+            sum := 0.0
+            for k := range constants {
+                a, _, _ := residual(x[0], x[1], k)
+                sum += a * a
+            }
+            return sum
+        },
+        Gradient: func (x []float64) []float64 {
+            grad := make([]float64, 2)
+            for k := range constants {
+                a, g, _ := residual(x[0], x[1], k)
+                grad[0] += 2.0 * a * g[0]
+                grad[1] += 2.0 * a * g[1]
+            }
+            return grad
+        },
+        Hessian: func (x []float64) *mat.SymDense {
+            hessian := mat.NewSymDense(2, nil)
+            for k := range constants {
+                a, g, h := residual(x[0], x[1], k)
+                hessian.SetSym(0, 0, hessian.At(0, 0) + 2.0 * (g[0]*g[0] + a*h[0]))
+                hessian.SetSym(0, 1, hessian.At(0, 1) + 2.0 * (g[0]*g[1] + a*h[1]))
+                hessian.SetSym(1, 1, hessian.At(1, 1) + 2.0 * (g[1]*g[1] + a*h[2]))
+            }
+            return hessian
+        },
+        Minima: [][]float64{{3.0, 0.5}},
+        MinimumValue: 0.0,
+        Start: []float64{1.0, 1.0},
+    }
+}
+
+
+/*
+SUMMARY
+    The Himmelblau function on the plane, f(x,y) = (x^2+y-11)^2 + (x+y^2-7)^2, it has four global minima.
+PARAMETERS
+    N/A
+RETURN
+    TestFunction: the test function
+*/
+func Himmelblau() TestFunction {
+    return TestFunction{
+        Name: "Himmelblau",
+        Dims: 2,
+        F: func (x []float64) float64 {
+            return math.Pow(x[0]*x[0] + x[1] - 11.0, 2) + math.Pow(x[0] + x[1]*x[1] - 7.0, 2)
+        },
+        Gradient: func (x []float64) []float64 {
+            a := x[0]*x[0] + x[1] - 11.0
+            b := x[0] + x[1]*x[1] - 7.0
+            return []float64{4.0 * x[0] * a + 2.0 * b, 2.0 * a + 4.0 * x[1] * b}
+        },
+        Hessian: func (x []float64) *mat.SymDense {
+            return mat.NewSymDense(2, []float64{
+                12.0 * x[0]*x[0] + 4.0 * x[1] - 42.0, 4.0 * (x[0] + x[1]),
+                4.0 * (x[0] + x[1]), 4.0 * x[0] + 12.0 * x[1]*x[1] - 26.0,
+            })
+        },
+        Minima: [][]float64{{3.0, 2.0},
+                            {-2.805118086952745, 3.131312518250573},
+                            {-3.779310253377747, -3.283185991286170},
+                            {3.584428340330492, -1.848126526964404}},
+        MinimumValue: 0.0,
+        Start: []float64{0.0, 0.0},
+    }
+}
+
+
+/*
+SUMMARY
+    The Branin (Branin-Hoo) function on the plane with the usual constants, it has three global minima.
+    f(x,y) = (y - 5.1/(4pi^2) x^2 + 5/pi x - 6)^2 + 10(1 - 1/(8pi))cos(x) + 10
+PARAMETERS
+    N/A
+RETURN
+    TestFunction: the test function
+*/
+func Branin() TestFunction {
+    b, c, r := 5.1 / (4.0 * math.Pi * math.Pi), 5.0 / math.Pi, 6.0
+    s, t := 10.0, 1.0 / (8.0 * math.Pi)
+    q := func (x []float64) float64 {
+        return x[1] - b * x[0]*x[0] + c * x[0] - r
+    }
+    return TestFunction{
+        Name: "Branin",
+        Dims: 2,
+        F: func (x []float64) float64 {
+            return q(x) * q(x) + s * (1 - t) * math.Cos(x[0]) + s
+        },
+        Gradient: func (x []float64) []float64 {
+            return []float64{2.0 * q(x) * (-2.0 * b * x[0] + c) - s * (1 - t) * math.Sin(x[0]), 2.0 * q(x)}
+        },
+        Hessian: func (x []float64) *mat.SymDense {
+            dq := -2.0 * b * x[0] + c
+            return mat.NewSymDense(2, []float64{
+                2.0 * dq * dq - 4.0 * b * q(x) - s * (1 - t) * math.Cos(x[0]), 2.0 * dq,
+                2.0 * dq, 2.0,
+            })
+        },
+        Minima: [][]float64{{-math.Pi, 12.275}, {math.Pi, 2.275}, {3.0 * math.Pi, 2.475}},
+        MinimumValue: s * t,
+        Start: []float64{6.0, 8.0},
+    }
+}
+
+
+/*
+SUMMARY
+    The Booth function on the plane, f(x,y) = (x+2y-7)^2 + (2x+y-5)^2, a convex quadratic.
+PARAMETERS
+    N/A
+RETURN
+    TestFunction: the test function
+*/
+func Booth() TestFunction {
+    return TestFunction{
+        Name: "Booth",
+        Dims: 2,
+        F: func (x []float64) float64 {
+            return math.Pow(x[0] + 2.0*x[1] - 7.0, 2) + math.Pow(2.0*x[0] + x[1] - 5.0, 2)
+        },
+        Gradient: func (x []float64) []float64 {
+            return []float64{10.0*x[0] + 8.0*x[1] - 34.0, 8.0*x[0] + 10.0*x[1] - 38.0}
+        },
+        Hessian: func (x []float64) *mat.SymDense {
+            return mat.NewSymDense(2, []float64{10.0, 8.0, 8.0, 10.0})
+        },
+        Minima: [][]float64{{1.0, 3.0}},
+        MinimumValue: 0.0,
+        Start: []float64{-5.0, -5.0},
+    }
+}
+
+
+/*
+SUMMARY
+    The Matyas function on the plane, f(x,y) = 0.26(x^2+y^2) - 0.48xy, a badly conditioned quadratic.
+PARAMETERS
+    N/A
+RETURN
+    TestFunction: the test function
+*/
+func Matyas() TestFunction {
+    return TestFunction{
+        Name: "Matyas",
+        Dims: 2,
+        F: func (x []float64) float64 {
+            return 0.26 * (x[0]*x[0] + x[1]*x[1]) - 0.48 * x[0] * x[1]
+        },
+        Gradient: func (x []float64) []float64 {
+            return []float64{0.52*x[0] - 0.48*x[1], 0.52*x[1] - 0.48*x[0]}
+        },
+        Hessian: func (x []float64) *mat.SymDense {
+            return mat.NewSymDense(2, []float64{0.52, -0.48, -0.48, 0.52})
+        },
+        Minima: [][]float64{{0.0, 0.0}},
+        MinimumValue: 0.0,
+        Start: []float64{8.0, 3.0},
+    }
+}
+
+
+/*
+SUMMARY
+    The three-hump camel function on the plane, f(x,y) = 2x^2 - 1.05x^4 + x^6/6 + xy + y^2.
+PARAMETERS
+    N/A
+RETURN
+    TestFunction: the test function
+*/
+func ThreeHumpCamel() TestFunction {
+    return TestFunction{
+        Name: "Three-hump camel",
+        Dims: 2,
+        F: func (x []float64) float64 {
+            return 2.0*x[0]*x[0] - 1.05*math.Pow(x[0], 4) + math.Pow(x[0], 6)/6.0 + x[0]*x[1] + x[1]*x[1]
+        },
+        Gradient: func (x []float64) []float64 {
+            return []float64{4.0*x[0] - 4.2*math.Pow(x[0], 3) + math.Pow(x[0], 5) + x[1], x[0] + 2.0*x[1]}
+        },
+        Hessian: func (x []float64) *mat.SymDense {
+            return mat.NewSymDense(2, []float64{4.0 - 12.6*x[0]*x[0] + 5.0*math.Pow(x[0], 4), 1.0, 1.0, 2.0})
+        },
+        Minima: [][]float64{{0.0, 0.0}},
+        MinimumValue: 0.0,
+        Start: []float64{1.0, -1.0},
+    }
+}
+
+
+/*
+SUMMARY
+    The standard suite: every test function above, the N dimensional ones in the given dimension.
+PARAMETERS
+    N int: the dimension of the N dimensional functions
+RETURN
+    []TestFunction: the suite
+*/
+func Suite(N int) []TestFunction {
+    return []TestFunction{
+        Sphere(N),
+        Rosenbrock(N),
+        Rastrigin(N),
+        Ackley(N),
+        StyblinskiTang(N),
+        Griewank(N),
+        Beale(),
+        Himmelblau(),
+        Branin(),
+        Booth(),
+        Matyas(),
+        ThreeHumpCamel(),
+    }
+}
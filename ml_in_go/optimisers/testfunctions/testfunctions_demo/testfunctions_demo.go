@@ -0,0 +1,18 @@
+package main
+
+import (
+    "fmt"
+
+    "ml_playground/optimisers/testfunctions"
+)
+
+
+/*
+Runs every optimiser on the whole suite of test functions (the N dimensional ones in 5 dimensions)
+and prints how many iterations each needed to get within 1e-6 of the global minimum.
+*/
+func main() {
+    results, err := testfunctions.Benchmark(testfunctions.Suite(5), testfunctions.DefaultContestants(1e-8), 10000, 1e-6)
+    if err != nil { panic(err) }
+    fmt.Print(testfunctions.FormatTable(results))
+}
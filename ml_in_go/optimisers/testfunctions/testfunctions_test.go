@@ -0,0 +1,77 @@
+package testfunctions
+
+import (
+    "math"
+    "testing"
+    "golang.org/x/exp/rand"
+)
+
+
+/*
+SUMMARY
+    The gradient of a function by central differences.
+PARAMETERS
+    F func([]float64) []float64: the function, its output may have several components
+    At []float64: the point
+RETURN
+    [][]float64: the i-th row is the derivative of the outputs with respect to the i-th input
+*/
+func centralDifferences(F func([]float64) []float64, At []float64) [][]float64 {
+    derivatives := make([][]float64, len(At))
+    x := append([]float64{}, At...)
+    for i := range x {
+        h := 1e-6 * math.Max(1.0, math.Abs(x[i]))
+        x[i] = At[i] + h
+        plus := F(x)
+        x[i] = At[i] - h
+        minus := F(x)
+        x[i] = At[i]
+        derivatives[i] = make([]float64, len(plus))
+        for k := range plus { derivatives[i][k] = (plus[k] - minus[k]) / (2.0 * h) }
+    }
+    return derivatives
+}
+
+
+func TestDerivatives(t *testing.T) {
+    randGen := rand.New(rand.NewSource(1))
+    agree := func (Analytic, Numeric, Scale float64) bool {
+        return math.Abs(Analytic - Numeric) <= 1e-5 * (1.0 + Scale)
+    }
+    for _, dims := range []int{2, 3} {
+        for _, function := range Suite(dims) {
+            for trial:=0; trial<20; trial++ {
+                // random points around the customary start and the minimisers
+                x := make([]float64, function.Dims)
+                for i := range x { x[i] = function.Start[i] + 3.0 * (2.0 * randGen.Float64() - 1.0) }
+                value := func (x []float64) []float64 { return []float64{function.F(x)} }
+                gradient, numericGradient := function.Gradient(x), centralDifferences(value, x)
+                hessian, numericHessian := function.Hessian(x), centralDifferences(function.Gradient, x)
+                scale := 0.0
+                for i := range x { scale = math.Max(scale, math.Abs(gradient[i])) }
+                for i := range x {
+                    if !agree(gradient[i], numericGradient[i][0], scale) {
+                        t.Errorf("%s (%d dims) at %v: derivative %d is %g, finite difference %g", function.Name, dims, x, i, gradient[i], numericGradient[i][0])
+                    }
+                    for j := range x {
+                        if !agree(hessian.At(i, j), numericHessian[i][j], math.Abs(numericHessian[i][j])) {
+                            t.Errorf("%s (%d dims) at %v: Hessian (%d,%d) is %g, finite difference %g", function.Name, dims, x, i, j, hessian.At(i, j), numericHessian[i][j])
+                        }
+                    }
+                }
+            }
+        }
+    }
+}
+
+
+func TestMinima(t *testing.T) {
+    for _, function := range Suite(3) {
+        if len(function.Start) != function.Dims { t.Errorf("%s: start of length %d in %d dims", function.Name, len(function.Start), function.Dims) }
+        for _, minimum := range function.Minima {
+            if value := function.F(minimum); math.Abs(value - function.MinimumValue) > 1e-4 * (1.0 + math.Abs(function.MinimumValue)) {
+                t.Errorf("%s: F%v = %g instead of %g", function.Name, minimum, value, function.MinimumValue)
+            }
+        }
+    }
+}
@@ -0,0 +1,218 @@
+package pca
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+)
+
+
+/*
+This type configures Bayesian PCA.
+    MaxIterations int: the maximal number of iterations (500 if 0)
+    Tolerance float64: stop when the log-likelihood per entry changes less than this
+    MaxPrecision float64: the cap of the precisions of the columns of W (1e12 if 0)
+*/
+type BayesianOptions struct {
+    MaxIterations int
+    Tolerance float64
+    MaxPrecision float64
+}
+
+
+/*
+This type stores a Bayesian PCA model (Bishop, Bayesian PCA): probabilistic PCA with the largest possible
+latent space where column k of W has the prior N(0, Alpha_k^{-1} I). The precisions are re-estimated
+(automatic relevance determination), the ones of unnecessary columns diverge and switch the columns off.
+    PPCA: the model with D-1 columns in W
+    Alpha []float64: the precisions of the columns of W
+    Iterations int: the number of iterations
+    Converged bool: whether the algorithm stopped because of the tolerance
+*/
+type BayesianPCA struct {
+    PPCA
+    Alpha []float64
+    Iterations int
+    Converged bool
+}
+
+
+/*
+SUMMARY
+    The inverse of a symmetric positive definite matrix.
+PARAMETERS
+    A *mat.SymDense: the matrix
+RETURN
+    *mat.SymDense: the inverse
+    error: *FactorisationError if A is not positive definite
+*/
+func inverseSPD(A *mat.SymDense) (*mat.SymDense, error) {
+    var chol mat.Cholesky
+    if ok := chol.Factorize(A); !ok { return nil, &FactorisationError{Factorisation: "Cholesky"} }
+    var inverse mat.SymDense
+    if err := chol.InverseTo(&inverse); err != nil { return nil, err }
+    return &inverse, nil
+}
+
+
+/*
+SUMMARY
+    The sample covariance of the data with the maximum likelihood normalisation 1/N.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Mean []float64: the mean of the data
+RETURN
+    *mat.SymDense: the covariance
+*/
+func scatter(X *mat.Dense, Mean []float64) *mat.SymDense {
+    n, d := X.Dims()
+    covariance := mat.NewSymDense(d, nil)
+    covariance.SymRankK(covariance, 1.0 / float64(n), centre(X, Mean).T())
+    return covariance
+}
+
+
+/*
+SUMMARY
+    The squared norms of the columns of a matrix.
+PARAMETERS
+    A *mat.Dense: the matrix
+RETURN
+    []float64: the squared norms
+*/
+func squaredColumnNorms(A *mat.Dense) []float64 {
+    _, k := A.Dims()
+    norms := make([]float64, k)
+    for c:=0; c<k; c++ {
+        norm := mat.Norm(A.ColView(c), 2)
+        norms[c] = norm * norm
+    }
+    return norms
+}
+
+
+/*
+SUMMARY
+    Fits Bayesian PCA with the EM algorithm of Bishop. With M = W^T W + NoiseVariance I, the sample
+    covariance S, the averages C = S W M^{-1} of x_n E[z_n]^T and G = NoiseVariance M^{-1} + M^{-1} W^T C
+    of E[z_n z_n^T], the updates are
+        W = C (G + NoiseVariance A / N)^{-1},
+        NoiseVariance = tr(S - 2 C W^T + G W^T W) / D,
+        Alpha_k = D / ||w_k||^2,
+    starting from the closed-form probabilistic PCA solution with min(D-1, N-1) components.
+PARAMETERS
+    X *mat.Dense: the complete data, each row is a point
+    Options BayesianOptions: the settings
+RETURN
+    BayesianPCA: the fitted model
+    error: *ParameterError if the data are invalid, *FactorisationError if a factorisation fails
+*/
+func FitBayesianPCA(X *mat.Dense, Options BayesianOptions) (BayesianPCA, error) {
+    n, d := X.Dims()
+    if d < 2 { return BayesianPCA{}, &ParameterError{Parameter: "number of dimensions", Value: float64(d), Expected: "at least 2"} }
+    maxIterations := Options.MaxIterations
+    if maxIterations <= 0 { maxIterations = 500 }
+    maxPrecision := Options.MaxPrecision
+    if maxPrecision <= 0 { maxPrecision = 1e12 }
+    k := d - 1
+    if n - 1 < k { k = n - 1 }
+
+    mean, values, v, err := decompose(X)
+    if err != nil { return BayesianPCA{}, err }
+    model := BayesianPCA{PPCA: maximumLikelihood(mean, values, v, n, k), Alpha: make([]float64, k)}
+    S := scatter(X, mean)
+    // a millionth of the average variance, the data may lie exactly in k dimensions
+    model.NoiseVariance = math.Max(model.NoiseVariance, 1e-6 * mat.Trace(S) / float64(d))
+    previous := math.Inf(-1)
+    SW := mat.NewDense(d, k, nil)
+    cross := mat.NewDense(d, k, nil)
+    var second mat.Dense
+    inner := mat.NewDense(k, k, nil)
+    updatePrecisions := func () {
+        for c, norm := range squaredColumnNorms(model.W) {
+            model.Alpha[c] = maxPrecision
+            if norm > float64(d) / maxPrecision { model.Alpha[c] = float64(d) / norm }
+        }
+    }
+    for model.Iterations < maxIterations {
+        model.Iterations++
+        updatePrecisions()
+
+        M := mat.NewSymDense(k, nil)
+        M.SymOuterK(1.0, model.W.T())
+        for c:=0; c<k; c++ { M.SetSym(c, c, M.At(c, c) + model.NoiseVariance) }
+        MInverse, err := inverseSPD(M)
+        if err != nil { return model, err }
+        SW.Mul(S, model.W)
+        // the average of x_n E[z_n]^T
+        cross.Mul(SW, MInverse)
+        // the average of E[z_n z_n^T], NoiseVariance M^{-1} + M^{-1} W^T S W M^{-1}
+        inner.Mul(model.W.T(), cross)
+        second.Mul(MInverse, inner)
+        for a:=0; a<k; a++ {
+            for b:=0; b<k; b++ { second.Set(a, b, second.At(a, b) + model.NoiseVariance * MInverse.At(a, b)) }
+        }
+        // W = cross (second + NoiseVariance A / N)^{-1}
+        inner.Copy(&second)
+        for c:=0; c<k; c++ { inner.Set(c, c, inner.At(c, c) + model.NoiseVariance * model.Alpha[c] / float64(n)) }
+        var WNew mat.Dense
+        if err := WNew.Solve(inner, cross.T()); err != nil { return model, err }
+        model.W = mat.DenseCopyOf(WNew.T())
+
+        // NoiseVariance = (tr S - 2 tr(cross W^T) + tr(second W^T W)) / D
+        var WW, secondWW mat.Dense
+        WW.Mul(model.W.T(), model.W)
+        secondWW.Mul(&second, &WW)
+        noise := mat.Trace(S) + mat.Trace(&secondWW)
+        for j:=0; j<d; j++ { noise -= 2.0 * floats.Dot(cross.RawRowView(j), model.W.RawRowView(j)) }
+        model.NoiseVariance = math.Max(noise / float64(d), 1e-12)
+
+        logLikelihood, err := model.LogLikelihood(X)
+        if err != nil { return model, err }
+        if math.Abs(logLikelihood - previous) / float64(n * d) < Options.Tolerance {
+            model.Converged = true
+            break
+        }
+        previous = logLikelihood
+    }
+    updatePrecisions()
+    return model, nil
+}
+
+
+/*
+SUMMARY
+    The columns of W which explain at least a given share of the variance of the latent part,
+    ||w_k||^2 / \sum_j ||w_j||^2 >= Threshold. Their number is the effective latent dimensionality.
+PARAMETERS
+    Threshold float64: the smallest share of an active column
+RETURN
+    []int: the indices of the active columns
+*/
+func (b BayesianPCA) ActiveComponents(Threshold float64) []int {
+    norms := squaredColumnNorms(b.W)
+    total := floats.Sum(norms)
+    var active []int
+    for c, norm := range norms {
+        if total > 0 && norm / total >= Threshold { active = append(active, c) }
+    }
+    return active
+}
+
+
+/*
+SUMMARY
+    The probabilistic PCA model made of the active columns of W.
+PARAMETERS
+    Threshold float64: the smallest share of an active column
+RETURN
+    PPCA: the reduced model
+*/
+func (b BayesianPCA) Reduce(Threshold float64) PPCA {
+    d, _ := b.W.Dims()
+    active := b.ActiveComponents(Threshold)
+    reduced := PPCA{Mean: append([]float64{}, b.Mean...), W: mat.NewDense(d, len(active), nil), NoiseVariance: b.NoiseVariance}
+    for a, c := range active { reduced.W.SetCol(a, mat.Col(nil, c, b.W)) }
+    return reduced
+}
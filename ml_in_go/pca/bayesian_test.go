@@ -0,0 +1,26 @@
+package pca
+
+import (
+    "math"
+    "testing"
+
+    "gonum.org/v1/gonum/mat"
+)
+
+
+func TestBayesianPCAScaleEquivariance(t *testing.T) {
+    // the data lie exactly in a plane, so the noise variance starts at its floor
+    for _, n := range []int{20, 200} {
+        X := lowRankData(n, 6, 2, 0, 7)
+        scaled := mat.DenseCopyOf(X)
+        scaled.Scale(10.0, scaled)
+        options := BayesianOptions{MaxIterations: 1}
+        model, err := FitBayesianPCA(X, options)
+        if err != nil { t.Fatal(err) }
+        scaledModel, err := FitBayesianPCA(scaled, options)
+        if err != nil { t.Fatal(err) }
+        if ratio := scaledModel.NoiseVariance / model.NoiseVariance; math.Abs(ratio - 100.0) > 1e-6 * 100.0 {
+            t.Errorf("%d points: scaling the data by 10 scales the noise variance by %g instead of 100", n, ratio)
+        }
+    }
+}
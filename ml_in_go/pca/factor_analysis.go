@@ -0,0 +1,231 @@
+package pca
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+)
+
+
+/*
+This type stores a factor analysis model: x = W z + Mean + noise where z ~ N(0,I) and
+noise ~ N(0, diag(NoiseVariances)), hence x ~ N(Mean, W W^T + diag(NoiseVariances)).
+Unlike probabilistic PCA every coordinate has its own noise variance.
+    Mean []float64: the mean of the data
+    W *mat.Dense: D by K matrix, the factor loadings
+    NoiseVariances []float64: the variances of the noise of the coordinates
+*/
+type FactorAnalysis struct {
+    Mean []float64
+    W *mat.Dense
+    NoiseVariances []float64
+}
+
+
+/*
+SUMMARY
+    The covariance of the data under the model, W W^T + diag(NoiseVariances).
+PARAMETERS
+    N/A
+RETURN
+    *mat.SymDense: the covariance
+*/
+func (f FactorAnalysis) Covariance() *mat.SymDense {
+    d, _ := f.W.Dims()
+    covariance := mat.NewSymDense(d, nil)
+    covariance.SymOuterK(1.0, f.W)
+    for j:=0; j<d; j++ { covariance.SetSym(j, j, covariance.At(j, j) + f.NoiseVariances[j]) }
+    return covariance
+}
+
+
+/*
+SUMMARY
+    The log-likelihood of the data. Missing entries (NaN) are marginalised out.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    float64: \sum_n log p(x_n)
+    error: *FactorisationError if the covariance is not positive definite
+*/
+func (f FactorAnalysis) LogLikelihood(X *mat.Dense) (float64, error) {
+    return gaussianLogLikelihood(X, f.Mean, f.Covariance())
+}
+
+
+/*
+SUMMARY
+    The number of free parameters of the model, the loadings are determined up to a rotation.
+PARAMETERS
+    N/A
+RETURN
+    int: the number of parameters
+*/
+func (f FactorAnalysis) NumParameters() int {
+    d, k := f.W.Dims()
+    return d * k - k * (k - 1) / 2 + 2 * d
+}
+
+
+/*
+SUMMARY
+    The Bayesian information criterion, lower is better.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    float64: -2 log-likelihood + #parameters log N
+    error: *FactorisationError if the covariance is not positive definite
+*/
+func (f FactorAnalysis) BIC(X *mat.Dense) (float64, error) {
+    n, _ := X.Dims()
+    logLikelihood, err := f.LogLikelihood(X)
+    if err != nil { return 0, err }
+    return -2.0 * logLikelihood + float64(f.NumParameters()) * math.Log(float64(n)), nil
+}
+
+
+/*
+SUMMARY
+    The posterior distribution of the latent variables, every point shares the posterior
+    covariance G = (I + W^T Psi^{-1} W)^{-1} and the posterior mean is G W^T Psi^{-1} (x - Mean).
+PARAMETERS
+    X *mat.Dense: the complete data, each row is a point
+RETURN
+    *mat.Dense: N by K matrix, the posterior means
+    *mat.SymDense: the posterior covariance
+    error: *FactorisationError if the posterior precision is not positive definite
+*/
+func (f FactorAnalysis) Posterior(X *mat.Dense) (*mat.Dense, *mat.SymDense, error) {
+    n, _ := X.Dims()
+    _, k := f.W.Dims()
+    G, projection, err := f.posteriorMaps()
+    if err != nil { return nil, nil, err }
+    means := mat.NewDense(n, k, nil)
+    means.Mul(centre(X, f.Mean), projection.T())
+    return means, G, nil
+}
+
+
+/*
+SUMMARY
+    The matrices of the posterior of the latent variables.
+PARAMETERS
+    N/A
+RETURN
+    *mat.SymDense: the posterior covariance G = (I + W^T Psi^{-1} W)^{-1}
+    *mat.Dense: K by D matrix, G W^T Psi^{-1}, the posterior mean is this times x - Mean
+    error: *FactorisationError if the posterior precision is not positive definite
+*/
+func (f FactorAnalysis) posteriorMaps() (*mat.SymDense, *mat.Dense, error) {
+    d, k := f.W.Dims()
+    scaled := mat.NewDense(d, k, nil)
+    scaled.Apply(func (j, c int, v float64) float64 { return v / f.NoiseVariances[j] }, f.W)
+    precision := mat.NewSymDense(k, nil)
+    for c:=0; c<k; c++ { precision.SetSym(c, c, 1.0) }
+    var product mat.Dense
+    product.Mul(f.W.T(), scaled)
+    for a:=0; a<k; a++ {
+        for b:=a; b<k; b++ { precision.SetSym(a, b, precision.At(a, b) + 0.5 * (product.At(a, b) + product.At(b, a))) }
+    }
+    G, err := inverseSPD(precision)
+    if err != nil { return nil, nil, err }
+    projection := mat.NewDense(k, d, nil)
+    projection.Mul(G, scaled.T())
+    return G, projection, nil
+}
+
+
+/*
+SUMMARY
+    Fits factor analysis with the EM algorithm. With the sample covariance S, G and B = G W^T Psi^{-1}
+    from the E-step, the updates are
+        W = S B^T (G + B S B^T)^{-1},
+        Psi = diag(S - W B S).
+    The model is initialised with the closed-form probabilistic PCA solution.
+PARAMETERS
+    X *mat.Dense: the complete data, each row is a point
+    Options EMOptions: the settings
+RETURN
+    FactorAnalysis: the fitted model
+    EMResult: the log-likelihood trace and the convergence
+    error: *ParameterError if the data or the options are invalid, *FactorisationError if a factorisation fails
+*/
+func FitFactorAnalysis(X *mat.Dense, Options EMOptions) (FactorAnalysis, EMResult, error) {
+    n, d := X.Dims()
+    k := Options.NumComponents
+    if k <= 0 || k >= d || k > n {
+        return FactorAnalysis{}, EMResult{}, &ParameterError{Parameter: "NumComponents", Value: float64(k), Expected: "in [1, #dimensions) and at most #points"}
+    }
+    maxIterations := Options.MaxIterations
+    if maxIterations <= 0 { maxIterations = 200 }
+
+    mean, values, v, err := decompose(X)
+    if err != nil { return FactorAnalysis{}, EMResult{}, err }
+    initial := maximumLikelihood(mean, values, v, n, k)
+    S := scatter(X, mean)
+    // the noise variances stay above a small fraction of the variances of the coordinates
+    floor := make([]float64, d)
+    for j := range floor { floor[j] = 1e-6 * S.At(j, j) }
+    model := FactorAnalysis{Mean: mean, W: initial.W, NoiseVariances: make([]float64, d)}
+    for j := range model.NoiseVariances { model.NoiseVariances[j] = math.Max(initial.NoiseVariance, floor[j]) }
+
+    var result EMResult
+    previous := math.Inf(-1)
+    var BS, second, WBS mat.Dense
+    for result.Iterations < maxIterations {
+        result.Iterations++
+        logLikelihood, err := model.LogLikelihood(X)
+        if err != nil { return model, result, err }
+        result.LogLikelihoods = append(result.LogLikelihoods, logLikelihood)
+        if (logLikelihood - previous) / float64(n * d) < Options.Tolerance {
+            result.Converged = true
+            break
+        }
+        previous = logLikelihood
+
+        G, B, err := model.posteriorMaps()
+        if err != nil { return model, result, err }
+        BS.Mul(B, S)
+        second.Mul(&BS, B.T())
+        second.Add(&second, G)
+        var W mat.Dense
+        if err := W.Solve(&second, &BS); err != nil { return model, result, err }
+        model.W = mat.DenseCopyOf(W.T())
+        WBS.Mul(model.W, &BS)
+        for j:=0; j<d; j++ { model.NoiseVariances[j] = math.Max(S.At(j, j) - WBS.At(j, j), floor[j]) }
+    }
+    return model, result, nil
+}
+
+
+/*
+SUMMARY
+    Fits factor analysis for every number of factors from 1 to MaxComponents and keeps the
+    one with the lowest Bayesian information criterion, the effective latent dimensionality.
+PARAMETERS
+    X *mat.Dense: the complete data, each row is a point
+    MaxComponents int: the largest number of factors
+    Options EMOptions: the settings, NumComponents is ignored
+RETURN
+    FactorAnalysis: the model with the lowest BIC
+    []float64: the BIC of every number of factors
+    error: *ParameterError if the data or the options are invalid, *FactorisationError if a factorisation fails
+*/
+func SelectFactorAnalysis(X *mat.Dense, MaxComponents int, Options EMOptions) (FactorAnalysis, []float64, error) {
+    var models []FactorAnalysis
+    var bics []float64
+    for k:=1; k<=MaxComponents; k++ {
+        Options.NumComponents = k
+        model, _, err := FitFactorAnalysis(X, Options)
+        if err != nil { return FactorAnalysis{}, nil, err }
+        bic, err := model.BIC(X)
+        if err != nil { return FactorAnalysis{}, nil, err }
+        models = append(models, model)
+        bics = append(bics, bic)
+    }
+    if len(models) == 0 {
+        return FactorAnalysis{}, nil, &ParameterError{Parameter: "MaxComponents", Value: float64(MaxComponents), Expected: "at least 1"}
+    }
+    return models[floats.MinIdx(bics)], bics, nil
+}
@@ -0,0 +1,146 @@
+package pca
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+)
+
+
+/*
+This type stores the state of the incremental PCA (Ross, Lim, Lin & Yang, Incremental Learning for
+Robust Visual Tracking), which sees the data batch by batch and never holds more than one batch.
+    NumComponents int: the number of principal components to keep
+    Whiten bool: whether Transform scales the components to unit variance
+    Mean []float64: the mean of the points seen so far
+    Components *mat.Dense: D by K matrix, the current principal axes
+    SingularValues []float64: the current singular values of the centred data
+    NumSamples int: the number of points seen so far
+    SumSquares float64: the sum of the squared distances of the points from the mean
+*/
+type IncrementalPCA struct {
+    NumComponents int
+    Whiten bool
+    Mean []float64
+    Components *mat.Dense
+    SingularValues []float64
+    NumSamples int
+    SumSquares float64
+}
+
+
+/*
+SUMMARY
+    Creates an incremental PCA which has not seen any data.
+PARAMETERS
+    NumComponents int: the number of principal components to keep
+    Whiten bool: whether Transform scales the components to unit variance
+RETURN
+    *IncrementalPCA: the empty model
+*/
+func NewIncrementalPCA(NumComponents int, Whiten bool) *IncrementalPCA {
+    return &IncrementalPCA{NumComponents: NumComponents, Whiten: Whiten}
+}
+
+
+/*
+SUMMARY
+    Updates the principal axes with a batch of points. The thin SVD of the stacked matrix
+        [ diag(SingularValues) Components^T ]
+        [ batch - batch mean                ]
+        [ sqrt(N M / (N + M)) (Mean - batch mean) ]
+    has the same singular values and right singular vectors as all the centred points seen so far,
+    up to the directions discarded earlier.
+PARAMETERS
+    X *mat.Dense: the batch, each row is a point
+RETURN
+    error: *ParameterError if the batch is invalid, *FactorisationError if the SVD fails
+*/
+func (p *IncrementalPCA) PartialFit(X *mat.Dense) error {
+    m, d := X.Dims()
+    k := p.NumComponents
+    if k <= 0 || k > d { return &ParameterError{Parameter: "NumComponents", Value: float64(k), Expected: "in [1, #dimensions]"} }
+    if p.Mean != nil && len(p.Mean) != d {
+        return &ParameterError{Parameter: "dimension of the batch", Value: float64(d), Expected: "the dimension of the previous batches"}
+    }
+    if p.NumSamples == 0 && m < k {
+        return &ParameterError{Parameter: "size of the first batch", Value: float64(m), Expected: "at least NumComponents"}
+    }
+    if m == 0 { return nil }
+
+    batchMean := columnMeans(X)
+    centred := centre(X, batchMean)
+    batchSquares := 0.0
+    for i:=0; i<m; i++ {
+        row := centred.RawRowView(i)
+        batchSquares += floats.Dot(row, row)
+    }
+
+    stacked := centred
+    if p.NumSamples > 0 {
+        previous := len(p.SingularValues)
+        stacked = mat.NewDense(previous + m + 1, d, nil)
+        for c, s := range p.SingularValues {
+            row := stacked.RawRowView(c)
+            mat.Col(row, c, p.Components)
+            floats.Scale(s, row)
+        }
+        stacked.Slice(previous, previous + m, 0, d).(*mat.Dense).Copy(centred)
+        n := float64(p.NumSamples)
+        scale := math.Sqrt(n * float64(m) / (n + float64(m)))
+        correction := stacked.RawRowView(previous + m)
+        floats.SubTo(correction, p.Mean, batchMean)
+        floats.Scale(scale, correction)
+
+        // the combined mean and sum of squares
+        batchSquares += floats.Dot(correction, correction)
+        floats.Scale(n / (n + float64(m)), p.Mean)
+        floats.AddScaled(p.Mean, float64(m) / (n + float64(m)), batchMean)
+    } else {
+        p.Mean = batchMean
+    }
+    p.NumSamples += m
+    p.SumSquares += batchSquares
+
+    var svd mat.SVD
+    if ok := svd.Factorize(stacked, mat.SVDThin); !ok { return &FactorisationError{Factorisation: "SVD"} }
+    var v mat.Dense
+    svd.VTo(&v)
+    fixSigns(&v)
+    values := svd.Values(nil)
+    if k > len(values) { k = len(values) }
+    p.Components = mat.DenseCopyOf(v.Slice(0, d, 0, k))
+    p.SingularValues = values[:k]
+    return nil
+}
+
+
+/*
+SUMMARY
+    The principal component analysis of the points seen so far, it can transform points like Fit's.
+PARAMETERS
+    N/A
+RETURN
+    PCA: the current model
+*/
+func (p *IncrementalPCA) PCA() PCA {
+    d, k := p.Components.Dims()
+    model := PCA{
+        Mean: append([]float64{}, p.Mean...),
+        Components: mat.DenseCopyOf(p.Components),
+        SingularValues: append([]float64{}, p.SingularValues...),
+        ExplainedVariance: make([]float64, k),
+        ExplainedVarianceRatio: make([]float64, k),
+        NumSamples: p.NumSamples,
+        Whiten: p.Whiten,
+    }
+    if p.NumSamples < 2 { return model }
+    total := p.SumSquares / float64(p.NumSamples - 1)
+    for c, s := range p.SingularValues {
+        model.ExplainedVariance[c] = s * s / float64(p.NumSamples - 1)
+        if total > 0 { model.ExplainedVarianceRatio[c] = model.ExplainedVariance[c] / total }
+    }
+    if d > k { model.NoiseVariance = math.Max(total - floats.Sum(model.ExplainedVariance), 0.0) / float64(d - k) }
+    return model
+}
@@ -0,0 +1,69 @@
+package pca
+
+import (
+    "fmt"
+    "testing"
+
+    "gonum.org/v1/gonum/mat"
+)
+
+
+/*
+SUMMARY
+    Fits an incremental PCA batch by batch, the last batch may be smaller than the others.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    NumComponents int: the number of principal components
+    BatchSize int: the number of points in a batch
+RETURN
+    PCA: the model after the last batch
+    error: the error of PartialFit
+*/
+func fitInBatches(X *mat.Dense, NumComponents, BatchSize int) (PCA, error) {
+    n, d := X.Dims()
+    incremental := NewIncrementalPCA(NumComponents, false)
+    for first:=0; first<n; first+=BatchSize {
+        last := first + BatchSize
+        if last > n { last = n }
+        if err := incremental.PartialFit(mat.DenseCopyOf(X.Slice(first, last, 0, d))); err != nil { return PCA{}, err }
+    }
+    return incremental.PCA(), nil
+}
+
+
+func TestIncrementalPCA(t *testing.T) {
+    cases := []struct {
+        n, d, rank, k, batch int
+        noise float64
+        valueTolerance, subspaceTolerance float64
+    }{
+        // the rank equals the number of components, nothing is discarded
+        {n: 203, d: 40, rank: 8, k: 8, batch: 50, noise: 0, valueTolerance: 1e-10, subspaceTolerance: 1e-6},
+        {n: 43, d: 300, rank: 8, k: 8, batch: 20, noise: 0, valueTolerance: 1e-10, subspaceTolerance: 1e-6},
+        // every component is kept, so the result is exact with noise as well
+        {n: 203, d: 12, rank: 8, k: 12, batch: 50, noise: 0.05, valueTolerance: 1e-10, subspaceTolerance: 1e-6},
+        // the discarded directions carry only the noise
+        {n: 203, d: 40, rank: 5, k: 5, batch: 50, noise: 0.01, valueTolerance: 1e-3, subspaceTolerance: 1e-2},
+        {n: 43, d: 300, rank: 5, k: 5, batch: 20, noise: 0.01, valueTolerance: 1e-3, subspaceTolerance: 1e-2},
+    }
+    for _, c := range cases {
+        name := fmt.Sprintf("%d by %d, %d components, batches of %d", c.n, c.d, c.k, c.batch)
+        if c.n % c.batch >= c.k { t.Fatalf("%s: the last batch is not smaller than the number of components", name) }
+        X := lowRankData(c.n, c.d, c.rank, c.noise, 3)
+        exact, err := Fit(X, Options{NumComponents: c.k})
+        if err != nil { t.Fatal(err) }
+        incremental, err := fitInBatches(X, c.k, c.batch)
+        if err != nil { t.Fatalf("%s: %v", name, err) }
+        if incremental.NumSamples != c.n { t.Errorf("%s: %d samples instead of %d", name, incremental.NumSamples, c.n) }
+        checkAgainstExact(t, name, exact, incremental, c.valueTolerance, c.subspaceTolerance)
+    }
+}
+
+
+func TestIncrementalPCASmallFirstBatch(t *testing.T) {
+    X := lowRankData(20, 6, 3, 0.1, 4)
+    incremental := NewIncrementalPCA(4, false)
+    if err := incremental.PartialFit(mat.DenseCopyOf(X.Slice(0, 3, 0, 6))); err == nil {
+        t.Error("a first batch smaller than NumComponents was accepted")
+    }
+}
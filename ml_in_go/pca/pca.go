@@ -0,0 +1,263 @@
+/*
+This library contains principal component analysis. The data points are the rows
+of the data matrices, the principal axes are the columns of Components.
+*/
+package pca
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+)
+
+
+/*
+This type configures Fit. If both NumComponents and VarianceThreshold are 0 every component is kept.
+    NumComponents int: the number of principal components to keep
+    VarianceThreshold float64: if positive, the smallest number of components whose
+        explained variance ratios add up to at least this is kept, NumComponents must be 0
+    Whiten bool: whether Transform scales the components to unit variance
+*/
+type Options struct {
+    NumComponents int
+    VarianceThreshold float64
+    Whiten bool
+}
+
+
+/*
+This type stores a fitted principal component analysis.
+    Mean []float64: the mean of the training data
+    Components *mat.Dense: D by K matrix, the principal axes in decreasing order of variance
+    SingularValues []float64: the singular values of the centred data belonging to the axes
+    ExplainedVariance []float64: the variance of the data along each axis
+    ExplainedVarianceRatio []float64: the fraction of the total variance along each axis
+    NoiseVariance float64: the average variance along the discarded directions
+    NumSamples int: the number of training points
+    Whiten bool: whether Transform scales the components to unit variance
+*/
+type PCA struct {
+    Mean []float64
+    Components *mat.Dense
+    SingularValues []float64
+    ExplainedVariance []float64
+    ExplainedVarianceRatio []float64
+    NoiseVariance float64
+    NumSamples int
+    Whiten bool
+}
+
+
+/*
+SUMMARY
+    Subtracts a vector from every row of a matrix.
+PARAMETERS
+    X *mat.Dense: the matrix, each row is a point
+    Mean []float64: the vector
+RETURN
+    *mat.Dense: the centred copy of X
+*/
+func centre(X *mat.Dense, Mean []float64) *mat.Dense {
+    n, d := X.Dims()
+    centred := mat.NewDense(n, d, nil)
+    centred.Apply(func (i, j int, v float64) float64 { return v - Mean[j] }, X)
+    return centred
+}
+
+
+/*
+SUMMARY
+    The mean of the rows of a matrix.
+PARAMETERS
+    X *mat.Dense: the matrix, each row is a point
+RETURN
+    []float64: the mean
+*/
+func columnMeans(X *mat.Dense) []float64 {
+    n, d := X.Dims()
+    mean := make([]float64, d)
+    for i:=0; i<n; i++ { floats.Add(mean, X.RawRowView(i)) }
+    floats.Scale(1.0 / float64(n), mean)
+    return mean
+}
+
+
+/*
+SUMMARY
+    Flips the sign of every axis so that its largest coordinate in absolute value is positive,
+    which makes the result independent of the sign convention of the factorisation.
+PARAMETERS
+    Axes *mat.Dense: the axes in the columns, changed in place
+RETURN
+    N/A
+*/
+func fixSigns(Axes *mat.Dense) {
+    d, k := Axes.Dims()
+    column := make([]float64, d)
+    for c:=0; c<k; c++ {
+        mat.Col(column, c, Axes)
+        if column[floats.MaxIdx(absolute(column))] < 0 {
+            floats.Scale(-1.0, column)
+            Axes.SetCol(c, column)
+        }
+    }
+}
+
+
+/*
+SUMMARY
+    The absolute values of a slice.
+PARAMETERS
+    X []float64: the slice
+RETURN
+    []float64: the absolute values
+*/
+func absolute(X []float64) []float64 {
+    abs := make([]float64, len(X))
+    for i, x := range X { abs[i] = math.Abs(x) }
+    return abs
+}
+
+
+/*
+SUMMARY
+    Chooses the number of components and fills in the variances from the singular values of the centred data.
+PARAMETERS
+    Values []float64: every singular value in decreasing order
+    N int: the number of points
+    D int: the dimension
+    Options Options: the settings
+RETURN
+    PCA: the model without Mean and Components
+    error: *ParameterError if the options are invalid
+*/
+func fromSingularValues(Values []float64, N, D int, Options Options) (PCA, error) {
+    variances := make([]float64, len(Values))
+    for i, s := range Values { variances[i] = s * s / float64(N - 1) }
+    total := floats.Sum(variances)
+    ratios := make([]float64, len(Values))
+    if total > 0 { floats.ScaleTo(ratios, 1.0 / total, variances) }
+
+    k := Options.NumComponents
+    if Options.VarianceThreshold > 0 {
+        if Options.VarianceThreshold > 1 {
+            return PCA{}, &ParameterError{Parameter: "VarianceThreshold", Value: Options.VarianceThreshold, Expected: "in (0,1]"}
+        }
+        if k != 0 {
+            return PCA{}, &ParameterError{Parameter: "NumComponents", Value: float64(k), Expected: "0 when VarianceThreshold is set"}
+        }
+        cumulative := 0.0
+        for k < len(ratios) && cumulative < Options.VarianceThreshold - 1e-12 {
+            cumulative += ratios[k]
+            k++
+        }
+    }
+    if k == 0 { k = len(Values) }
+    if k < 0 || k > len(Values) {
+        return PCA{}, &ParameterError{Parameter: "NumComponents", Value: float64(k), Expected: "in [0, min(#points, #dimensions)]"}
+    }
+    model := PCA{
+        SingularValues: append([]float64{}, Values[:k]...),
+        ExplainedVariance: variances[:k],
+        ExplainedVarianceRatio: ratios[:k],
+        NumSamples: N,
+        Whiten: Options.Whiten,
+    }
+    if D > k { model.NoiseVariance = floats.Sum(variances[k:]) / float64(D - k) }
+    return model, nil
+}
+
+
+/*
+SUMMARY
+    The thin singular value decomposition of the centred data.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    []float64: the mean of the data
+    []float64: the singular values in decreasing order
+    *mat.Dense: D by min(N,D) matrix, the right singular vectors with fixed signs
+    error: *ParameterError if there are fewer than 2 points, *FactorisationError if the SVD fails
+*/
+func decompose(X *mat.Dense) ([]float64, []float64, *mat.Dense, error) {
+    n, _ := X.Dims()
+    if n < 2 { return nil, nil, nil, &ParameterError{Parameter: "number of points", Value: float64(n), Expected: "at least 2"} }
+    mean := columnMeans(X)
+    var svd mat.SVD
+    if ok := svd.Factorize(centre(X, mean), mat.SVDThin); !ok {
+        return nil, nil, nil, &FactorisationError{Factorisation: "SVD"}
+    }
+    var v mat.Dense
+    svd.VTo(&v)
+    fixSigns(&v)
+    return mean, svd.Values(nil), &v, nil
+}
+
+
+/*
+SUMMARY
+    Fits principal component analysis with the singular value decomposition of the centred data,
+    which is more accurate than the eigendecomposition of the covariance matrix.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Options Options: the settings
+RETURN
+    PCA: the fitted model
+    error: *ParameterError if the options or the data are invalid, *FactorisationError if the SVD fails
+*/
+func Fit(X *mat.Dense, Options Options) (PCA, error) {
+    n, d := X.Dims()
+    mean, values, v, err := decompose(X)
+    if err != nil { return PCA{}, err }
+    model, err := fromSingularValues(values, n, d, Options)
+    if err != nil { return PCA{}, err }
+    model.Mean = mean
+    model.Components = mat.DenseCopyOf(v.Slice(0, d, 0, len(model.SingularValues)))
+    return model, nil
+}
+
+
+/*
+SUMMARY
+    Projects points onto the principal axes.
+PARAMETERS
+    X *mat.Dense: the points, each row is a point
+RETURN
+    *mat.Dense: N by K matrix, the coordinates along the axes (divided by their
+        standard deviations when whitening)
+*/
+func (p PCA) Transform(X *mat.Dense) *mat.Dense {
+    n, _ := X.Dims()
+    _, k := p.Components.Dims()
+    Z := mat.NewDense(n, k, nil)
+    Z.Mul(centre(X, p.Mean), p.Components)
+    if p.Whiten {
+        Z.Apply(func (i, j int, v float64) float64 { return v / math.Sqrt(p.ExplainedVariance[j]) }, Z)
+    }
+    return Z
+}
+
+
+/*
+SUMMARY
+    Maps coordinates along the principal axes back to the data space. For points of the
+    training data this is the best rank K approximation in the least squares sense.
+PARAMETERS
+    Z *mat.Dense: N by K matrix, the output of Transform
+RETURN
+    *mat.Dense: the reconstructed points, each row is a point
+*/
+func (p PCA) InverseTransform(Z *mat.Dense) *mat.Dense {
+    n, _ := Z.Dims()
+    d, _ := p.Components.Dims()
+    unscaled := Z
+    if p.Whiten {
+        unscaled = mat.NewDense(n, len(p.ExplainedVariance), nil)
+        unscaled.Apply(func (i, j int, v float64) float64 { return v * math.Sqrt(p.ExplainedVariance[j]) }, Z)
+    }
+    X := mat.NewDense(n, d, nil)
+    X.Mul(unscaled, p.Components.T())
+    X.Apply(func (i, j int, v float64) float64 { return v + p.Mean[j] }, X)
+    return X
+}
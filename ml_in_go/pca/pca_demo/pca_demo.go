@@ -0,0 +1,183 @@
+package main
+
+import (
+    "fmt"
+    "math"
+    "time"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/stat"
+
+    "ml_playground/pca"
+    "ml_playground/pic"
+)
+
+
+/*
+SUMMARY
+    Turns an image into a matrix whose rows are the rows of the image, the three channels side by side.
+PARAMETERS
+    img pic.RGBImg: the image
+RETURN
+    *mat.Dense: height by 3*width matrix
+*/
+func ImageRows(img pic.RGBImg) *mat.Dense {
+    height, width := img[0].Dims()
+    X := mat.NewDense(height, 3*width, nil)
+    for c := 0; c < 3; c++ {
+        X.Slice(0, height, c*width, (c+1)*width).(*mat.Dense).Copy(&img[c])
+    }
+    return X
+}
+
+
+/*
+SUMMARY
+    The inverse of ImageRows, the values are clipped to [0,255].
+PARAMETERS
+    X *mat.Dense: height by 3*width matrix
+RETURN
+    pic.RGBImg: the image
+*/
+func RowsImage(X *mat.Dense) pic.RGBImg {
+    height, width3 := X.Dims()
+    width := width3 / 3
+    var img pic.RGBImg = make([]mat.Dense, 3)
+    for c := 0; c < 3; c++ {
+        img[c] = *mat.DenseCopyOf(X.Slice(0, height, c*width, (c+1)*width))
+        img[c].Apply(func (i, j int, v float64) float64 { return math.Min(math.Max(v, 0.0), 255.0) }, &img[c])
+    }
+    return img
+}
+
+
+/*
+SUMMARY
+    The sine of the largest principal angle between the spaces spanned by two sets of orthonormal axes.
+PARAMETERS
+    A *mat.Dense: D by K matrix, orthonormal columns
+    B *mat.Dense: D by K matrix, orthonormal columns
+RETURN
+    float64: 0 if the spaces coincide, 1 if they have orthogonal directions
+*/
+func SubspaceDistance(A, B *mat.Dense) float64 {
+    var product mat.Dense
+    product.Mul(A.T(), B)
+    var svd mat.SVD
+    if ok := svd.Factorize(&product, mat.SVDNone); !ok { panic("SVD has failed") }
+    values := svd.Values(nil)
+    smallest := math.Min(values[len(values)-1], 1.0)
+    return math.Sqrt(1.0 - smallest * smallest)
+}
+
+
+/*
+SUMMARY
+    Prints how far an approximate principal component analysis is from the exact one: the largest
+    relative error of the singular values, the subspace distance of the leading half of the axes (the
+    trailing axes have close singular values, so they are ill-determined) and the reconstruction RMSE.
+PARAMETERS
+    Name string: the name of the method
+    X *mat.Dense: the data, each row is a point
+    Exact pca.PCA: the exact solution
+    Approximate pca.PCA: the approximate solution
+    Elapsed time.Duration: the time taken by the approximate method
+RETURN
+    N/A
+*/
+func Compare(Name string, X *mat.Dense, Exact, Approximate pca.PCA, Elapsed time.Duration) {
+    valueError := 0.0
+    for c, s := range Exact.SingularValues {
+        valueError = math.Max(valueError, math.Abs(Approximate.SingularValues[c] - s) / s)
+    }
+    d, k := Exact.Components.Dims()
+    distance := SubspaceDistance(Exact.Components.Slice(0, d, 0, k/2).(*mat.Dense), Approximate.Components.Slice(0, d, 0, k/2).(*mat.Dense))
+    rmse := func (model pca.PCA) float64 {
+        var residual mat.Dense
+        residual.Sub(X, model.InverseTransform(model.Transform(X)))
+        n, d := X.Dims()
+        return mat.Norm(&residual, 2) / math.Sqrt(float64(n*d))
+    }
+    fmt.Printf("    %-11s %8v, singular values within %.1e, leading subspace distance %.1e, RMSE %.4f (exact %.4f)\n",
+        Name, Elapsed.Round(time.Millisecond), valueError, distance, rmse(Approximate), rmse(Exact))
+}
+
+
+/*
+SUMMARY
+    Checks the randomised and the incremental PCA against the exact solution.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    NumComponents int: the number of principal components
+    BatchSize int: the number of points in a batch of the incremental PCA
+RETURN
+    N/A
+*/
+func Accuracy(X *mat.Dense, NumComponents, BatchSize int) {
+    n, d := X.Dims()
+    fmt.Printf("%d by %d data, %d components\n", n, d, NumComponents)
+    start := time.Now()
+    exact, err := pca.Fit(X, pca.Options{NumComponents: NumComponents})
+    if err != nil { panic(err) }
+    fmt.Printf("    %-11s %8v\n", "exact", time.Since(start).Round(time.Millisecond))
+
+    start = time.Now()
+    randomised, err := pca.FitRandomised(X, pca.RandomisedOptions{NumComponents: NumComponents, Seed: 1})
+    if err != nil { panic(err) }
+    Compare("randomised", X, exact, randomised, time.Since(start))
+
+    start = time.Now()
+    incremental := pca.NewIncrementalPCA(NumComponents, false)
+    for first:=0; first<n; first+=BatchSize {
+        last := first + BatchSize
+        if last > n { last = n }
+        if err := incremental.PartialFit(mat.DenseCopyOf(X.Slice(first, last, 0, d))); err != nil { panic(err) }
+    }
+    Compare("incremental", X, exact, incremental.PCA(), time.Since(start))
+}
+
+
+/*
+We compress an image with PCA: the rows of the image are the points, we keep the smallest number
+of principal components which explain a given fraction of the variance and reconstruct the image.
+We also check that whitened components are uncorrelated with unit variance, and compare the
+randomised and the incremental PCA with the exact one on the wide image and on its transpose.
+*/
+func main() {
+    var img pic.RGBImg = make([]mat.Dense, 3)
+    if err := img.LoadPixels("image.jpg"); err != nil { panic(err) }
+    X := ImageRows(img)
+    height, width3 := X.Dims()
+
+    for _, threshold := range []float64{0.9, 0.99} {
+        model, err := pca.Fit(X, pca.Options{VarianceThreshold: threshold})
+        if err != nil { panic(err) }
+        k := len(model.SingularValues)
+        reconstruction := model.InverseTransform(model.Transform(X))
+        var residual mat.Dense
+        residual.Sub(X, reconstruction)
+        rmse := mat.Norm(&residual, 2) / math.Sqrt(float64(height*width3))
+        stored := k * (height + width3 + 1) + width3
+        fmt.Printf("%.0f%% of the variance: %d components, %.1f%% of the storage, RMSE %.4f\n",
+            100*threshold, k, 100*float64(stored)/float64(height*width3), rmse)
+        RowsImage(reconstruction).SaveImage(fmt.Sprintf("image_%d_components.jpg", k))
+    }
+
+    whitened, err := pca.Fit(X, pca.Options{NumComponents: 10, Whiten: true})
+    if err != nil { panic(err) }
+    Z := whitened.Transform(X)
+    covariance := mat.NewSymDense(10, nil)
+    stat.CovarianceMatrix(covariance, Z, nil)
+    deviation := 0.0
+    for i:=0; i<10; i++ {
+        for j:=0; j<10; j++ {
+            identity := 0.0
+            if i == j { identity = 1.0 }
+            deviation = math.Max(deviation, math.Abs(covariance.At(i, j) - identity))
+        }
+    }
+    fmt.Printf("largest deviation of the whitened covariance from the identity %.2e\n", deviation)
+
+    Accuracy(X, 20, 100)
+    Accuracy(mat.DenseCopyOf(X.T()), 20, 200)
+}
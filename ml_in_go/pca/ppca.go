@@ -0,0 +1,392 @@
+package pca
+
+import (
+    "math"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+    "gonum.org/v1/gonum/stat/distuv"
+)
+
+
+/*
+This type stores a probabilistic PCA model (Tipping & Bishop, Probabilistic Principal Component Analysis):
+x = W z + Mean + noise where z ~ N(0,I) and noise ~ N(0, NoiseVariance I), hence x ~ N(Mean, W W^T + NoiseVariance I).
+    Mean []float64: the mean of the data
+    W *mat.Dense: D by K matrix, the linear map from the latent space
+    NoiseVariance float64: the variance of the isotropic noise
+*/
+type PPCA struct {
+    Mean []float64
+    W *mat.Dense
+    NoiseVariance float64
+}
+
+
+/*
+This type configures the EM algorithm of probabilistic PCA.
+    NumComponents int: the dimension of the latent space
+    MaxIterations int: the maximal number of iterations (200 if 0)
+    Tolerance float64: stop when the log-likelihood per observed entry improves less than this
+*/
+type EMOptions struct {
+    NumComponents int
+    MaxIterations int
+    Tolerance float64
+}
+
+
+/*
+This type stores the outcome of an EM algorithm.
+    LogLikelihoods []float64: the log-likelihood of the observed entries at the start of each iteration
+    Iterations int: the number of iterations
+    Converged bool: whether the algorithm stopped because of the tolerance
+*/
+type EMResult struct {
+    LogLikelihoods []float64
+    Iterations int
+    Converged bool
+}
+
+
+/*
+SUMMARY
+    The closed-form maximum likelihood solution from the singular values of the centred data:
+    NoiseVariance is the average of the discarded eigenvalues of the sample covariance and
+    W = U (Lambda - NoiseVariance I)^{1/2} where U holds the leading eigenvectors.
+PARAMETERS
+    Mean []float64: the mean of the data
+    Values []float64: the singular values of the centred data in decreasing order
+    V *mat.Dense: the right singular vectors
+    N int: the number of points
+    NumComponents int: the dimension of the latent space
+RETURN
+    PPCA: the model
+*/
+func maximumLikelihood(Mean, Values []float64, V *mat.Dense, N, NumComponents int) PPCA {
+    d, _ := V.Dims()
+    // the eigenvalues of the sample covariance, the ones beyond min(N,D) are 0
+    eigenvalues := make([]float64, d)
+    for i, s := range Values { eigenvalues[i] = s * s / float64(N) }
+    model := PPCA{Mean: Mean, W: mat.NewDense(d, NumComponents, nil)}
+    if d > NumComponents { model.NoiseVariance = floats.Sum(eigenvalues[NumComponents:]) / float64(d - NumComponents) }
+    for k:=0; k<NumComponents; k++ {
+        scale := math.Sqrt(math.Max(eigenvalues[k] - model.NoiseVariance, 0.0))
+        for j:=0; j<d; j++ { model.W.Set(j, k, scale * V.At(j, k)) }
+    }
+    return model
+}
+
+
+/*
+SUMMARY
+    Fits probabilistic PCA with the closed-form maximum likelihood solution.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    NumComponents int: the dimension of the latent space
+RETURN
+    PPCA: the fitted model
+    error: *ParameterError if the data or NumComponents are invalid, *FactorisationError if the SVD fails
+*/
+func FitPPCA(X *mat.Dense, NumComponents int) (PPCA, error) {
+    n, d := X.Dims()
+    if NumComponents <= 0 || NumComponents >= d || NumComponents > n {
+        return PPCA{}, &ParameterError{Parameter: "NumComponents", Value: float64(NumComponents), Expected: "in [1, #dimensions) and at most #points"}
+    }
+    mean, values, v, err := decompose(X)
+    if err != nil { return PPCA{}, err }
+    return maximumLikelihood(mean, values, v, n, NumComponents), nil
+}
+
+
+/*
+SUMMARY
+    The covariance of the data under the model, W W^T + NoiseVariance I.
+PARAMETERS
+    N/A
+RETURN
+    *mat.SymDense: the covariance
+*/
+func (m PPCA) Covariance() *mat.SymDense {
+    d, _ := m.W.Dims()
+    covariance := mat.NewSymDense(d, nil)
+    covariance.SymOuterK(1.0, m.W)
+    for j:=0; j<d; j++ { covariance.SetSym(j, j, covariance.At(j, j) + m.NoiseVariance) }
+    return covariance
+}
+
+
+/*
+SUMMARY
+    The observed coordinates of a point, the missing ones are NaN.
+PARAMETERS
+    X []float64: the point
+RETURN
+    []int: the indices of the observed coordinates
+*/
+func observed(X []float64) []int {
+    var indices []int
+    for j, x := range X {
+        if !math.IsNaN(x) { indices = append(indices, j) }
+    }
+    return indices
+}
+
+
+/*
+SUMMARY
+    The log-likelihood of data under a Gaussian. Missing entries (NaN) are marginalised out.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Mean []float64: the mean of the Gaussian
+    Covariance *mat.SymDense: the covariance of the Gaussian
+RETURN
+    float64: \sum_n log p(x_n)
+    error: *FactorisationError if the covariance is not positive definite
+*/
+func gaussianLogLikelihood(X *mat.Dense, Mean []float64, Covariance *mat.SymDense) (float64, error) {
+    n, _ := X.Dims()
+    logLikelihood := 0.0
+    for i:=0; i<n; i++ {
+        row := X.RawRowView(i)
+        indices := observed(row)
+        if len(indices) == 0 { continue }
+        sub := mat.NewSymDense(len(indices), nil)
+        diff := mat.NewVecDense(len(indices), nil)
+        for a, ja := range indices {
+            diff.SetVec(a, row[ja] - Mean[ja])
+            for b, jb := range indices[a:] { sub.SetSym(a, a+b, Covariance.At(ja, jb)) }
+        }
+        var chol mat.Cholesky
+        if ok := chol.Factorize(sub); !ok { return 0, &FactorisationError{Factorisation: "Cholesky"} }
+        var solved mat.VecDense
+        if err := chol.SolveVecTo(&solved, diff); err != nil { return 0, err }
+        logLikelihood -= 0.5 * (float64(len(indices)) * math.Log(2*math.Pi) + chol.LogDet() + mat.Dot(diff, &solved))
+    }
+    return logLikelihood, nil
+}
+
+
+/*
+SUMMARY
+    The log-likelihood of the data. Missing entries (NaN) are marginalised out.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    float64: \sum_n log p(x_n)
+    error: *FactorisationError if the covariance is not positive definite
+*/
+func (m PPCA) LogLikelihood(X *mat.Dense) (float64, error) {
+    return gaussianLogLikelihood(X, m.Mean, m.Covariance())
+}
+
+
+/*
+SUMMARY
+    The posterior of the latent variable of one point given its observed coordinates,
+    p(z|x) = N(M^{-1} W_O^T (x_O - Mean_O), NoiseVariance M^{-1}) where M = W_O^T W_O + NoiseVariance I.
+PARAMETERS
+    X []float64: the point, missing coordinates are NaN
+RETURN
+    []float64: the posterior mean
+    *mat.SymDense: the posterior covariance
+    error: *FactorisationError if M is not positive definite
+*/
+func (m PPCA) latentPosterior(X []float64) ([]float64, *mat.SymDense, error) {
+    _, k := m.W.Dims()
+    indices := observed(X)
+    precision := mat.NewSymDense(k, nil)
+    projection := make([]float64, k)
+    for _, j := range indices {
+        w := m.W.RawRowView(j)
+        precision.SymRankOne(precision, 1.0, mat.NewVecDense(k, w))
+        floats.AddScaled(projection, X[j] - m.Mean[j], w)
+    }
+    for a:=0; a<k; a++ { precision.SetSym(a, a, precision.At(a, a) + m.NoiseVariance) }
+    var chol mat.Cholesky
+    if ok := chol.Factorize(precision); !ok { return nil, nil, &FactorisationError{Factorisation: "Cholesky"} }
+    var mean mat.VecDense
+    if err := chol.SolveVecTo(&mean, mat.NewVecDense(k, projection)); err != nil { return nil, nil, err }
+    var covariance mat.SymDense
+    if err := chol.InverseTo(&covariance); err != nil { return nil, nil, err }
+    covariance.ScaleSym(m.NoiseVariance, &covariance)
+    return mean.RawVector().Data, &covariance, nil
+}
+
+
+/*
+SUMMARY
+    The posterior distribution of the latent variables of complete data points. Every point
+    shares the posterior covariance NoiseVariance (W^T W + NoiseVariance I)^{-1}.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    *mat.Dense: N by K matrix, the posterior means
+    *mat.SymDense: the posterior covariance
+    error: *FactorisationError if the posterior precision is not positive definite
+*/
+func (m PPCA) Posterior(X *mat.Dense) (*mat.Dense, *mat.SymDense, error) {
+    n, _ := X.Dims()
+    _, k := m.W.Dims()
+    means := mat.NewDense(n, k, nil)
+    var covariance *mat.SymDense
+    for i:=0; i<n; i++ {
+        mean, sigma, err := m.latentPosterior(X.RawRowView(i))
+        if err != nil { return nil, nil, err }
+        means.SetRow(i, mean)
+        covariance = sigma
+    }
+    return means, covariance, nil
+}
+
+
+/*
+SUMMARY
+    Fills in the missing entries (NaN) with their conditional expectation W_M E[z|x_O] + Mean_M.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+RETURN
+    *mat.Dense: the completed copy of X
+    error: *FactorisationError if a posterior precision is not positive definite
+*/
+func (m PPCA) Impute(X *mat.Dense) (*mat.Dense, error) {
+    n, d := X.Dims()
+    completed := mat.DenseCopyOf(X)
+    for i:=0; i<n; i++ {
+        row := completed.RawRowView(i)
+        mean, _, err := m.latentPosterior(row)
+        if err != nil { return nil, err }
+        for j:=0; j<d; j++ {
+            if math.IsNaN(row[j]) { row[j] = m.Mean[j] + floats.Dot(m.W.RawRowView(j), mean) }
+        }
+    }
+    return completed, nil
+}
+
+
+/*
+SUMMARY
+    Draws points from the generative model.
+PARAMETERS
+    N int: the number of points
+    Src rand.Source: the source of randomness
+RETURN
+    *mat.Dense: the points, each row is a point
+*/
+func (m PPCA) Sample(N int, Src rand.Source) *mat.Dense {
+    d, k := m.W.Dims()
+    normal := distuv.Normal{Mu: 0, Sigma: 1, Src: Src}
+    Z := mat.NewDense(N, k, nil)
+    Z.Apply(func (i, j int, v float64) float64 { return normal.Rand() }, Z)
+    X := mat.NewDense(N, d, nil)
+    X.Mul(Z, m.W.T())
+    noise := math.Sqrt(m.NoiseVariance)
+    X.Apply(func (i, j int, v float64) float64 { return v + m.Mean[j] + noise * normal.Rand() }, X)
+    return X
+}
+
+
+/*
+SUMMARY
+    Fits probabilistic PCA with the EM algorithm, which allows missing entries (NaN) in the data.
+    The E-step computes the posterior of every latent variable from the observed coordinates, the M-step
+    updates the mean, every row of W and the noise variance using the observed entries only
+    (Ilin & Raiko, Practical Approaches to Principal Component Analysis in the Presence of Missing Values).
+    The model is initialised with the closed-form solution on the mean-imputed data.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point, missing entries are NaN
+    Options EMOptions: the settings
+RETURN
+    PPCA: the fitted model
+    EMResult: the log-likelihood trace and the convergence
+    error: *ParameterError if the data or the options are invalid, *FactorisationError if a factorisation fails
+*/
+func FitPPCAEM(X *mat.Dense, Options EMOptions) (PPCA, EMResult, error) {
+    n, d := X.Dims()
+    k := Options.NumComponents
+    if k <= 0 || k >= d || k > n {
+        return PPCA{}, EMResult{}, &ParameterError{Parameter: "NumComponents", Value: float64(k), Expected: "in [1, #dimensions) and at most #points"}
+    }
+    maxIterations := Options.MaxIterations
+    if maxIterations <= 0 { maxIterations = 200 }
+
+    // the column means of the observed entries
+    mean := make([]float64, d)
+    counts := make([]float64, d)
+    numObserved := 0.0
+    for i:=0; i<n; i++ {
+        for _, j := range observed(X.RawRowView(i)) {
+            mean[j] += X.At(i, j)
+            counts[j]++
+            numObserved++
+        }
+    }
+    for j := range mean {
+        if counts[j] == 0 { return PPCA{}, EMResult{}, &ParameterError{Parameter: "observed entries of a column", Value: 0, Expected: "at least 1"} }
+        mean[j] /= counts[j]
+    }
+    imputed := mat.DenseCopyOf(X)
+    imputed.Apply(func (i, j int, v float64) float64 { if math.IsNaN(v) { return mean[j] }; return v }, imputed)
+    _, values, v, err := decompose(imputed)
+    if err != nil { return PPCA{}, EMResult{}, err }
+    model := maximumLikelihood(mean, values, v, n, k)
+    model.NoiseVariance = math.Max(model.NoiseVariance, 1e-6)
+
+    var result EMResult
+    latentMeans := mat.NewDense(n, k, nil)
+    latentCovariances := make([]*mat.SymDense, n)
+    previous := math.Inf(-1)
+    for result.Iterations < maxIterations {
+        result.Iterations++
+        logLikelihood, err := model.LogLikelihood(X)
+        if err != nil { return model, result, err }
+        result.LogLikelihoods = append(result.LogLikelihoods, logLikelihood)
+        if (logLikelihood - previous) / numObserved < Options.Tolerance {
+            result.Converged = true
+            break
+        }
+        previous = logLikelihood
+
+        // E-step
+        for i:=0; i<n; i++ {
+            latentMean, covariance, err := model.latentPosterior(X.RawRowView(i))
+            if err != nil { return model, result, err }
+            latentMeans.SetRow(i, latentMean)
+            latentCovariances[i] = covariance
+        }
+        // M-step, the mean first with the old W
+        for j:=0; j<d; j++ {
+            total := 0.0
+            for i:=0; i<n; i++ {
+                if x := X.At(i, j); !math.IsNaN(x) { total += x - floats.Dot(model.W.RawRowView(j), latentMeans.RawRowView(i)) }
+            }
+            model.Mean[j] = total / counts[j]
+        }
+        noise := 0.0
+        for j:=0; j<d; j++ {
+            second := mat.NewSymDense(k, nil)
+            cross := mat.NewVecDense(k, nil)
+            for i:=0; i<n; i++ {
+                x := X.At(i, j)
+                if math.IsNaN(x) { continue }
+                z := mat.NewVecDense(k, latentMeans.RawRowView(i))
+                second.AddSym(second, latentCovariances[i])
+                second.SymRankOne(second, 1.0, z)
+                cross.AddScaledVec(cross, x - model.Mean[j], z)
+            }
+            var chol mat.Cholesky
+            if ok := chol.Factorize(second); !ok { return model, result, &FactorisationError{Factorisation: "Cholesky"} }
+            row := mat.NewVecDense(k, model.W.RawRowView(j))
+            if err := chol.SolveVecTo(row, cross); err != nil { return model, result, err }
+            for i:=0; i<n; i++ {
+                x := X.At(i, j)
+                if math.IsNaN(x) { continue }
+                residual := x - model.Mean[j] - mat.Dot(row, mat.NewVecDense(k, latentMeans.RawRowView(i)))
+                noise += residual * residual + mat.Inner(row, latentCovariances[i], row)
+            }
+        }
+        model.NoiseVariance = noise / numObserved
+    }
+    return model, result, nil
+}
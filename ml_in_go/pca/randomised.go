@@ -0,0 +1,136 @@
+package pca
+
+import (
+    "math"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+    "gonum.org/v1/gonum/stat/distuv"
+)
+
+
+/*
+This type configures the randomised PCA.
+    NumComponents int: the number of principal components to keep
+    Oversampling int: the number of extra random directions (10 if 0)
+    PowerIterations int: the number of power iterations, more iterations help when the
+        singular values decay slowly (4 if 0, none if negative)
+    Whiten bool: whether Transform scales the components to unit variance
+    Seed int: seed of the random directions
+*/
+type RandomisedOptions struct {
+    NumComponents int
+    Oversampling int
+    PowerIterations int
+    Whiten bool
+    Seed int
+}
+
+
+/*
+SUMMARY
+    Orthonormalises the columns of a matrix in place with the modified Gram-Schmidt process,
+    repeated twice for numerical stability. Columns which are linearly dependent on the previous
+    ones become 0.
+PARAMETERS
+    A *mat.Dense: the matrix, changed in place
+RETURN
+    N/A
+*/
+func orthonormalise(A *mat.Dense) {
+    _, l := A.Dims()
+    for c:=0; c<l; c++ {
+        column := A.ColView(c).(*mat.VecDense)
+        norm := mat.Norm(column, 2)
+        for pass:=0; pass<2; pass++ {
+            for p:=0; p<c; p++ {
+                previous := A.ColView(p)
+                column.AddScaledVec(column, -mat.Dot(previous, column), previous)
+            }
+        }
+        if length := mat.Norm(column, 2); length > 1e-12 * norm {
+            column.ScaleVec(1.0 / length, column)
+        } else {
+            column.Zero()
+        }
+    }
+}
+
+
+/*
+SUMMARY
+    Fits principal component analysis with the randomised singular value decomposition (Halko, Martinsson
+    & Tropp, Finding Structure with Randomness). A Gaussian test matrix with K + Oversampling columns
+    captures the range of the centred data, power iterations sharpen it, and the exact SVD of the
+    small projected matrix gives the components. The cost is O(N D (K + Oversampling)) per pass,
+    so neither the D by D covariance nor the full SVD is ever formed, which suits tall and wide data.
+PARAMETERS
+    X *mat.Dense: the data, each row is a point
+    Options RandomisedOptions: the settings
+RETURN
+    PCA: the fitted model, the explained variance ratios use the exact total variance
+    error: *ParameterError if the options or the data are invalid, *FactorisationError if the SVD fails
+*/
+func FitRandomised(X *mat.Dense, Options RandomisedOptions) (PCA, error) {
+    n, d := X.Dims()
+    k := Options.NumComponents
+    if n < 2 { return PCA{}, &ParameterError{Parameter: "number of points", Value: float64(n), Expected: "at least 2"} }
+    if k <= 0 || k > n || k > d {
+        return PCA{}, &ParameterError{Parameter: "NumComponents", Value: float64(k), Expected: "in [1, min(#points, #dimensions)]"}
+    }
+    oversampling := Options.Oversampling
+    if oversampling <= 0 { oversampling = 10 }
+    powerIterations := Options.PowerIterations
+    if powerIterations == 0 { powerIterations = 4 }
+    l := k + oversampling
+    if l > n { l = n }
+    if l > d { l = d }
+
+    mean := columnMeans(X)
+    centred := centre(X, mean)
+    normal := distuv.Normal{Mu: 0, Sigma: 1, Src: rand.NewSource(uint64(Options.Seed))}
+    omega := mat.NewDense(d, l, nil)
+    omega.Apply(func (i, j int, v float64) float64 { return normal.Rand() }, omega)
+    Q := mat.NewDense(n, l, nil)
+    Q.Mul(centred, omega)
+    orthonormalise(Q)
+    for q:=0; q<powerIterations; q++ {
+        omega.Mul(centred.T(), Q)
+        orthonormalise(omega)
+        Q.Mul(centred, omega)
+        orthonormalise(Q)
+    }
+
+    // the SVD of the small l by D matrix Q^T X
+    B := mat.NewDense(l, d, nil)
+    B.Mul(Q.T(), centred)
+    var svd mat.SVD
+    if ok := svd.Factorize(B, mat.SVDThin); !ok { return PCA{}, &FactorisationError{Factorisation: "SVD"} }
+    var v mat.Dense
+    svd.VTo(&v)
+    fixSigns(&v)
+    values := svd.Values(nil)
+
+    total := 0.0
+    for i:=0; i<n; i++ {
+        row := centred.RawRowView(i)
+        total += floats.Dot(row, row)
+    }
+    total /= float64(n - 1)
+    model := PCA{
+        Mean: mean,
+        Components: mat.DenseCopyOf(v.Slice(0, d, 0, k)),
+        SingularValues: values[:k],
+        ExplainedVariance: make([]float64, k),
+        ExplainedVarianceRatio: make([]float64, k),
+        NumSamples: n,
+        Whiten: Options.Whiten,
+    }
+    for c, s := range model.SingularValues {
+        model.ExplainedVariance[c] = s * s / float64(n - 1)
+        if total > 0 { model.ExplainedVarianceRatio[c] = model.ExplainedVariance[c] / total }
+    }
+    if d > k { model.NoiseVariance = math.Max(total - floats.Sum(model.ExplainedVariance), 0.0) / float64(d - k) }
+    return model, nil
+}
@@ -0,0 +1,132 @@
+package pca
+
+import (
+    "math"
+    "testing"
+    "golang.org/x/exp/rand"
+
+    "gonum.org/v1/gonum/mat"
+)
+
+
+/*
+SUMMARY
+    Random data of low rank with geometrically decaying singular values plus isotropic noise.
+PARAMETERS
+    N int: the number of points
+    D int: the dimension
+    Rank int: the rank of the noiseless data
+    Noise float64: the standard deviation of the noise
+    Seed int: seed of the random numbers
+RETURN
+    *mat.Dense: N by D matrix, each row is a point
+*/
+func lowRankData(N, D, Rank int, Noise float64, Seed int) *mat.Dense {
+    randGen := rand.New(rand.NewSource(uint64(Seed)))
+    random := func (Rows, Cols int) *mat.Dense {
+        A := mat.NewDense(Rows, Cols, nil)
+        A.Apply(func (i, j int, v float64) float64 { return randGen.NormFloat64() }, A)
+        return A
+    }
+    scores, axes := random(N, Rank), random(Rank, D)
+    for r:=0; r<Rank; r++ {
+        row := axes.RawRowView(r)
+        for j := range row { row[j] *= 10.0 * math.Pow(0.6, float64(r)) }
+    }
+    X := mat.NewDense(N, D, nil)
+    X.Mul(scores, axes)
+    X.Apply(func (i, j int, v float64) float64 { return v + 3.0 + Noise * randGen.NormFloat64() }, X)
+    return X
+}
+
+
+/*
+SUMMARY
+    The distance of the subspaces spanned by the orthonormal columns of two matrices,
+    |A A^T - B B^T|_F / sqrt(2), 0 for the same subspace and sqrt(K) for orthogonal ones.
+    The square root amplifies the rounding errors, so equal subspaces are about 1e-7 apart.
+PARAMETERS
+    A *mat.Dense: D by K matrix with orthonormal columns
+    B *mat.Dense: D by K matrix with orthonormal columns
+RETURN
+    float64: the distance
+*/
+func subspaceDistance(A, B *mat.Dense) float64 {
+    _, k := A.Dims()
+    var cross mat.Dense
+    cross.Mul(A.T(), B)
+    norm := mat.Norm(&cross, 2)
+    return math.Sqrt(math.Max(float64(k) - norm * norm, 0.0))
+}
+
+
+/*
+SUMMARY
+    Fails the test if the singular values or the subspace of an approximate PCA are far from the exact one.
+PARAMETERS
+    t *testing.T: the test
+    Name string: the name of the case in the messages
+    Exact PCA: the exact solution
+    Approximate PCA: the approximate solution
+    ValueTolerance float64: the largest relative error of the singular values
+    SubspaceTolerance float64: the largest subspace distance
+RETURN
+    N/A
+*/
+func checkAgainstExact(t *testing.T, Name string, Exact, Approximate PCA, ValueTolerance, SubspaceTolerance float64) {
+    t.Helper()
+    if len(Approximate.SingularValues) != len(Exact.SingularValues) {
+        t.Fatalf("%s: %d singular values, expected %d", Name, len(Approximate.SingularValues), len(Exact.SingularValues))
+    }
+    for c, s := range Exact.SingularValues {
+        if relative := math.Abs(Approximate.SingularValues[c] - s) / s; relative > ValueTolerance {
+            t.Errorf("%s: singular value %d is %g instead of %g", Name, c, Approximate.SingularValues[c], s)
+        }
+    }
+    for j, m := range Exact.Mean {
+        if math.Abs(Approximate.Mean[j] - m) > 1e-9 * (1.0 + math.Abs(m)) {
+            t.Errorf("%s: mean %d is %g instead of %g", Name, j, Approximate.Mean[j], m)
+        }
+    }
+    if distance := subspaceDistance(Exact.Components, Approximate.Components); distance > SubspaceTolerance {
+        t.Errorf("%s: subspace distance %g", Name, distance)
+    }
+}
+
+
+func TestFitRandomised(t *testing.T) {
+    cases := []struct {
+        name string
+        n, d, rank int
+        noise float64
+        valueTolerance, subspaceTolerance float64
+    }{
+        {name: "tall exact rank", n: 300, d: 40, rank: 5, noise: 0, valueTolerance: 1e-10, subspaceTolerance: 1e-6},
+        {name: "wide exact rank", n: 40, d: 300, rank: 5, noise: 0, valueTolerance: 1e-10, subspaceTolerance: 1e-6},
+        {name: "tall noisy", n: 300, d: 40, rank: 8, noise: 0.05, valueTolerance: 1e-6, subspaceTolerance: 1e-4},
+        {name: "wide noisy", n: 40, d: 300, rank: 8, noise: 0.05, valueTolerance: 1e-6, subspaceTolerance: 1e-4},
+    }
+    for _, c := range cases {
+        X := lowRankData(c.n, c.d, c.rank, c.noise, 1)
+        exact, err := Fit(X, Options{NumComponents: 5})
+        if err != nil { t.Fatal(err) }
+        randomised, err := FitRandomised(X, RandomisedOptions{NumComponents: 5, Seed: 2})
+        if err != nil { t.Fatal(err) }
+        checkAgainstExact(t, c.name, exact, randomised, c.valueTolerance, c.subspaceTolerance)
+        for i, r := range exact.ExplainedVarianceRatio {
+            if math.Abs(randomised.ExplainedVarianceRatio[i] - r) > 1e-6 {
+                t.Errorf("%s: explained variance ratio %d is %g instead of %g", c.name, i, randomised.ExplainedVarianceRatio[i], r)
+            }
+        }
+    }
+}
+
+
+func TestFitRandomisedInvalidComponents(t *testing.T) {
+    X := lowRankData(10, 4, 2, 0.1, 1)
+    for _, k := range []int{0, 5, 11} {
+        if _, err := FitRandomised(X, RandomisedOptions{NumComponents: k}); err == nil {
+            t.Errorf("NumComponents %d accepted", k)
+        }
+    }
+}
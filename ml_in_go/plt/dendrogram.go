@@ -0,0 +1,53 @@
+package plt
+
+import (
+    "gonum.org/v1/plot/plotter"
+)
+
+
+/*
+SUMMARY
+    Creates the lines of a dendrogram. The points are the clusters 0, ..., N-1 and the cluster
+    created by the i-th merge is N+i, as in clustering.Dendrogram. Every merge is drawn as a
+    bracket joining its two clusters at its height, the leaves are placed at 0, 1, ..., N-1
+    in an order where no brackets cross.
+PARAMETERS
+    Left []int: the first merged cluster of each merge
+    Right []int: the second merged cluster of each merge
+    Heights []float64: the height of each merge
+    LineWidth float64: the width of the lines
+    HexColour int: the colour of the lines
+RETURN
+    []*plotter.Line: the lines to add to a plot
+    []int: the points in the order of the leaves
+*/
+func MakeDendrogramLines(Left, Right []int, Heights []float64, LineWidth float64, HexColour int) ([]*plotter.Line, []int) {
+    n := len(Left) + 1
+    // the x coordinate and the height of the top of every cluster
+    positions := make([]float64, 2*n - 1)
+    tops := make([]float64, 2*n - 1)
+    var order []int
+    var place func(cluster int)
+    place = func(cluster int) {
+        if cluster < n {
+            positions[cluster] = float64(len(order))
+            order = append(order, cluster)
+            return
+        }
+        merge := cluster - n
+        place(Left[merge])
+        place(Right[merge])
+        positions[cluster] = 0.5 * (positions[Left[merge]] + positions[Right[merge]])
+        tops[cluster] = Heights[merge]
+    }
+    if n > 1 { place(2*n - 2) } else { order = []int{0} }
+
+    lines := make([]*plotter.Line, len(Left))
+    for merge := range Left {
+        left, right := Left[merge], Right[merge]
+        X := []float64{positions[left], positions[left], positions[right], positions[right]}
+        Y := []float64{tops[left], Heights[merge], Heights[merge], tops[right]}
+        lines[merge] = MakeLineUnicorn(X, Y, LineWidth, HexColour, []float64{})
+    }
+    return lines, order
+}
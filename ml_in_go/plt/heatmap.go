@@ -43,12 +43,12 @@ SUMMARY
 PARAMETERS
     N/A
 RETURN
-    int: height in pixels
-    int width in pixels
+    int: width in pixels (the number of columns)
+    int: height in pixels (the number of rows)
 */
 func (f *MatrixHeatMap) Dims() (int, int) {
     H, W := f.Matrix.Dims()
-    return H, W
+    return W, H
 }
 
 
@@ -90,8 +90,9 @@ RETURN
     float64: z coordinate r row and c column in the matrix
 */
 func (f *MatrixHeatMap) Z(c, r int) float64 {
-    _, M := f.Matrix.Dims()
-    return f.Matrix.At(c, M-r-1)
+    // the first row of the matrix is at the top, like in an image
+    H, _ := f.Matrix.Dims()
+    return f.Matrix.At(H-r-1, c)
 }
 
 
@@ -185,7 +186,7 @@ func FillImage (data plotter.GridXYZ, pal palette.Palette) *image.RGBA64 {
         for j := 0; j < m; j++ {
             v := data.Z(i, j)
             colorIdx := int((v - min) * float64(len(colors)-1) / (max - min))
-            img.Set(i, n-j, colors[colorIdx])
+            img.Set(i, m-1-j, colors[colorIdx])
         }
     }
     return img
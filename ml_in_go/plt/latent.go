@@ -0,0 +1,103 @@
+package plt
+
+import (
+    "math"
+
+    "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/gonum/floats"
+    "gonum.org/v1/gonum/stat/distmv"
+
+    "gonum.org/v1/plot"
+    "gonum.org/v1/plot/plotter"
+)
+
+
+/*
+SUMMARY
+    Creates the heatmap of the density of a 2d latent space, the mixture of a Gaussian around
+    every latent point. The range covers the points with a margin of 3 standard deviations.
+PARAMETERS
+    Means *mat.Dense: N by 2 matrix, the latent points
+    Covariances []*mat.SymDense: the covariance of each point, or a single covariance shared by every point
+    Resolution int: the number of pixels along each side
+RETURN
+    MatrixHeatMap: the density, the first row of the matrix is the top of the image
+*/
+func MakeLatentDensity(Means *mat.Dense, Covariances []*mat.SymDense, Resolution int) MatrixHeatMap {
+    n, _ := Means.Dims()
+    covariance := func (i int) *mat.SymDense {
+        if len(Covariances) == 1 { return Covariances[0] }
+        return Covariances[i]
+    }
+    margins := make([]float64, 2)
+    for i:=0; i<n; i++ {
+        for c := range margins { margins[c] = math.Max(margins[c], 3.0 * math.Sqrt(covariance(i).At(c, c))) }
+    }
+    xs, ys := mat.Col(nil, 0, Means), mat.Col(nil, 1, Means)
+    XRange := Range{Min: floats.Min(xs) - margins[0], Max: floats.Max(xs) + margins[0]}
+    YRange := Range{Min: floats.Min(ys) - margins[1], Max: floats.Max(ys) + margins[1]}
+    density := mat.NewDense(Resolution, Resolution, nil)
+    // the pixel of a coordinate counted from From, clipped to the image
+    pixel := func (v, From, Length float64) int {
+        return int(math.Min(math.Max((v - From) / Length, 0.0) * float64(Resolution), float64(Resolution - 1)))
+    }
+    width, height := XRange.Max - XRange.Min, YRange.Max - YRange.Min
+    for i:=0; i<n; i++ {
+        mean := Means.RawRowView(i)
+        pdf, ok := distmv.NewNormal(mean, covariance(i), nil)
+        if !ok { panic("Covariance is not positive definite") }
+        // the density is negligible beyond 5 standard deviations
+        xWidth, yWidth := 5.0 * math.Sqrt(covariance(i).At(0, 0)), 5.0 * math.Sqrt(covariance(i).At(1, 1))
+        for r:=pixel(-mean[1] - yWidth, -YRange.Max, height); r<=pixel(-mean[1] + yWidth, -YRange.Max, height); r++ {
+            y := YRange.Max - float64(r) / float64(Resolution) * height
+            for c:=pixel(mean[0] - xWidth, XRange.Min, width); c<=pixel(mean[0] + xWidth, XRange.Min, width); c++ {
+                x := XRange.Min + float64(c) / float64(Resolution) * width
+                density.Set(r, c, density.At(r, c) + pdf.Prob([]float64{x, y}))
+            }
+        }
+    }
+    return MatrixHeatMap{Matrix: density, XRange: XRange, YRange: YRange}
+}
+
+
+/*
+SUMMARY
+    Creates the density plot of a 2d latent space, shared by the latent variable models and the embeddings.
+PARAMETERS
+    Means *mat.Dense: N by 2 matrix, the latent points
+    Covariances []*mat.SymDense: the covariance of each point, or a single covariance shared by every point
+    Title string: the title of the plot
+RETURN
+    *plot.Plot: the resulting plot
+*/
+func LatentDensityPlot(Means *mat.Dense, Covariances []*mat.SymDense, Title string) *plot.Plot {
+    m := MakeLatentDensity(Means, Covariances, 300)
+    p := plot.New()
+    p.Title.Text = Title
+    p.X.Label.Text = `$x$`
+    p.Y.Label.Text = `$y$`
+    img := FillImage(&m, DesignedPalette{Type: BLACK_BODY_PALETTE, Num: 256})
+    p.Add(plotter.NewImage(img, m.XRange.Min, m.YRange.Min, m.XRange.Max, m.YRange.Max))
+    return p
+}
+
+
+/*
+SUMMARY
+    Creates the scatter plot of a 2d latent space, the colours follow the order of the points.
+PARAMETERS
+    Points *mat.Dense: N by 2 matrix, the latent points
+    Title string: the title of the plot
+RETURN
+    *plot.Plot: the resulting plot
+*/
+func LatentScatterPlot(Points *mat.Dense, Title string) *plot.Plot {
+    n, _ := Points.Dims()
+    p := plot.New()
+    p.Title.Text = Title
+    p.X.Label.Text = `$x$`
+    p.Y.Label.Text = `$y$`
+    pal := DesignedPalette{Type: KINDLMANN_PALETTE, Num: n}
+    p.Add(MakeScatterUnicorn(mat.Col(nil, 0, Points), mat.Col(nil, 1, Points), CIRCLE_POINT_MARKER, 3.0, pal))
+    return p
+}
@@ -0,0 +1,98 @@
+package plt
+
+import (
+    "image/color"
+    "sort"
+    "gonum.org/v1/plot/palette"
+    "gonum.org/v1/plot/plotter"
+)
+
+
+/*
+SUMMARY
+    Creates one line per chain showing the draws against the iteration number (trace plot).
+    Well mixed chains look like overlapping noise without trends.
+PARAMETERS
+    Draws [][]float64: chains by draws of one parameter
+    LineWidth float64: the width of the lines
+    Palette palette.Palette: the colours of the chains (reused cyclically)
+RETURN
+    []*plotter.Line: the lines to add to a plot
+*/
+func MakeTraceLines(Draws [][]float64, LineWidth float64, Palette palette.Palette) []*plotter.Line {
+    colours := Palette.Colors()
+    lines := make([]*plotter.Line, len(Draws))
+    for c, chain := range Draws {
+        X := make([]float64, len(chain))
+        for i := range X { X[i] = float64(i) }
+        r, g, b, a := colours[c % len(colours)].RGBA()
+        lines[c] = MakeLineUnicorn(X, chain, LineWidth, RGBA2HEX(r, g, b, a), []float64{})
+    }
+    return lines
+}
+
+
+/*
+SUMMARY
+    Creates a rank plot: the draws are ranked over every chain and each chain gets a step line of
+    the histogram of its ranks. For well mixed chains every histogram is close to uniform.
+PARAMETERS
+    Draws [][]float64: chains by draws of one parameter
+    NumBins int: the number of histogram bins
+    LineWidth float64: the width of the lines
+    Palette palette.Palette: the colours of the chains (reused cyclically)
+RETURN
+    []*plotter.Line: the lines to add to a plot
+*/
+func MakeRankLines(Draws [][]float64, NumBins int, LineWidth float64, Palette palette.Palette) []*plotter.Line {
+    type entry struct {
+        value float64
+        chain int
+    }
+    var pooled []entry
+    for c, chain := range Draws {
+        for _, x := range chain { pooled = append(pooled, entry{value: x, chain: c}) }
+    }
+    sort.Slice(pooled, func(a, b int) bool { return pooled[a].value < pooled[b].value })
+    counts := make([][]float64, len(Draws))
+    for c := range counts { counts[c] = make([]float64, NumBins) }
+    for rank, e := range pooled {
+        counts[e.chain][rank * NumBins / len(pooled)]++
+    }
+
+    colours := Palette.Colors()
+    lines := make([]*plotter.Line, len(Draws))
+    for c := range Draws {
+        X := make([]float64, 0, 2*NumBins)
+        Y := make([]float64, 0, 2*NumBins)
+        for bin:=0; bin<NumBins; bin++ {
+            X = append(X, float64(bin), float64(bin + 1))
+            Y = append(Y, counts[c][bin], counts[c][bin])
+        }
+        r, g, b, a := colours[c % len(colours)].RGBA()
+        lines[c] = MakeLineUnicorn(X, Y, LineWidth, RGBA2HEX(r, g, b, a), []float64{})
+    }
+    return lines
+}
+
+
+/*
+SUMMARY
+    Creates a normalised histogram of the draws pooled over every chain, an estimate of the marginal density.
+PARAMETERS
+    Draws [][]float64: chains by draws of one parameter
+    NumBins int: the number of histogram bins
+    HexColour int: the fill colour of the bars
+RETURN
+    *plotter.Histogram: the histogram to add to a plot
+*/
+func MakeMarginalHistogram(Draws [][]float64, NumBins int, HexColour int) *plotter.Histogram {
+    var values plotter.Values
+    for _, chain := range Draws { values = append(values, chain...) }
+    histogram, err := plotter.NewHist(values, NumBins)
+    if err != nil { panic(err) }
+    histogram.Normalize(1)
+    r, g, b, a := HEX2RGBA(HexColour)
+    histogram.FillColor = color.RGBA{R: r, G: g, B: b, A: a}
+    return histogram
+}
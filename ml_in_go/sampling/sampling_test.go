@@ -0,0 +1,20 @@
+package main
+
+import (
+    "testing"
+
+    "ml_playground/mrf"
+    "ml_playground/utils"
+)
+
+
+func TestGraphCutBeatsICM(t *testing.T) {
+    matrix := LoadNoisyScottie()
+    // on a model where the coupling matters ICM stops in a local minimum, the minimal cut is the global one
+    model := mrf.NewIsingModel(matrix, mrf.EIGHT_NEIGHBOURHOOD, 1.0, 1.5)
+    icmEnergy := model.Energy(utils.Flatten(model.ICM(10), true))
+    graphCutEnergy := model.Energy(utils.Flatten(model.GraphCut(), true))
+    if graphCutEnergy > icmEnergy {
+        t.Errorf("graph cut energy %g exceeds the ICM energy %g", graphCutEnergy, icmEnergy)
+    }
+}
@@ -1,165 +1,94 @@
 package main
 
 import (
+    "fmt"
     "math"
-    "golang.org/x/exp/rand"
+    "runtime"
 
     "gonum.org/v1/gonum/mat"
+    "gonum.org/v1/plot"
+    "gonum.org/v1/plot/plotter"
+    "gonum.org/v1/plot/vg"
 
+    "ml_playground/mrf"
     "ml_playground/pic"
+    "ml_playground/plt"
     "ml_playground/utils"
 )
 
 
-// random number seed and source
+// random number seed
 var randSeed = 10
-var randSrc = rand.NewSource(uint64(randSeed))
-
-
-// this type stores a pair of coordinate
-type Coord struct {
-    Y int
-    X int
-}
 
 
 /*
 SUMMARY
-    Returns the set of neighbours of a pixel in all 8 directions.
+    Turns a restored image of -1s and +1s into a grayscale RGB image.
 PARAMETERS
-    j int: row of the pixel
-    i int: col of the pixel
-    Height int: the height of the image
-    Width int: the width of the image
+    Channel *mat.Dense: the restored image
 RETURN
-    []Coord: set of coordinates (pair of indices) of all neighbours
+    pic.RGBImg: the image with values in [0,255]
 */
-func Neighbours(j, i int, Height, Width int) []Coord {
-    if j == 0 && i == 0 {
-        return []Coord{Coord{1,0},
-                       Coord{1,1},
-                       Coord{0,1}}
-    } else if j == Height-1 && i == Width-1 {
-        return []Coord{Coord{Height-2, Width-1},
-                       Coord{Height-2, Width-2},
-                       Coord{Height-1, Width-2}}
-    } else if j == Height-1 && i == 0 {
-        return []Coord{Coord{Height-2, 0},
-                       Coord{Height-2, 1},
-                       Coord{Height-1, 1}}
-    } else if j == 0 && i == Width - 1 {
-        return []Coord{Coord{0, Width-2},
-                       Coord{1, Width-2},
-                       Coord{1, Width-1}}
-    } else if j == Height-1 {
-        return []Coord{Coord{Height-1, i+1},
-                       Coord{Height-1, i-1},
-                       Coord{Height-2, i},
-                       Coord{Height-2, i-1},
-                       Coord{Height-2, i+1}}
-    } else if j == 0 {
-        return []Coord{Coord{0, i+1},
-                       Coord{0, i-1},
-                       Coord{1, i},
-                       Coord{1, i-1},
-                       Coord{1, i+1}}
-    } else if i == Width-1 {
-        return []Coord{Coord{j-1, Width-1},
-                       Coord{j+1, Width-1},
-                       Coord{j, Width-2},
-                       Coord{j-1, Width-2},
-                       Coord{j+1, Width-2}}
-    } else if i == 0 {
-        return []Coord{Coord{j-1, 0},
-                       Coord{j+1, 0},
-                       Coord{j, 1},
-                       Coord{j-1, 1},
-                       Coord{j+1, 1}}
-    } else {
-        return []Coord{Coord{j, i+1},
-                       Coord{j, i-1},
-                       Coord{j-1, i},
-                       Coord{j-1, i-1},
-                       Coord{j-1, i+1},
-                       Coord{j+1, i},
-                       Coord{j+1, i-1},
-                       Coord{j+1, i+1}}
+func ToRGB(Channel *mat.Dense) pic.RGBImg {
+    Height, Width := Channel.Dims()
+    var img pic.RGBImg = make([]mat.Dense, 3)
+    for c := range img {
+        img[c] = *mat.NewDense(Height, Width, nil)
+        img[c].Apply(func (j, i int, v float64) float64 { return (Channel.At(j, i)+1)*127.5 }, &img[c])
     }
+    return img
 }
 
 
 /*
 SUMMARY
-    Computes the sum: `\sum_{j\in\mathcal{N}(I)} Value\cdot X_j` where `\mathcal{N}(I)` is the set
-    of neighbours of I in X. When there are less than eight neighbours the sum is normalised.
+    Plots per-pixel probabilities as a heatmap.
 PARAMETERS
-    j int: row of the pixel
-    i int: col of the pixel
-    Width int: the width of the image
+    Marginals *mat.Dense: the probability of +1 for each pixel
+    Title string: the title of the plot
 RETURN
-    []Coord: set of coordinates (pair of indices) of all neighbours
+    *plot.Plot: the plot
 */
-func NeighbourSum(I int, Value float64, X []float64, Width int) float64 {
-    Height := len(X) / Width
-    coord2Index := func (y, x int) int {
-        return y*Width + x
+func MarginalHeatMap(Marginals *mat.Dense, Title string) *plot.Plot {
+    Height, Width := Marginals.Dims()
+    m := plt.MatrixHeatMap{
+        Matrix: Marginals,
+        XRange: plt.Range{Min: 0.0, Max: float64(Width)},
+        YRange: plt.Range{Min: 0.0, Max: float64(Height)},
     }
-    index2Coord := func (index int) (int, int) {
-        return index / Width, index % Width
-    }
-    miniSum := 0.0
-    row, col := index2Coord(I)
-    neighbours := Neighbours(row , col, Height, Width)
-    for _, neighbour := range neighbours {
-        index := coord2Index(neighbour.Y, neighbour.X)
-        miniSum += X[index] * Value
-    }
-    return miniSum / float64(len(neighbours)) * 8.0
+    pal := plt.DesignedPalette{Type: plt.BLACK_BODY_PALETTE, Num: 100}
+    p := plot.New()
+    p.Title.Text = Title
+    p.Add(plotter.NewImage(plt.FillImage(&m, pal), 0, 0, float64(Width), float64(Height)))
+    return p
 }
 
 
 /*
 SUMMARY
-    Returns a large value if X is likely to have generated Y.
-PARAMETERS
-    X float64: x value
-    Y float64: y value
-RETURN
-    float64: large if |2*Y-1 - X|<epsilon, else small
-
-*/
-func L(X, Y float64) float64 {
-    return math.Exp(-0.5*math.Pow(2*Y-1 - X, 2))
-}
-
-/*
-SUMMARY
-    Computes the Variational Bayes for Ising Model on binary images.
+    The mean absolute difference of two matrices of the same size.
 PARAMETERS
-    Img *mat.Dense: one channel from an image (in BW images all channels are the same)
-    Periods int: the number of iterations
+    A *mat.Dense: the first matrix
+    B *mat.Dense: the second matrix
 RETURN
-    *mat.Dense: one channel of the ICM restored image
+    float64: the mean of |A_ij - B_ij|
 */
-func VariationalBayes(Img *mat.Dense, Periods int) *mat.Dense {
-    Height, Width := Img.Dims()
-    y := utils.Flatten(Img, true)
-    x := utils.Linspace(1.0, 1.0, len(y))
-    mu := utils.Linspace(0.0, 0.0, len(y))
-    m := 0.0
-    for tau:=0; tau<Periods; tau++ {
-        for i := range x {
-            m = NeighbourSum(i, 1.0, mu, Width)
-            mu[i] = math.Tanh(m + 7.0 * (L(1.0, y[i]) - L(-1.0, y[i])))
+func MeanAbsDifference(A, B *mat.Dense) float64 {
+    H, W := A.Dims()
+    sum := 0.0
+    for j:=0; j<H; j++ {
+        for i:=0; i<W; i++ {
+            sum += math.Abs(A.At(j, i) - B.At(j, i))
         }
     }
-    img := mat.NewDense(Height, Width, mu)
-    return img
+    return sum / float64(H*W)
 }
 
 
 /*
-Next we try to restore a noisy image with ICM on Ising Model and animate the effort.
+Next we try to restore a noisy image with mean-field Variational Bayes on Ising Model and animate the effort.
+Then we compare the marginals of mean-field, loopy belief propagation and Gibbs sampling, and the MAP
+estimates of max-product belief propagation, TRW-S and graph cuts.
 */
 func main() {
     var img pic.RGBImg = make([]mat.Dense, 3)
@@ -170,18 +99,42 @@ func main() {
     img.Apply(func (j, i int, v float64) float64 { return v/255.0 })
     matrix := mat.NewDense(Height, Width, nil)
     matrix.Copy(&img[0])
+    // the likelihood weight 3 gives a field of 6 at white and -6 at black pixels
+    model := mrf.NewIsingModel(matrix, mrf.EIGHT_NEIGHBOURHOOD, 1.0, 3.0)
     gm := pic.GifMaker{ Delay: 100 }
     for i:=1; i<6; i++ {
-        rand.Seed(6)
-        channel := VariationalBayes(matrix, i)
-        channel.Apply(func (j, i int, v float64) float64 { return (v+1)*127.5 }, channel)
-        img[0] = *mat.NewDense(Height, Width, nil)
-        img[1] = *mat.NewDense(Height, Width, nil)
-        img[2] = *mat.NewDense(Height, Width, nil)
-        img[0].Copy(channel)
-        img[1].Copy(channel)
-        img[2].Copy(channel)
-        gm.CollectImages(img.ToImage())
+        channel := model.VariationalBayes(i)
+        restored := utils.Flatten(channel, true)
+        for k := range restored {
+            if restored[k] >= 0 { restored[k] = 1.0 } else { restored[k] = -1.0 }
+        }
+        fmt.Println("energy of the thresholded posterior mean after", i, "sweeps", model.Energy(restored))
+        gm.CollectImages(ToRGB(channel).ToImage())
     }
     gm.RenderFrames("scottie_vb.gif")
+
+    pairwise := model.PairwiseMRF()
+    sumProduct := pairwise.BeliefPropagation(mrf.BPOptions{MaxIterations: 150, Damping: 0.1, Tolerance: 1e-3})
+    fmt.Println("sum-product converged", sumProduct.Converged, "after", sumProduct.Iterations, "iterations")
+    bpMarginals := mat.NewDense(Height, Width, mat.Col(nil, 1, sumProduct.Beliefs))
+    gibbsMarginals := model.ParallelGibbs(mrf.GibbsOptions{BurnIn: 20, NumSamples: 200, NumWorkers: runtime.NumCPU(), Seed: randSeed}).Marginals
+    vbMarginals := model.VariationalBayes(10)
+    vbMarginals.Apply(func (j, i int, v float64) float64 { return (v+1)/2 }, vbMarginals)
+    fmt.Println("mean |p_bp - p_gibbs|", MeanAbsDifference(bpMarginals, gibbsMarginals))
+    fmt.Println("mean |p_vb - p_gibbs|", MeanAbsDifference(vbMarginals, gibbsMarginals))
+    for _, figure := range []struct{ marginals *mat.Dense; title, filename string }{
+        {marginals: bpMarginals, title: "Loopy BP marginals", filename: "marginals_bp.png"},
+        {marginals: gibbsMarginals, title: "Gibbs marginals", filename: "marginals_gibbs.png"},
+        {marginals: vbMarginals, title: "Mean-field marginals", filename: "marginals_vb.png"},
+    } {
+        p := MarginalHeatMap(figure.marginals, figure.title)
+        if err := p.Save(4*vg.Inch, 3*vg.Inch, figure.filename); err != nil { panic(err) }
+    }
+
+    maxProduct := pairwise.BeliefPropagation(mrf.BPOptions{MaxIterations: 50, Damping: 0.5, Tolerance: 1e-3, MaxProduct: true})
+    trws := pairwise.TRWS(50, 1e-3)
+    fmt.Println("energy of max-product BP", pairwise.Energy(maxProduct.Labels))
+    fmt.Println("energy of TRW-S", pairwise.Energy(trws.Labels), "converged", trws.Converged, "after", trws.Iterations, "iterations")
+    graphCut := utils.Flatten(model.GraphCut(), true)
+    fmt.Println("energy of the graph cut (exact MAP)", model.Energy(graphCut))
 }